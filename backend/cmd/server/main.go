@@ -1,15 +1,25 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/necorox/FlowCore/backend/config"
 	"github.com/necorox/FlowCore/backend/internal/api/admin"
+	"github.com/necorox/FlowCore/backend/internal/api/auth"
 	"github.com/necorox/FlowCore/backend/internal/api/runtime"
+	"github.com/necorox/FlowCore/backend/internal/authz"
 	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/flow"
+	"github.com/necorox/FlowCore/backend/internal/flow/nodes"
+	"github.com/necorox/FlowCore/backend/internal/flow/script"
+	"github.com/necorox/FlowCore/backend/internal/identity"
+	"github.com/necorox/FlowCore/backend/internal/mailer"
 	"github.com/necorox/FlowCore/backend/internal/middleware"
+	"github.com/necorox/FlowCore/backend/internal/models"
+	"github.com/necorox/FlowCore/backend/internal/routing"
 )
 
 func main() {
@@ -24,6 +34,78 @@ func main() {
 	}
 	defer db.Close()
 
+	// ノードレジストリとフローエンジンを初期化
+	registry := flow.NewRegistry()
+	if err := nodes.Register(registry); err != nil {
+		log.Fatalf("Failed to register nodes: %v", err)
+	}
+	scriptLimits := script.DefaultLimits
+	scriptLimits.Timeout = cfg.Flow.ScriptTimeout
+	scriptLimits.MaxSteps = cfg.Flow.ScriptMaxSteps
+	engine := flow.NewEngine(db, registry, flow.Config{
+		Script: scriptLimits,
+		Limits: flow.Limits{
+			MaxSteps:         cfg.Flow.MaxSteps,
+			Timeout:          cfg.Flow.Timeout,
+			MaxRequestBytes:  cfg.Flow.MaxRequestBytes,
+			MaxResponseBytes: cfg.Flow.MaxResponseBytes,
+			MaxParallelism:   cfg.Flow.MaxParallelism,
+		},
+	})
+
+	// Runtime API（動的エンドポイント）のハンドラー
+	runtimeHandler := runtime.NewHandler(db, engine)
+	if err := runtimeHandler.Reload(context.Background()); err != nil {
+		log.Printf("Warning: failed to load endpoints: %v", err)
+	}
+
+	// メール送信
+	mail, err := mailer.New(cfg.Mail.Driver, cfg.Mail.Dir)
+	if err != nil {
+		log.Fatalf("Failed to initialize mailer: %v", err)
+	}
+
+	// 内部IdP（MASTER_KEYが未設定の場合は認証APIを無効にする）
+	identityService, err := identity.New(db, identity.Config{
+		MasterKey:       cfg.Auth.MasterKey,
+		Issuer:          cfg.Auth.Issuer,
+		AccessTokenTTL:  cfg.Auth.AccessTokenTTL,
+		RefreshTokenTTL: cfg.Auth.RefreshTokenTTL,
+		VerificationURL: cfg.Auth.VerificationURL,
+		VerificationTTL: cfg.Auth.VerificationTTL,
+		Mailer:          mail,
+		MailFrom:        cfg.Mail.From,
+	})
+	if err != nil {
+		log.Printf("Warning: authentication API is disabled: %v", err)
+	}
+
+	// Admin APIの管理者（管理者が存在しない場合はADMIN_EMAIL・ADMIN_PASSWORDのownerを作成する）
+	adminService := identity.NewAdminService(db, identity.AdminConfig{SessionTTL: cfg.Admin.SessionTTL})
+	created, err := adminService.Bootstrap(context.Background(), cfg.Admin.Email, cfg.Admin.Password)
+	switch {
+	case err != nil:
+		log.Printf("Warning: %v", err)
+	case created:
+		log.Printf("Created owner admin %s", cfg.Admin.Email)
+	}
+
+	// サーバー間の呼び出しに使用するAPIキー
+	apiKeyService := identity.NewAPIKeyService(db)
+
+	// Admin API・Runtime APIの認証（検証が設定されていない認証方式のエンドポイントはすべてのリクエストを拒否する）
+	authenticator := &authz.Authenticator{Admin: adminService.Authenticate, APIKey: apiKeyService.Authenticate}
+	if identityService != nil {
+		authenticator.JWT = identityService.Authenticate
+	}
+	// requireAdmin は指定したロール以上の管理者のみを許可するミドルウェアを返す
+	requireAdmin := func(role string) func(http.Handler) http.Handler {
+		return authenticator.Middleware(authz.Rule(&models.EndpointAuth{Mode: models.AuthAdmin, Roles: []string{role}}))
+	}
+	viewer := requireAdmin(models.AdminRoleViewer)
+	editor := requireAdmin(models.AdminRoleEditor)
+	owner := requireAdmin(models.AdminRoleOwner)
+
 	// ルーターを設定
 	r := chi.NewRouter()
 
@@ -37,34 +119,93 @@ func main() {
 		w.Write([]byte("OK"))
 	})
 
-	// Admin API
+	// Admin API（ログイン以外は管理者のセッショントークンが必要。viewer: 参照、editor: 変更、owner: 管理者・APIキー・認証設定の管理）
 	r.Route("/admin", func(r chi.Router) {
+		// 管理者のログイン・アカウント管理API
+		accountsHandler := admin.NewAccountsHandler(adminService)
+		r.Post("/login", accountsHandler.Login)
+		r.With(viewer).Post("/logout", accountsHandler.Logout)
+		r.With(viewer).Get("/me", accountsHandler.Me)
+		r.With(owner).Get("/accounts", accountsHandler.GetAll)
+		r.With(owner).Post("/accounts", accountsHandler.Create)
+		r.With(owner).Put("/accounts/{id}", accountsHandler.Update)
+		r.With(owner).Delete("/accounts/{id}", accountsHandler.Delete)
+
+		// APIキー管理API
+		apiKeysHandler := admin.NewAPIKeysHandler(apiKeyService)
+		r.With(owner).Get("/api-keys", apiKeysHandler.GetAll)
+		r.With(owner).Post("/api-keys", apiKeysHandler.Create)
+		r.With(owner).Get("/api-keys/{id}", apiKeysHandler.GetByID)
+		r.With(owner).Post("/api-keys/{id}/revoke", apiKeysHandler.Revoke)
+		r.With(owner).Delete("/api-keys/{id}", apiKeysHandler.Delete)
+
 		// テーブル管理API
 		tablesHandler := admin.NewTablesHandler(db)
-		r.Get("/tables", tablesHandler.GetAll)
-		r.Post("/tables", tablesHandler.Create)
-		r.Put("/tables/{id}", tablesHandler.Update)
-		r.Delete("/tables/{id}", tablesHandler.Delete)
-		r.Post("/tables/{id}/import", tablesHandler.ImportCSV)
+		r.With(viewer).Get("/tables", tablesHandler.GetAll)
+		r.With(editor).Post("/tables", tablesHandler.Create)
+		r.With(editor).Put("/tables/{id}", tablesHandler.Update)
+		r.With(editor).Delete("/tables/{id}", tablesHandler.Delete)
+		r.With(editor).Post("/tables/{id}/import", tablesHandler.ImportCSV)
 
 		// エンドポイント管理API
-		endpointsHandler := admin.NewEndpointsHandler(db)
-		r.Get("/endpoints", endpointsHandler.GetAll)
-		r.Get("/endpoints/{id}", endpointsHandler.GetByID)
-		r.Post("/endpoints", endpointsHandler.Create)
-		r.Put("/endpoints/{id}", endpointsHandler.Update)
-		r.Delete("/endpoints/{id}", endpointsHandler.Delete)
+		endpointsHandler := admin.NewEndpointsHandler(db, engine, runtimeHandler.Invalidate)
+		r.With(viewer).Get("/endpoints", endpointsHandler.GetAll)
+		r.With(viewer).Get("/endpoints/{id}", endpointsHandler.GetByID)
+		r.With(editor).Post("/endpoints", endpointsHandler.Create)
+		r.With(editor).Put("/endpoints/{id}", endpointsHandler.Update)
+		r.With(editor).Delete("/endpoints/{id}", endpointsHandler.Delete)
+		r.With(viewer).Get("/endpoints/{id}/versions", endpointsHandler.ListVersions)
+		r.With(viewer).Get("/endpoints/{id}/versions/diff", endpointsHandler.DiffVersions)
+		r.With(viewer).Get("/endpoints/{id}/versions/{version}", endpointsHandler.GetVersion)
+		r.With(editor).Post("/endpoints/{id}/versions/{version}/deploy", endpointsHandler.Deploy)
+		r.With(editor).Post("/endpoints/{id}/rollback", endpointsHandler.Rollback)
+		r.With(viewer).Get("/endpoints/{id}/deployments", endpointsHandler.ListDeployments)
+		r.With(editor).Post("/endpoints/{id}/test", endpointsHandler.Test)
+
+		// サブフロー管理API
+		subflowsHandler := admin.NewSubflowsHandler(db, registry)
+		r.With(viewer).Get("/subflows", subflowsHandler.GetAll)
+		r.With(viewer).Get("/subflows/{id}", subflowsHandler.GetByID)
+		r.With(editor).Post("/subflows", subflowsHandler.Create)
+		r.With(editor).Put("/subflows/{id}", subflowsHandler.Update)
+		r.With(editor).Delete("/subflows/{id}", subflowsHandler.Delete)
+		r.With(viewer).Get("/subflows/{id}/versions", subflowsHandler.ListVersions)
+		r.With(viewer).Get("/subflows/{id}/versions/{version}", subflowsHandler.GetVersion)
+		r.With(viewer).Get("/subflows/{id}/dependents", subflowsHandler.GetDependents)
 
 		// 認証管理API
 		authHandler := admin.NewAuthHandler(db)
-		r.Get("/auth/settings", authHandler.GetSettings)
-		r.Put("/auth/settings", authHandler.UpdateSettings)
-		r.Get("/auth/fields", authHandler.GetFields)
+		r.With(viewer).Get("/auth/settings", authHandler.GetSettings)
+		r.With(owner).Put("/auth/settings", authHandler.UpdateSettings)
+		r.With(viewer).Get("/auth/fields", authHandler.GetFields)
+
+		// APIドキュメント
+		docsHandler := admin.NewDocsHandler(db)
+		r.With(viewer).Get("/docs/openapi.json", docsHandler.GetOpenAPI)
+
+		// ノードタイプAPI
+		nodeTypesHandler := admin.NewNodeTypesHandler(registry)
+		r.With(viewer).Get("/node-types", nodeTypesHandler.GetAll)
 	})
 
-	// Runtime API（動的エンドポイント）
-	runtimeHandler := runtime.NewHandler(db)
-	r.HandleFunc("/api/*", runtimeHandler.Execute)
+	// 認証API（内部IdP）
+	if identityService != nil {
+		idpHandler := auth.NewHandler(identityService)
+		r.Route("/auth", func(r chi.Router) {
+			r.Post("/signup", idpHandler.Signup)
+			r.Post("/login", idpHandler.Login)
+			r.Post("/refresh", idpHandler.Refresh)
+			r.Post("/logout", idpHandler.Logout)
+			r.Post("/password", idpHandler.ChangePassword)
+			r.Post("/verify-email", idpHandler.VerifyEmail)
+			r.Post("/verify-email/resend", idpHandler.ResendVerification)
+		})
+		r.Get("/.well-known/jwks.json", idpHandler.JWKS)
+	}
+
+	// Runtime API（動的エンドポイント。エンドポイントの認証設定に従って呼び出し元を認証する）
+	r.With(runtimeHandler.Resolve, authenticator.Middleware(runtime.AuthRule)).
+		HandleFunc(routing.Prefix+"/*", runtimeHandler.Execute)
 
 	// サーバーを起動
 	addr := cfg.Server.Host + ":" + cfg.Server.Port
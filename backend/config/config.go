@@ -3,12 +3,18 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 )
 
 // Config はアプリケーション設定を保持する
 type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
+	Flow     FlowConfig
+	Auth     AuthConfig
+	Admin    AdminConfig
+	Mail     MailConfig
 }
 
 // ServerConfig はサーバー設定
@@ -27,6 +33,41 @@ type DatabaseConfig struct {
 	SSLMode  string
 }
 
+// FlowConfig はフロー実行設定
+type FlowConfig struct {
+	ScriptTimeout    time.Duration
+	ScriptMaxSteps   int
+	MaxSteps         int
+	Timeout          time.Duration
+	MaxRequestBytes  int64
+	MaxResponseBytes int64
+	MaxParallelism   int
+}
+
+// AuthConfig は内部IdP（エンドユーザー認証）の設定
+type AuthConfig struct {
+	MasterKey       string // 署名鍵の暗号化に使用する（未設定の場合は認証APIを無効にする）
+	Issuer          string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	VerificationURL string // 確認メールのリンク先（フロントエンドの確認ページ）
+	VerificationTTL time.Duration
+}
+
+// AdminConfig はAdmin APIの管理者の認証の設定
+type AdminConfig struct {
+	Email      string // 管理者が存在しない場合に作成するownerのメールアドレス
+	Password   string // 同パスワード
+	SessionTTL time.Duration
+}
+
+// MailConfig はメール送信の設定
+type MailConfig struct {
+	Driver string // log または file
+	Dir    string // fileの場合の保存先
+	From   string
+}
+
 // Load は環境変数から設定を読み込む
 func Load() *Config {
 	return &Config{
@@ -42,6 +83,33 @@ func Load() *Config {
 			DBName:   getEnv("DB_NAME", "flowcore"),
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
+		Flow: FlowConfig{
+			ScriptTimeout:    time.Duration(getEnvInt("SCRIPT_TIMEOUT_MS", 1000)) * time.Millisecond,
+			ScriptMaxSteps:   getEnvInt("SCRIPT_MAX_STEPS", 10000000),
+			MaxSteps:         getEnvInt("FLOW_MAX_STEPS", 1000),
+			Timeout:          time.Duration(getEnvInt("FLOW_TIMEOUT_MS", 30000)) * time.Millisecond,
+			MaxRequestBytes:  int64(getEnvInt("MAX_REQUEST_BODY_BYTES", 1<<20)),
+			MaxResponseBytes: int64(getEnvInt("MAX_RESPONSE_BYTES", 10<<20)),
+			MaxParallelism:   getEnvInt("FLOW_MAX_PARALLELISM", 4),
+		},
+		Auth: AuthConfig{
+			MasterKey:       os.Getenv("MASTER_KEY"),
+			Issuer:          getEnv("AUTH_ISSUER", "flowcore"),
+			AccessTokenTTL:  time.Duration(getEnvInt("AUTH_ACCESS_TOKEN_TTL_SEC", 900)) * time.Second,
+			RefreshTokenTTL: time.Duration(getEnvInt("AUTH_REFRESH_TOKEN_TTL_SEC", 30*24*60*60)) * time.Second,
+			VerificationURL: getEnv("AUTH_EMAIL_VERIFICATION_URL", "http://localhost:3000/verify-email"),
+			VerificationTTL: time.Duration(getEnvInt("AUTH_EMAIL_VERIFICATION_TTL_SEC", 24*60*60)) * time.Second,
+		},
+		Admin: AdminConfig{
+			Email:      os.Getenv("ADMIN_EMAIL"),
+			Password:   os.Getenv("ADMIN_PASSWORD"),
+			SessionTTL: time.Duration(getEnvInt("ADMIN_SESSION_TTL_SEC", 12*60*60)) * time.Second,
+		},
+		Mail: MailConfig{
+			Driver: getEnv("MAILER", "log"),
+			Dir:    getEnv("MAILER_DIR", "./tmp/mail"),
+			From:   getEnv("MAIL_FROM", "FlowCore <no-reply@localhost>"),
+		},
 	}
 }
 
@@ -59,3 +127,12 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,148 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/necorox/FlowCore/backend/internal/authz"
+	"github.com/necorox/FlowCore/backend/internal/identity"
+	"github.com/necorox/FlowCore/backend/internal/models"
+	"github.com/necorox/FlowCore/backend/internal/utils"
+)
+
+// AccountsHandler は管理者のログインと管理者アカウント管理APIのハンドラー
+type AccountsHandler struct {
+	admins *identity.AdminService
+}
+
+// NewAccountsHandler は新しいAccountsHandlerを作成する
+func NewAccountsHandler(admins *identity.AdminService) *AccountsHandler {
+	return &AccountsHandler{admins: admins}
+}
+
+// Login はメールアドレスとパスワードでログインし、セッショントークンを発行する
+func (h *AccountsHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req models.AdminLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondValidationError(w, map[string]string{"body": "Invalid JSON"})
+		return
+	}
+	if req.Email == "" || req.Password == "" {
+		utils.RespondUnauthorized(w, "Invalid email or password")
+		return
+	}
+
+	resp, err := h.admins.Login(r.Context(), req.Email, req.Password)
+	if err != nil {
+		respondAccountError(w, err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, resp)
+}
+
+// Logout は現在のセッショントークンを失効させる
+func (h *AccountsHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	token, _ := authz.BearerToken(r)
+	if err := h.admins.Logout(r.Context(), token); err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to logout: %v", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Me はログイン中の管理者を取得する
+func (h *AccountsHandler) Me(w http.ResponseWriter, r *http.Request) {
+	principal := authz.PrincipalFrom(r.Context())
+	if principal == nil {
+		utils.RespondUnauthorized(w, "Authentication required")
+		return
+	}
+
+	admin, err := h.admins.Get(r.Context(), principal.Subject)
+	if err != nil {
+		respondAccountError(w, err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, admin)
+}
+
+// GetAll は管理者一覧を取得する
+func (h *AccountsHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	admins, err := h.admins.List(r.Context())
+	if err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to get admins: %v", err))
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.AdminUsersResponse{Admins: admins})
+}
+
+// Create は管理者を作成する
+func (h *AccountsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAdminUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondValidationError(w, map[string]string{"body": "Invalid JSON"})
+		return
+	}
+
+	admin, err := h.admins.Create(r.Context(), req.Email, req.Password, req.Role)
+	if err != nil {
+		respondAccountError(w, err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, admin)
+}
+
+// Update は管理者のロール・パスワードを更新する
+func (h *AccountsHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateAdminUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondValidationError(w, map[string]string{"body": "Invalid JSON"})
+		return
+	}
+
+	admin, err := h.admins.Update(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		respondAccountError(w, err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, admin)
+}
+
+// Delete は管理者を削除する
+func (h *AccountsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.admins.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		respondAccountError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondAccountError は管理者アカウントのエラーをレスポンスに変換する
+func respondAccountError(w http.ResponseWriter, err error) {
+	var validationErr identity.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		utils.RespondValidationError(w, map[string]string(validationErr))
+	case errors.Is(err, sql.ErrNoRows):
+		utils.RespondNotFound(w, "Admin not found")
+	case errors.Is(err, identity.ErrEmailTaken):
+		utils.RespondConflict(w, "Email is already registered", nil)
+	case errors.Is(err, identity.ErrLastOwner):
+		utils.RespondConflict(w, "At least one owner is required", nil)
+	case errors.Is(err, identity.ErrInvalidCredentials):
+		utils.RespondUnauthorized(w, "Invalid email or password")
+	default:
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to process admin: %v", err))
+	}
+}
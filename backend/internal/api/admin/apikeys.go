@@ -0,0 +1,103 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/necorox/FlowCore/backend/internal/authz"
+	"github.com/necorox/FlowCore/backend/internal/identity"
+	"github.com/necorox/FlowCore/backend/internal/models"
+	"github.com/necorox/FlowCore/backend/internal/utils"
+)
+
+// APIKeysHandler はAPIキー管理APIのハンドラー
+type APIKeysHandler struct {
+	keys *identity.APIKeyService
+}
+
+// NewAPIKeysHandler は新しいAPIKeysHandlerを作成する
+func NewAPIKeysHandler(keys *identity.APIKeyService) *APIKeysHandler {
+	return &APIKeysHandler{keys: keys}
+}
+
+// GetAll はAPIキー一覧を取得する
+func (h *APIKeysHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.keys.List(r.Context())
+	if err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to get API keys: %v", err))
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.APIKeysResponse{APIKeys: keys})
+}
+
+// GetByID はAPIキーを取得する
+func (h *APIKeysHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	key, err := h.keys.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		respondAPIKeyError(w, err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, key)
+}
+
+// Create はAPIキーを作成する（キーはこのレスポンスでのみ返す）
+func (h *APIKeysHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondValidationError(w, map[string]string{"body": "Invalid JSON"})
+		return
+	}
+
+	var createdBy string
+	if principal := authz.PrincipalFrom(r.Context()); principal != nil {
+		createdBy = principal.Subject
+	}
+
+	resp, err := h.keys.Create(r.Context(), &req, createdBy)
+	if err != nil {
+		respondAPIKeyError(w, err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, resp)
+}
+
+// Revoke はAPIキーを失効させる
+func (h *APIKeysHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	key, err := h.keys.Revoke(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		respondAPIKeyError(w, err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, key)
+}
+
+// Delete はAPIキーを削除する
+func (h *APIKeysHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.keys.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		respondAPIKeyError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondAPIKeyError はAPIキーのエラーをレスポンスに変換する
+func respondAPIKeyError(w http.ResponseWriter, err error) {
+	var validationErr identity.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		utils.RespondValidationError(w, map[string]string(validationErr))
+	case errors.Is(err, sql.ErrNoRows):
+		utils.RespondNotFound(w, "API key not found")
+	default:
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to process API key: %v", err))
+	}
+}
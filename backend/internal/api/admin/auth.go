@@ -1,12 +1,13 @@
 package admin
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/identity"
 	"github.com/necorox/FlowCore/backend/internal/models"
 	"github.com/necorox/FlowCore/backend/internal/utils"
 )
@@ -25,7 +26,7 @@ func NewAuthHandler(db *database.DB) *AuthHandler {
 func (h *AuthHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	settings, err := h.getAuthSettings(ctx)
+	settings, err := h.db.AuthSettings(ctx)
 	if err != nil {
 		utils.RespondInternalError(w, fmt.Sprintf("Failed to get auth settings: %v", err))
 		return
@@ -39,7 +40,13 @@ func (h *AuthHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var req models.UpdateAuthSettingsRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			utils.RespondValidationError(w, map[string]string{"body": fmt.Sprintf("Unknown field %s", field)})
+			return
+		}
 		utils.RespondValidationError(w, map[string]string{"body": "Invalid JSON"})
 		return
 	}
@@ -49,6 +56,18 @@ func (h *AuthHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
 		utils.RespondValidationError(w, map[string]string{"method": "Method is required"})
 		return
 	}
+	if req.Method != "email" {
+		utils.RespondValidationError(w, map[string]string{"method": "Method must be 'email'"})
+		return
+	}
+	if req.Config == nil {
+		utils.RespondValidationError(w, map[string]string{"config": "Config is required"})
+		return
+	}
+	if errs := identity.ValidateAuthConfig(req.Config); len(errs) > 0 {
+		utils.RespondValidationError(w, errs)
+		return
+	}
 
 	// 設定をJSONに変換
 	configJSON, err := json.Marshal(req.Config)
@@ -61,7 +80,7 @@ func (h *AuthHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
 	_, err = h.db.ExecContext(ctx, `
 		UPDATE meta_auth_settings
 		SET method = $1, config = $2, updated_at = NOW()
-		WHERE id = (SELECT id FROM meta_auth_settings LIMIT 1)
+		WHERE id = (SELECT id FROM meta_auth_settings ORDER BY created_at LIMIT 1)
 	`, req.Method, configJSON)
 	if err != nil {
 		utils.RespondInternalError(w, fmt.Sprintf("Failed to update auth settings: %v", err))
@@ -69,7 +88,7 @@ func (h *AuthHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 更新後の設定を取得
-	settings, err := h.getAuthSettings(ctx)
+	settings, err := h.db.AuthSettings(ctx)
 	if err != nil {
 		utils.RespondInternalError(w, fmt.Sprintf("Failed to get updated auth settings: %v", err))
 		return
@@ -90,33 +109,3 @@ func (h *AuthHandler) GetFields(w http.ResponseWriter, r *http.Request) {
 
 	utils.RespondJSON(w, http.StatusOK, models.AuthFieldsResponse{Fields: fields})
 }
-
-// Helper methods
-
-func (h *AuthHandler) getAuthSettings(ctx context.Context) (*models.AuthSettings, error) {
-	query := `
-		SELECT id, method, config, created_at, updated_at
-		FROM meta_auth_settings
-		LIMIT 1
-	`
-	var settings models.AuthSettings
-	var configJSON []byte
-
-	err := h.db.QueryRowContext(ctx, query).Scan(
-		&settings.ID,
-		&settings.Method,
-		&configJSON,
-		&settings.CreatedAt,
-		&settings.UpdatedAt,
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	// 設定をデコード
-	if err := json.Unmarshal(configJSON, &settings.Config); err != nil {
-		return nil, err
-	}
-
-	return &settings, nil
-}
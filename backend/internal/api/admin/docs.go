@@ -0,0 +1,73 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/flow"
+	"github.com/necorox/FlowCore/backend/internal/openapi"
+	"github.com/necorox/FlowCore/backend/internal/utils"
+)
+
+// DocsHandler はAPIドキュメントのハンドラー
+type DocsHandler struct {
+	db *database.DB
+}
+
+// NewDocsHandler は新しいDocsHandlerを作成する
+func NewDocsHandler(db *database.DB) *DocsHandler {
+	return &DocsHandler{db: db}
+}
+
+// GetOpenAPI はデプロイされたエンドポイントのOpenAPIドキュメントを返す
+func (h *DocsHandler) GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	endpoints, err := h.getDeployedEndpoints(ctx)
+	if err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to get endpoints: %v", err))
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, openapi.Generate("FlowCore Runtime API", "1.0.0", endpoints))
+}
+
+// Helper methods
+
+func (h *DocsHandler) getDeployedEndpoints(ctx context.Context) ([]openapi.Endpoint, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT e.name, e.method, v.path, v.auth, e.tags, v.request_schema, v.flow_definition
+		FROM meta_endpoints e
+		JOIN flow_versions v ON v.id = e.deployed_version_id
+		ORDER BY v.path ASC, e.method ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var endpoints []openapi.Endpoint
+	for rows.Next() {
+		var endpoint openapi.Endpoint
+		var authJSON, tagsJSON, requestSchema, flowJSON []byte
+		if err := rows.Scan(&endpoint.Name, &endpoint.Method, &endpoint.Path, &authJSON, &tagsJSON, &requestSchema, &flowJSON); err != nil {
+			return nil, err
+		}
+		if endpoint.Flow, err = flow.Parse(flowJSON); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(authJSON, &endpoint.Auth); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(tagsJSON, &endpoint.Tags); err != nil {
+			return nil, err
+		}
+		endpoint.RequestSchema = requestSchema
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints, rows.Err()
+}
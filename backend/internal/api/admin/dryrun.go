@@ -0,0 +1,241 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/necorox/FlowCore/backend/internal/flow"
+	"github.com/necorox/FlowCore/backend/internal/jsonschema"
+	"github.com/necorox/FlowCore/backend/internal/models"
+	"github.com/necorox/FlowCore/backend/internal/routing"
+	"github.com/necorox/FlowCore/backend/internal/utils"
+)
+
+// Test はエンドポイントのフローをテスト実行し、レスポンスとノードごとのトレースを返す
+// データベースへの書き込みはトランザクション内で行い、commitが指定されない限りロールバックする
+func (h *EndpointsHandler) Test(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	endpointID := chi.URLParam(r, "id")
+
+	var req models.TestEndpointRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondValidationError(w, map[string]string{"body": "Invalid JSON"})
+			return
+		}
+	}
+	if req.Flow != nil && req.Version != nil {
+		utils.RespondValidationError(w, map[string]string{"flow": "Specify either flow or version, not both"})
+		return
+	}
+
+	endpoint, err := h.getEndpointByID(ctx, endpointID)
+	if err != nil {
+		respondEndpointError(w, err)
+		return
+	}
+
+	// 実行するフローを決定（省略時は下書き）
+	// バージョンを指定した場合は、そのバージョンのパス・実行制限・認証設定・リクエストスキーマを使用する
+	f := &endpoint.Flow
+	switch {
+	case req.Flow != nil:
+		f = req.Flow
+	case req.Version != nil:
+		v, err := h.getVersion(ctx, endpointID, *req.Version)
+		if err != nil {
+			respondVersionError(w, err)
+			return
+		}
+		f = v.Flow
+		endpoint.Path = v.Path
+		endpoint.Limits = *v.Limits
+		endpoint.Auth = *v.Auth
+		endpoint.RequestSchema = v.RequestSchema
+	}
+
+	if err := flow.Validate(f, h.registry); err != nil {
+		utils.RespondValidationError(w, err)
+		return
+	}
+	if !checkSubflowCalls(w, ctx, h.db, f) {
+		return
+	}
+
+	flowReq, details := buildTestRequest(endpoint, &req.Request)
+	if details != nil {
+		utils.RespondValidationError(w, details)
+		return
+	}
+
+	// リクエストボディをJSON Schemaで検証（違反はフローを実行せずに結果として返す）
+	if len(endpoint.RequestSchema) > 0 {
+		schema, err := jsonschema.Compile(endpoint.RequestSchema)
+		if err != nil {
+			utils.RespondInternalError(w, fmt.Sprintf("Failed to compile request schema: %v", err))
+			return
+		}
+		if err := schema.Validate(flowReq.Body); err != nil {
+			var schemaErr *jsonschema.ValidationError
+			errors.As(err, &schemaErr)
+			utils.RespondJSON(w, http.StatusOK, models.TestEndpointResponse{
+				Error: &models.TestError{Code: "VALIDATION_ERROR", Message: "Request body does not match the schema", Details: schemaErr.Details()},
+				Trace: []models.TraceEntry{},
+			})
+			return
+		}
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to begin transaction: %v", err))
+		return
+	}
+	defer tx.Rollback()
+
+	started := time.Now()
+	result, runErr := h.engine.Run(ctx, f, flowReq, flow.Options{
+		Trace:   true,
+		Querier: tx,
+		Limits:  flow.EndpointLimits(endpoint.Limits),
+	})
+
+	response := models.TestEndpointResponse{
+		Trace:      result.Trace,
+		DurationMs: float64(time.Since(started)) / float64(time.Millisecond),
+	}
+	if response.Trace == nil {
+		response.Trace = []models.TraceEntry{}
+	}
+
+	if runErr != nil {
+		response.Error = testError(runErr)
+		// API利用者に返されるエラーレスポンス
+		mapped := flow.MapError(f, runErr)
+		response.Response = &models.TestResponse{
+			StatusCode: mapped.StatusCode,
+			Headers:    map[string][]string{"Content-Type": {"application/json"}},
+			Body:       utils.ErrorResponse{Error: utils.ErrorDetail{Code: mapped.Code, Message: mapped.Message, Details: mapped.Details}},
+			Format:     flow.FormatJSON,
+		}
+	} else {
+		response.Response = &models.TestResponse{
+			StatusCode: result.Response.StatusCode,
+			Headers:    result.Response.Headers,
+			Body:       result.Response.Body,
+			Format:     result.Response.Format,
+		}
+		if req.Commit {
+			if err := tx.Commit(); err != nil {
+				utils.RespondInternalError(w, fmt.Sprintf("Failed to commit transaction: %v", err))
+				return
+			}
+			response.Committed = true
+		}
+	}
+
+	utils.RespondJSON(w, http.StatusOK, response)
+}
+
+// Helper methods
+
+// buildTestRequest はテスト実行用のリクエストを作成する
+// パスが指定された場合はエンドポイントのパスとマッチさせてパスパラメータを取り出す
+func buildTestRequest(endpoint *models.Endpoint, tr *models.TestRequest) (*flow.Request, map[string]string) {
+	req := &flow.Request{
+		Method:  strings.ToUpper(tr.Method),
+		Path:    endpoint.Path,
+		Params:  map[string]interface{}{},
+		Query:   map[string][]string{},
+		Headers: map[string]string{},
+		Cookies: map[string]string{},
+		Body:    tr.Body,
+		Form:    map[string][]string{},
+		Files:   map[string][]*flow.File{},
+	}
+	if req.Method == "" {
+		req.Method = endpoint.Method
+	}
+
+	if tr.Path != "" {
+		pattern, err := routing.ParsePattern(endpoint.Path)
+		if err != nil {
+			return nil, map[string]string{"path": fmt.Sprintf("Invalid endpoint path: %v", err)}
+		}
+		path := routing.NormalizePath(tr.Path)
+		params, ok := pattern.Match(path)
+		if !ok {
+			return nil, map[string]string{"request.path": fmt.Sprintf("Path does not match %s", endpoint.Path)}
+		}
+		req.Path = path
+		req.Params = params
+	}
+	for key, value := range tr.Params {
+		req.Params[key] = value
+	}
+	req.Auth = tr.Auth
+
+	req.Query = testValues(tr.Query)
+	req.Form = testValues(tr.Form)
+	for key, value := range tr.Cookies {
+		req.Cookies[key] = value
+	}
+
+	// 実際のリクエストと同様にヘッダー名を正規化する
+	for key, value := range tr.Headers {
+		req.Headers[http.CanonicalHeaderKey(key)] = value
+	}
+
+	return req, nil
+}
+
+// testValues はクエリ・フォームの値（文字列または配列）を複数値のマップに変換する
+func testValues(values map[string]interface{}) map[string][]string {
+	result := make(map[string][]string, len(values))
+	for key, value := range values {
+		switch v := value.(type) {
+		case []interface{}:
+			for _, item := range v {
+				result[key] = append(result[key], fmt.Sprint(item))
+			}
+		case nil:
+			result[key] = []string{""}
+		default:
+			result[key] = []string{fmt.Sprint(v)}
+		}
+	}
+	return result
+}
+
+// testError はフローの実行エラーをテスト結果のエラーに変換する
+func testError(err error) *models.TestError {
+	testErr := &models.TestError{Code: "EXECUTION_ERROR", Message: err.Error()}
+
+	var nodeErr *flow.NodeError
+	if errors.As(err, &nodeErr) {
+		testErr.Code = "NODE_ERROR"
+		testErr.Message = nodeErr.Err.Error()
+		testErr.NodeID = nodeErr.NodeID
+	}
+
+	testErr.Class = flow.ClassifyError(err).Class
+
+	var reqErr *flow.RequestError
+	switch {
+	case errors.As(err, &reqErr):
+		testErr.Code = "VALIDATION_ERROR"
+		testErr.Details = reqErr.Details
+	case errors.Is(err, flow.ErrNoResponse):
+		testErr.Code = "NO_RESPONSE"
+	case errors.Is(err, flow.ErrTimeout):
+		testErr.Code = "TIMEOUT"
+	case errors.Is(err, flow.ErrStepLimit):
+		testErr.Code = "STEP_LIMIT_EXCEEDED"
+	}
+	return testErr
+}
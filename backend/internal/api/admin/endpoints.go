@@ -6,21 +6,35 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/flow"
+	"github.com/necorox/FlowCore/backend/internal/jsonschema"
 	"github.com/necorox/FlowCore/backend/internal/models"
+	"github.com/necorox/FlowCore/backend/internal/routing"
 	"github.com/necorox/FlowCore/backend/internal/utils"
 )
 
+// endpointColumns はエンドポイント取得時のカラム（e: meta_endpoints, dv: デプロイ中のバージョン）
+const endpointColumns = `e.id, e.name, e.method, e.path, e.flow_definition, e.limits, e.auth, e.tags, e.request_schema, e.created_at, e.updated_at,
+		COALESCE((SELECT MAX(v.version) FROM flow_versions v WHERE v.endpoint_id = e.id), 0),
+		dv.version`
+
 // EndpointsHandler はエンドポイント管理APIのハンドラー
 type EndpointsHandler struct {
-	db *database.DB
+	db       *database.DB
+	engine   *flow.Engine
+	registry *flow.Registry
+	onChange func()
 }
 
 // NewEndpointsHandler は新しいEndpointsHandlerを作成する
-func NewEndpointsHandler(db *database.DB) *EndpointsHandler {
-	return &EndpointsHandler{db: db}
+// onChange はエンドポイントが作成・更新・削除されたときに呼び出される
+func NewEndpointsHandler(db *database.DB, engine *flow.Engine, onChange func()) *EndpointsHandler {
+	return &EndpointsHandler{db: db, engine: engine, registry: engine.Registry(), onChange: onChange}
 }
 
 // GetAll はすべてのエンドポイントを取得する
@@ -77,6 +91,56 @@ func (h *EndpointsHandler) Create(w http.ResponseWriter, r *http.Request) {
 		utils.RespondValidationError(w, map[string]string{"flow": "At least one node is required"})
 		return
 	}
+	if err := flow.Validate(&req.Flow, h.registry); err != nil {
+		utils.RespondValidationError(w, err)
+		return
+	}
+	if !checkSubflowCalls(w, ctx, h.db, &req.Flow) {
+		return
+	}
+	if req.Limits == nil {
+		req.Limits = &models.EndpointLimits{}
+	}
+	if details := validateLimits(req.Limits); details != nil {
+		utils.RespondValidationError(w, details)
+		return
+	}
+	if req.Auth == nil {
+		req.Auth = &models.EndpointAuth{}
+	}
+	if details := validateAuth(req.Auth); details != nil {
+		utils.RespondValidationError(w, details)
+		return
+	}
+	if msg := models.ValidateTags(req.Tags); msg != "" {
+		utils.RespondValidationError(w, map[string]string{"tags": msg})
+		return
+	}
+	if req.Tags == nil {
+		req.Tags = []string{}
+	}
+	requestSchema, details := compileRequestSchema(req.RequestSchema)
+	if details != nil {
+		utils.RespondValidationError(w, details)
+		return
+	}
+
+	// メソッドとパスを検証し、既存のエンドポイントとの競合を確認
+	req.Method = strings.ToUpper(req.Method)
+	req.Path = routing.NormalizePath(req.Path)
+	if details := validateRoute(req.Method, req.Path); details != nil {
+		utils.RespondValidationError(w, details)
+		return
+	}
+	conflict, err := h.findConflictingEndpoint(ctx, "", req.Method, req.Path)
+	if err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to check endpoint conflicts: %v", err))
+		return
+	}
+	if conflict != nil {
+		respondRouteConflict(w, conflict)
+		return
+	}
 
 	// フロー定義をJSONに変換
 	flowJSON, err := json.Marshal(req.Flow)
@@ -85,18 +149,22 @@ func (h *EndpointsHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// エンドポイントを作成
-	var endpointID string
-	err = h.db.QueryRowContext(ctx, `
-		INSERT INTO meta_endpoints (name, method, path, flow_definition)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id
-	`, req.Name, req.Method, req.Path, flowJSON).Scan(&endpointID)
+	// エンドポイントと最初のバージョンを作成
+	endpointID, err := h.createEndpoint(ctx, &req, flowJSON, requestSchema)
 	if err != nil {
+		if database.IsUniqueViolation(err) {
+			utils.RespondConflict(w, "Endpoint with the same method and path already exists", nil)
+			return
+		}
+		if respondCalledSubflowDeleted(w, err) {
+			return
+		}
 		utils.RespondInternalError(w, fmt.Sprintf("Failed to create endpoint: %v", err))
 		return
 	}
 
+	h.notifyChange()
+
 	// 作成されたエンドポイントを取得
 	endpoint, err := h.getEndpointByID(ctx, endpointID)
 	if err != nil {
@@ -119,7 +187,7 @@ func (h *EndpointsHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 既存のエンドポイントを確認
-	_, err := h.getEndpointByID(ctx, endpointID)
+	existing, err := h.getEndpointByID(ctx, endpointID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			utils.RespondNotFound(w, "Endpoint not found")
@@ -134,13 +202,89 @@ func (h *EndpointsHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if req.Name != "" {
 		updates["name"] = req.Name
 	}
-	if req.Method != "" {
-		updates["method"] = req.Method
+	if req.Method != "" || req.Path != "" {
+		method, path := existing.Method, existing.Path
+		if req.Method != "" {
+			method = strings.ToUpper(req.Method)
+		}
+		if req.Path != "" {
+			path = routing.NormalizePath(req.Path)
+		}
+
+		// メソッドとパスを検証し、他のエンドポイントとの競合を確認
+		if details := validateRoute(method, path); details != nil {
+			utils.RespondValidationError(w, details)
+			return
+		}
+		conflict, err := h.findConflictingEndpoint(ctx, endpointID, method, path)
+		if err != nil {
+			utils.RespondInternalError(w, fmt.Sprintf("Failed to check endpoint conflicts: %v", err))
+			return
+		}
+		if conflict != nil {
+			respondRouteConflict(w, conflict)
+			return
+		}
+
+		updates["method"] = method
+		// パスはバージョンに含まれるため、変更された場合のみ更新して新しいバージョンを作成する
+		if path != existing.Path {
+			updates["path"] = path
+		}
 	}
-	if req.Path != "" {
-		updates["path"] = req.Path
+	if req.Limits != nil {
+		if details := validateLimits(req.Limits); details != nil {
+			utils.RespondValidationError(w, details)
+			return
+		}
+		limitsJSON, err := json.Marshal(req.Limits)
+		if err != nil {
+			utils.RespondInternalError(w, fmt.Sprintf("Failed to marshal limits: %v", err))
+			return
+		}
+		updates["limits"] = limitsJSON
+	}
+	if req.Auth != nil {
+		if details := validateAuth(req.Auth); details != nil {
+			utils.RespondValidationError(w, details)
+			return
+		}
+		authJSON, err := json.Marshal(req.Auth)
+		if err != nil {
+			utils.RespondInternalError(w, fmt.Sprintf("Failed to marshal auth: %v", err))
+			return
+		}
+		updates["auth"] = authJSON
+	}
+	if req.Tags != nil {
+		if msg := models.ValidateTags(req.Tags); msg != "" {
+			utils.RespondValidationError(w, map[string]string{"tags": msg})
+			return
+		}
+		tagsJSON, err := json.Marshal(req.Tags)
+		if err != nil {
+			utils.RespondInternalError(w, fmt.Sprintf("Failed to marshal tags: %v", err))
+			return
+		}
+		updates["tags"] = tagsJSON
+	}
+	if req.RequestSchema != nil {
+		requestSchema, details := compileRequestSchema(req.RequestSchema)
+		if details != nil {
+			utils.RespondValidationError(w, details)
+			return
+		}
+		// nullが指定された場合はスキーマを削除する
+		updates["request_schema"] = nullableJSON(requestSchema)
 	}
 	if len(req.Flow.Nodes) > 0 {
+		if err := flow.Validate(&req.Flow, h.registry); err != nil {
+			utils.RespondValidationError(w, err)
+			return
+		}
+		if !checkSubflowCalls(w, ctx, h.db, &req.Flow) {
+			return
+		}
 		flowJSON, err := json.Marshal(req.Flow)
 		if err != nil {
 			utils.RespondInternalError(w, fmt.Sprintf("Failed to marshal flow: %v", err))
@@ -149,8 +293,24 @@ func (h *EndpointsHandler) Update(w http.ResponseWriter, r *http.Request) {
 		updates["flow_definition"] = flowJSON
 	}
 
-	// 更新を実行
+	// 更新を実行（バージョンに含まれる設定が変更された場合は新しいバージョンを作成）
 	if len(updates) > 0 {
+		tx, err := h.db.BeginTx(ctx, nil)
+		if err != nil {
+			utils.RespondInternalError(w, fmt.Sprintf("Failed to begin transaction: %v", err))
+			return
+		}
+		defer tx.Rollback()
+
+		if _, ok := updates["flow_definition"]; ok {
+			if err := lockCalledSubflows(ctx, tx, &req.Flow); err != nil {
+				if !respondCalledSubflowDeleted(w, err) {
+					utils.RespondInternalError(w, fmt.Sprintf("Failed to lock subflows: %v", err))
+				}
+				return
+			}
+		}
+
 		query := "UPDATE meta_endpoints SET "
 		args := []interface{}{}
 		i := 1
@@ -167,11 +327,28 @@ func (h *EndpointsHandler) Update(w http.ResponseWriter, r *http.Request) {
 		query += fmt.Sprintf(", updated_at = NOW() WHERE id = $%d", i)
 		args = append(args, endpointID)
 
-		_, err := h.db.ExecContext(ctx, query, args...)
+		_, err = tx.ExecContext(ctx, query, args...)
 		if err != nil {
+			if database.IsUniqueViolation(err) {
+				utils.RespondConflict(w, "Endpoint with the same method and path already exists", nil)
+				return
+			}
+			utils.RespondInternalError(w, fmt.Sprintf("Failed to update endpoint: %v", err))
+			return
+		}
+
+		if versionedUpdate(updates) {
+			if _, err := createVersion(ctx, tx, endpointID); err != nil {
+				utils.RespondInternalError(w, fmt.Sprintf("Failed to create flow version: %v", err))
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
 			utils.RespondInternalError(w, fmt.Sprintf("Failed to update endpoint: %v", err))
 			return
 		}
+		h.notifyChange()
 	}
 
 	// 更新後のエンドポイントを取得
@@ -206,17 +383,247 @@ func (h *EndpointsHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		utils.RespondInternalError(w, fmt.Sprintf("Failed to delete endpoint: %v", err))
 		return
 	}
+	h.notifyChange()
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // Helper methods
 
+func (h *EndpointsHandler) notifyChange() {
+	if h.onChange != nil {
+		h.onChange()
+	}
+}
+
+// validateRoute はメソッドとパステンプレートを検証し、エラーがあれば詳細を返す
+func validateRoute(method, path string) map[string]string {
+	if !models.ValidEndpointMethods[method] {
+		return map[string]string{"method": "Method must be one of GET, POST, PUT, DELETE, PATCH"}
+	}
+	if _, err := routing.ParsePattern(path); err != nil {
+		return map[string]string{"path": err.Error()}
+	}
+	return nil
+}
+
+// findConflictingEndpoint は同じメソッドで同じリクエストにマッチするエンドポイントを探す
+// /users/{id} と /users/{user_id} のようにパラメータ名だけが異なるパスも競合とみなす
+// Runtime APIはデプロイされたバージョンのパスでルーティングするため、デプロイ済みのパスとの競合も確認する
+func (h *EndpointsHandler) findConflictingEndpoint(ctx context.Context, excludeID, method, path string) (*models.Endpoint, error) {
+	pattern, err := routing.ParsePattern(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT e.id, e.name, e.method, e.path, v.path
+		FROM meta_endpoints e
+		LEFT JOIN flow_versions v ON v.id = e.deployed_version_id
+		WHERE e.method = $1
+	`, method)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var endpoint models.Endpoint
+		var deployedPath sql.NullString
+		if err := rows.Scan(&endpoint.ID, &endpoint.Name, &endpoint.Method, &endpoint.Path, &deployedPath); err != nil {
+			return nil, err
+		}
+		if endpoint.ID == excludeID {
+			continue
+		}
+		paths := []string{endpoint.Path}
+		if deployedPath.Valid && deployedPath.String != endpoint.Path {
+			paths = append(paths, deployedPath.String)
+		}
+		for _, p := range paths {
+			other, err := routing.ParsePattern(p)
+			if err != nil {
+				continue
+			}
+			if other.Shape() == pattern.Shape() {
+				endpoint.Path = p
+				return &endpoint, nil
+			}
+		}
+	}
+
+	return nil, rows.Err()
+}
+
+// respondRouteConflict はルート競合エラーを返す
+func respondRouteConflict(w http.ResponseWriter, conflict *models.Endpoint) {
+	utils.RespondConflict(w, "Endpoint with the same method and path already exists", map[string]string{
+		"endpoint_id": conflict.ID,
+		"name":        conflict.Name,
+		"method":      conflict.Method,
+		"path":        conflict.Path,
+	})
+}
+
+// validateLimits はエンドポイントの実行制限を検証する
+func validateLimits(limits *models.EndpointLimits) map[string]string {
+	details := make(map[string]string)
+	if limits.MaxSteps < 0 {
+		details["limits.max_steps"] = "Must not be negative"
+	}
+	if limits.TimeoutMs < 0 {
+		details["limits.timeout_ms"] = "Must not be negative"
+	}
+	if limits.MaxRequestBytes < 0 {
+		details["limits.max_request_bytes"] = "Must not be negative"
+	}
+	if limits.MaxResponseBytes < 0 {
+		details["limits.max_response_bytes"] = "Must not be negative"
+	}
+	if limits.MaxParallelism < 0 {
+		details["limits.max_parallelism"] = "Must not be negative"
+	}
+	if len(details) > 0 {
+		return details
+	}
+	return nil
+}
+
+// validateAuth はエンドポイントの認証設定を検証する
+// modeが空の場合はpublicとして扱う
+func validateAuth(auth *models.EndpointAuth) map[string]string {
+	if auth.Mode == "" {
+		auth.Mode = models.AuthPublic
+	}
+	if !models.ValidAuthModes[auth.Mode] {
+		return map[string]string{"auth.mode": "Must be one of public, jwt, api_key, admin"}
+	}
+
+	details := make(map[string]string)
+	switch {
+	case len(auth.Roles) > 0 && auth.Mode != models.AuthJWT && auth.Mode != models.AuthAdmin:
+		details["auth.roles"] = "Roles can only be used with jwt or admin"
+	case slices.Contains(auth.Roles, ""):
+		details["auth.roles"] = "Must not contain empty values"
+	case auth.Mode == models.AuthAdmin && slices.ContainsFunc(auth.Roles, func(role string) bool {
+		return !slices.Contains(models.AdminRoles, role)
+	}):
+		details["auth.roles"] = "Admin roles must be owner, editor or viewer"
+	}
+	switch {
+	case len(auth.Scopes) > 0 && auth.Mode != models.AuthAPIKey:
+		details["auth.scopes"] = "Scopes can only be used with api_key"
+	case slices.Contains(auth.Scopes, ""):
+		details["auth.scopes"] = "Must not contain empty values"
+	}
+	if len(details) > 0 {
+		return details
+	}
+	return nil
+}
+
+// compileRequestSchema はリクエストボディのJSON Schemaを検証し、保存するJSONを返す
+// 省略またはnullの場合はnilを返す
+func compileRequestSchema(raw json.RawMessage) ([]byte, map[string]string) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if _, err := jsonschema.Compile(raw); err != nil {
+		return nil, map[string]string{"request_schema": err.Error()}
+	}
+	return raw, nil
+}
+
+// nullableJSON はnilの場合にNULLとして保存されるよう変換する
+func nullableJSON(data []byte) interface{} {
+	if data == nil {
+		return nil
+	}
+	return data
+}
+
+// createEndpoint はエンドポイントとバージョン1のフローを作成する
+func (h *EndpointsHandler) createEndpoint(ctx context.Context, req *models.CreateEndpointRequest, flowJSON, requestSchema []byte) (string, error) {
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if err := lockCalledSubflows(ctx, tx, &req.Flow); err != nil {
+		return "", err
+	}
+
+	limitsJSON, err := json.Marshal(req.Limits)
+	if err != nil {
+		return "", err
+	}
+	authJSON, err := json.Marshal(req.Auth)
+	if err != nil {
+		return "", err
+	}
+	tagsJSON, err := json.Marshal(req.Tags)
+	if err != nil {
+		return "", err
+	}
+
+	var endpointID string
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO meta_endpoints (name, method, path, flow_definition, limits, auth, tags, request_schema)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`, req.Name, req.Method, req.Path, flowJSON, limitsJSON, authJSON, tagsJSON, nullableJSON(requestSchema)).Scan(&endpointID)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := createVersion(ctx, tx, endpointID); err != nil {
+		return "", err
+	}
+
+	return endpointID, tx.Commit()
+}
+
+// versionedFields はフローのバージョンに含まれるmeta_endpointsのカラム
+// その他のカラム（名前・メソッド・タグ）は更新すると即座にRuntime APIに反映される
+var versionedFields = []string{"flow_definition", "path", "limits", "auth", "request_schema"}
+
+// versionedUpdate は更新にバージョンに含まれるカラムが含まれるかを返す
+func versionedUpdate(updates map[string]interface{}) bool {
+	for _, field := range versionedFields {
+		if _, ok := updates[field]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// createVersion はエンドポイントの現在のフロー定義と設定から新しいバージョンを作成し、バージョン番号を返す
+func createVersion(ctx context.Context, tx *sql.Tx, endpointID string) (int, error) {
+	// 同時保存でバージョン番号が衝突しないようエンドポイントの行をロックする
+	if _, err := tx.ExecContext(ctx, `SELECT 1 FROM meta_endpoints WHERE id = $1 FOR UPDATE`, endpointID); err != nil {
+		return 0, err
+	}
+
+	var version int
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO flow_versions (endpoint_id, version, flow_definition, path, limits, auth, request_schema)
+		SELECT e.id,
+			COALESCE((SELECT MAX(v.version) FROM flow_versions v WHERE v.endpoint_id = e.id), 0) + 1,
+			e.flow_definition, e.path, e.limits, e.auth, e.request_schema
+		FROM meta_endpoints e
+		WHERE e.id = $1
+		RETURNING version
+	`, endpointID).Scan(&version)
+	return version, err
+}
+
 func (h *EndpointsHandler) getAllEndpoints(ctx context.Context) ([]models.Endpoint, error) {
 	query := `
-		SELECT id, name, method, path, flow_definition, created_at, updated_at
-		FROM meta_endpoints
-		ORDER BY created_at DESC
+		SELECT ` + endpointColumns + `
+		FROM meta_endpoints e
+		LEFT JOIN flow_versions dv ON dv.id = e.deployed_version_id
+		ORDER BY e.created_at DESC
 	`
 	rows, err := h.db.QueryContext(ctx, query)
 	if err != nil {
@@ -226,27 +633,11 @@ func (h *EndpointsHandler) getAllEndpoints(ctx context.Context) ([]models.Endpoi
 
 	var endpoints []models.Endpoint
 	for rows.Next() {
-		var endpoint models.Endpoint
-		var flowJSON []byte
-
-		if err := rows.Scan(
-			&endpoint.ID,
-			&endpoint.Name,
-			&endpoint.Method,
-			&endpoint.Path,
-			&flowJSON,
-			&endpoint.CreatedAt,
-			&endpoint.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-
-		// フロー定義をデコード
-		if err := json.Unmarshal(flowJSON, &endpoint.Flow); err != nil {
+		endpoint, err := scanEndpoint(rows)
+		if err != nil {
 			return nil, err
 		}
-
-		endpoints = append(endpoints, endpoint)
+		endpoints = append(endpoints, *endpoint)
 	}
 
 	return endpoints, nil
@@ -254,30 +645,54 @@ func (h *EndpointsHandler) getAllEndpoints(ctx context.Context) ([]models.Endpoi
 
 func (h *EndpointsHandler) getEndpointByID(ctx context.Context, endpointID string) (*models.Endpoint, error) {
 	query := `
-		SELECT id, name, method, path, flow_definition, created_at, updated_at
-		FROM meta_endpoints
-		WHERE id = $1
+		SELECT ` + endpointColumns + `
+		FROM meta_endpoints e
+		LEFT JOIN flow_versions dv ON dv.id = e.deployed_version_id
+		WHERE e.id = $1
 	`
+	return scanEndpoint(h.db.QueryRowContext(ctx, query, endpointID))
+}
+
+// scanEndpoint はendpointColumnsで取得した行をエンドポイントに変換する
+func scanEndpoint(row interface{ Scan(...interface{}) error }) (*models.Endpoint, error) {
 	var endpoint models.Endpoint
-	var flowJSON []byte
+	var flowJSON, limitsJSON, authJSON, tagsJSON, requestSchema []byte
 
-	err := h.db.QueryRowContext(ctx, query, endpointID).Scan(
+	err := row.Scan(
 		&endpoint.ID,
 		&endpoint.Name,
 		&endpoint.Method,
 		&endpoint.Path,
 		&flowJSON,
+		&limitsJSON,
+		&authJSON,
+		&tagsJSON,
+		&requestSchema,
 		&endpoint.CreatedAt,
 		&endpoint.UpdatedAt,
+		&endpoint.Version,
+		&endpoint.DeployedVersion,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	// フロー定義をデコード
+	// フロー定義・実行制限・認証設定・タグをデコード
 	if err := json.Unmarshal(flowJSON, &endpoint.Flow); err != nil {
 		return nil, err
 	}
+	if err := json.Unmarshal(limitsJSON, &endpoint.Limits); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(authJSON, &endpoint.Auth); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(tagsJSON, &endpoint.Tags); err != nil {
+		return nil, err
+	}
+	if requestSchema != nil {
+		endpoint.RequestSchema = json.RawMessage(requestSchema)
+	}
 
 	return &endpoint, nil
 }
@@ -0,0 +1,24 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/necorox/FlowCore/backend/internal/flow"
+	"github.com/necorox/FlowCore/backend/internal/models"
+	"github.com/necorox/FlowCore/backend/internal/utils"
+)
+
+// NodeTypesHandler はノードタイプ一覧APIのハンドラー
+type NodeTypesHandler struct {
+	registry *flow.Registry
+}
+
+// NewNodeTypesHandler は新しいNodeTypesHandlerを作成する
+func NewNodeTypesHandler(registry *flow.Registry) *NodeTypesHandler {
+	return &NodeTypesHandler{registry: registry}
+}
+
+// GetAll は利用可能なノードタイプと設定スキーマを取得する
+func (h *NodeTypesHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	utils.RespondJSON(w, http.StatusOK, models.NodeTypesResponse{NodeTypes: h.registry.List()})
+}
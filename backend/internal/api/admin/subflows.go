@@ -0,0 +1,674 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lib/pq"
+	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/flow"
+	"github.com/necorox/FlowCore/backend/internal/models"
+	"github.com/necorox/FlowCore/backend/internal/utils"
+)
+
+// subflowColumns はサブフロー取得時のカラム（s: meta_subflows）
+const subflowColumns = `s.id, s.name, s.description, s.inputs, s.outputs, s.flow_definition, s.created_at, s.updated_at,
+		COALESCE((SELECT MAX(v.version) FROM subflow_versions v WHERE v.subflow_id = s.id), 0)`
+
+// SubflowsHandler はサブフロー管理APIのハンドラー
+type SubflowsHandler struct {
+	db       *database.DB
+	registry *flow.Registry
+}
+
+// NewSubflowsHandler は新しいSubflowsHandlerを作成する
+func NewSubflowsHandler(db *database.DB, registry *flow.Registry) *SubflowsHandler {
+	return &SubflowsHandler{db: db, registry: registry}
+}
+
+// GetAll はすべてのサブフローを取得する
+func (h *SubflowsHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	subflows, err := h.getAllSubflows(ctx)
+	if err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to get subflows: %v", err))
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.SubflowsResponse{Subflows: subflows})
+}
+
+// GetByID はIDでサブフローを取得する
+func (h *SubflowsHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	subflow, err := h.getSubflowByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		respondSubflowError(w, err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, subflow)
+}
+
+// Create は新しいサブフローとバージョン1を作成する
+func (h *SubflowsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req models.CreateSubflowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondValidationError(w, map[string]string{"body": "Invalid JSON"})
+		return
+	}
+
+	// バリデーション
+	if req.Name == "" {
+		utils.RespondValidationError(w, map[string]string{"name": "Name is required"})
+		return
+	}
+	if !flow.ValidSubflowName(req.Name) {
+		utils.RespondValidationError(w, map[string]string{"name": "Name must start with a letter and contain only letters, digits, '_' and '-'"})
+		return
+	}
+	if len(req.Flow.Nodes) == 0 {
+		utils.RespondValidationError(w, map[string]string{"flow": "At least one node is required"})
+		return
+	}
+	if req.Inputs == nil {
+		req.Inputs = []models.SubflowParam{}
+	}
+	if req.Outputs == nil {
+		req.Outputs = []models.SubflowParam{}
+	}
+	if !h.validateDefinition(w, ctx, &req.Flow, req.Inputs, req.Outputs) {
+		return
+	}
+
+	subflowID, err := h.createSubflow(ctx, &req)
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			utils.RespondConflict(w, "Subflow with the same name already exists", nil)
+			return
+		}
+		if respondCalledSubflowDeleted(w, err) {
+			return
+		}
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to create subflow: %v", err))
+		return
+	}
+
+	subflow, err := h.getSubflowByID(ctx, subflowID)
+	if err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to get created subflow: %v", err))
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, subflow)
+}
+
+// Update はサブフローを更新する
+// 入力・出力・フローのいずれかを変更した場合は新しいバージョンを作成する（既存のバージョンを呼び出しているフローには影響しない）
+// サブフロー名は呼び出し側から参照されるため変更できない
+func (h *SubflowsHandler) Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	subflowID := chi.URLParam(r, "id")
+
+	var req models.UpdateSubflowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondValidationError(w, map[string]string{"body": "Invalid JSON"})
+		return
+	}
+
+	existing, err := h.getSubflowByID(ctx, subflowID)
+	if err != nil {
+		respondSubflowError(w, err)
+		return
+	}
+
+	newVersion := req.Inputs != nil || req.Outputs != nil || req.Flow != nil
+	if req.Inputs != nil {
+		existing.Inputs = *req.Inputs
+	}
+	if req.Outputs != nil {
+		existing.Outputs = *req.Outputs
+	}
+	if req.Flow != nil {
+		if len(req.Flow.Nodes) == 0 {
+			utils.RespondValidationError(w, map[string]string{"flow": "At least one node is required"})
+			return
+		}
+		existing.Flow = *req.Flow
+	}
+	if newVersion && !h.validateDefinition(w, ctx, &existing.Flow, existing.Inputs, existing.Outputs) {
+		return
+	}
+
+	if req.Description != nil || newVersion {
+		if req.Description != nil {
+			existing.Description = *req.Description
+		}
+		if err := h.updateSubflow(ctx, existing, newVersion); err != nil {
+			if respondCalledSubflowDeleted(w, err) {
+				return
+			}
+			utils.RespondInternalError(w, fmt.Sprintf("Failed to update subflow: %v", err))
+			return
+		}
+	}
+
+	subflow, err := h.getSubflowByID(ctx, subflowID)
+	if err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to get updated subflow: %v", err))
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, subflow)
+}
+
+// Delete はサブフローを削除する
+// 呼び出しているエンドポイント・サブフローがある場合は削除せず、依存元の一覧とともに409を返す
+func (h *SubflowsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	subflowID := chi.URLParam(r, "id")
+
+	subflow, err := h.getSubflowByID(ctx, subflowID)
+	if err != nil {
+		respondSubflowError(w, err)
+		return
+	}
+
+	// 依存元の確認と削除の間に呼び出すフローが保存されないよう、サブフローの行をロックしてから確認する
+	// フローを保存するトランザクションは呼び出すサブフローの行を共有ロックする（lockCalledSubflows）
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to begin transaction: %v", err))
+		return
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, `SELECT id FROM meta_subflows WHERE id = $1 FOR UPDATE`, subflowID).Scan(&subflowID); err != nil {
+		respondSubflowError(w, err)
+		return
+	}
+
+	dependents, err := h.getDependents(ctx, tx, subflow.Name)
+	if err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to get subflow dependents: %v", err))
+		return
+	}
+	if len(dependents) > 0 {
+		utils.RespondConflict(w, "Subflow is called by other flows", models.SubflowDependentsResponse{Dependents: dependents})
+		return
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM meta_subflows WHERE id = $1", subflowID); err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to delete subflow: %v", err))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to delete subflow: %v", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListVersions はサブフローのバージョン一覧を取得する
+func (h *SubflowsHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	subflowID := chi.URLParam(r, "id")
+
+	if _, err := h.getSubflowByID(ctx, subflowID); err != nil {
+		respondSubflowError(w, err)
+		return
+	}
+
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT v.id, v.subflow_id, s.name, v.version, v.inputs, v.outputs, v.created_at
+		FROM subflow_versions v
+		JOIN meta_subflows s ON s.id = v.subflow_id
+		WHERE v.subflow_id = $1
+		ORDER BY v.version DESC
+	`, subflowID)
+	if err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to get versions: %v", err))
+		return
+	}
+	defer rows.Close()
+
+	versions := []models.SubflowVersion{}
+	for rows.Next() {
+		var v models.SubflowVersion
+		var inputsJSON, outputsJSON []byte
+		if err := rows.Scan(&v.ID, &v.SubflowID, &v.Name, &v.Version, &inputsJSON, &outputsJSON, &v.CreatedAt); err != nil {
+			utils.RespondInternalError(w, fmt.Sprintf("Failed to get versions: %v", err))
+			return
+		}
+		if err := decodeParams(inputsJSON, outputsJSON, &v.Inputs, &v.Outputs); err != nil {
+			utils.RespondInternalError(w, fmt.Sprintf("Failed to get versions: %v", err))
+			return
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to get versions: %v", err))
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.SubflowVersionsResponse{Versions: versions})
+}
+
+// GetVersion はサブフローの指定バージョンを取得する
+func (h *SubflowsHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	version, ok := versionParam(w, chi.URLParam(r, "version"), "version")
+	if !ok {
+		return
+	}
+
+	subflow, err := h.getSubflowByID(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		respondSubflowError(w, err)
+		return
+	}
+
+	v, err := h.db.SubflowVersion(ctx, subflow.Name, version)
+	if err != nil {
+		respondVersionError(w, err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, v)
+}
+
+// GetDependents はサブフローを呼び出しているエンドポイント・サブフローの一覧を取得する
+// 変更・削除の前に影響範囲を確認するために使用する
+func (h *SubflowsHandler) GetDependents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	subflow, err := h.getSubflowByID(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		respondSubflowError(w, err)
+		return
+	}
+
+	dependents, err := h.getDependents(ctx, h.db, subflow.Name)
+	if err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to get subflow dependents: %v", err))
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.SubflowDependentsResponse{Dependents: dependents})
+}
+
+// Helper methods
+
+// validateDefinition はサブフローの定義と、サブフローが呼び出すサブフローを検証する
+// 問題があればエラーレスポンスを返し、falseを返す
+func (h *SubflowsHandler) validateDefinition(w http.ResponseWriter, ctx context.Context, f *models.Flow, inputs, outputs []models.SubflowParam) bool {
+	if err := flow.ValidateSubflow(f, inputs, outputs, h.registry); err != nil {
+		utils.RespondValidationError(w, err)
+		return false
+	}
+	return checkSubflowCalls(w, ctx, h.db, f)
+}
+
+// checkSubflowCalls はフローが呼び出すサブフローのバージョンが存在し、入力・出力ピンが宣言と一致するか検証する
+// 問題があればエラーレスポンスを返し、falseを返す
+func checkSubflowCalls(w http.ResponseWriter, ctx context.Context, db *database.DB, f *models.Flow) bool {
+	err := flow.ValidateSubflowCalls(f, func(name string, version int) (*models.SubflowVersion, error) {
+		sv, err := db.SubflowVersion(ctx, name, version)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return sv, err
+	})
+	if err == nil {
+		return true
+	}
+
+	var validationErr *flow.ValidationErrors
+	if errors.As(err, &validationErr) {
+		utils.RespondValidationError(w, validationErr)
+		return false
+	}
+	utils.RespondInternalError(w, fmt.Sprintf("Failed to get subflows: %v", err))
+	return false
+}
+
+// calledSubflowDeletedError はフローの保存中に呼び出すサブフローが削除された場合のエラー
+type calledSubflowDeletedError struct {
+	Name string
+}
+
+func (e *calledSubflowDeletedError) Error() string {
+	return fmt.Sprintf("called subflow %q was deleted", e.Name)
+}
+
+// lockCalledSubflows はフローが呼び出すサブフローの行を共有ロックし、削除されていないことを確認する
+// フローを保存するトランザクション内で呼び出し、保存と並行してサブフローが削除されないようにする（Delete参照）
+func lockCalledSubflows(ctx context.Context, tx *sql.Tx, f *models.Flow) error {
+	names := []string{}
+	for _, call := range flow.SubflowCalls(f) {
+		if !slices.Contains(names, call.Name) {
+			names = append(names, call.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT name FROM meta_subflows WHERE name = ANY($1) ORDER BY name FOR SHARE
+	`, pq.Array(names))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	found := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		found[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if !found[name] {
+			return &calledSubflowDeletedError{Name: name}
+		}
+	}
+	return nil
+}
+
+// respondCalledSubflowDeleted はlockCalledSubflowsのエラーの場合に409を返してtrue、それ以外はfalseを返す
+func respondCalledSubflowDeleted(w http.ResponseWriter, err error) bool {
+	var deleted *calledSubflowDeletedError
+	if !errors.As(err, &deleted) {
+		return false
+	}
+	utils.RespondConflict(w, "Called subflow was deleted while saving", map[string]string{"subflow": deleted.Name})
+	return true
+}
+
+// createSubflow はサブフローとバージョン1を作成する
+func (h *SubflowsHandler) createSubflow(ctx context.Context, req *models.CreateSubflowRequest) (string, error) {
+	inputsJSON, outputsJSON, flowJSON, err := encodeDefinition(req.Inputs, req.Outputs, &req.Flow)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if err := lockCalledSubflows(ctx, tx, &req.Flow); err != nil {
+		return "", err
+	}
+
+	var subflowID string
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO meta_subflows (name, description, inputs, outputs, flow_definition)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, req.Name, req.Description, inputsJSON, outputsJSON, flowJSON).Scan(&subflowID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := createSubflowVersion(ctx, tx, subflowID, inputsJSON, outputsJSON, flowJSON); err != nil {
+		return "", err
+	}
+
+	return subflowID, tx.Commit()
+}
+
+// updateSubflow はサブフローを更新し、newVersionがtrueの場合は新しいバージョンを作成する
+func (h *SubflowsHandler) updateSubflow(ctx context.Context, subflow *models.Subflow, newVersion bool) error {
+	inputsJSON, outputsJSON, flowJSON, err := encodeDefinition(subflow.Inputs, subflow.Outputs, &subflow.Flow)
+	if err != nil {
+		return err
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if newVersion {
+		if err := lockCalledSubflows(ctx, tx, &subflow.Flow); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE meta_subflows
+		SET description = $1, inputs = $2, outputs = $3, flow_definition = $4, updated_at = NOW()
+		WHERE id = $5
+	`, subflow.Description, inputsJSON, outputsJSON, flowJSON, subflow.ID); err != nil {
+		return err
+	}
+
+	if newVersion {
+		if err := createSubflowVersion(ctx, tx, subflow.ID, inputsJSON, outputsJSON, flowJSON); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// createSubflowVersion はサブフローの新しいバージョンを作成する
+func createSubflowVersion(ctx context.Context, tx *sql.Tx, subflowID string, inputsJSON, outputsJSON, flowJSON []byte) error {
+	// 同時保存でバージョン番号が衝突しないようサブフローの行をロックする
+	if _, err := tx.ExecContext(ctx, `SELECT 1 FROM meta_subflows WHERE id = $1 FOR UPDATE`, subflowID); err != nil {
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO subflow_versions (subflow_id, version, inputs, outputs, flow_definition)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4
+		FROM subflow_versions
+		WHERE subflow_id = $1
+	`, subflowID, inputsJSON, outputsJSON, flowJSON)
+	return err
+}
+
+func encodeDefinition(inputs, outputs []models.SubflowParam, f *models.Flow) (inputsJSON, outputsJSON, flowJSON []byte, err error) {
+	if inputsJSON, err = json.Marshal(inputs); err != nil {
+		return nil, nil, nil, err
+	}
+	if outputsJSON, err = json.Marshal(outputs); err != nil {
+		return nil, nil, nil, err
+	}
+	if flowJSON, err = json.Marshal(f); err != nil {
+		return nil, nil, nil, err
+	}
+	return inputsJSON, outputsJSON, flowJSON, nil
+}
+
+func decodeParams(inputsJSON, outputsJSON []byte, inputs, outputs *[]models.SubflowParam) error {
+	if err := json.Unmarshal(inputsJSON, inputs); err != nil {
+		return err
+	}
+	return json.Unmarshal(outputsJSON, outputs)
+}
+
+// getDependents はサブフローを呼び出しているエンドポイント（下書き・デプロイ中のバージョン）と
+// 他のサブフロー（最新のバージョン）を返す
+func (h *SubflowsHandler) getDependents(ctx context.Context, q database.Querier, name string) ([]models.SubflowDependent, error) {
+	dependents := []models.SubflowDependent{}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT e.id, e.name, e.method, e.path, e.flow_definition, dv.flow_definition
+		FROM meta_endpoints e
+		LEFT JOIN flow_versions dv ON dv.id = e.deployed_version_id
+		ORDER BY e.path ASC, e.method ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		dep := models.SubflowDependent{Kind: "endpoint"}
+		var draftJSON, deployedJSON []byte
+		if err := rows.Scan(&dep.ID, &dep.Name, &dep.Method, &dep.Path, &draftJSON, &deployedJSON); err != nil {
+			return nil, err
+		}
+
+		versions := map[int]bool{}
+		if dep.Draft, err = callsSubflow(draftJSON, name, versions); err != nil {
+			return nil, err
+		}
+		if dep.Deployed, err = callsSubflow(deployedJSON, name, versions); err != nil {
+			return nil, err
+		}
+		if dep.Draft || dep.Deployed {
+			dep.Versions = sortedVersions(versions)
+			dependents = append(dependents, dep)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	subflowRows, err := q.QueryContext(ctx, `
+		SELECT id, name, flow_definition FROM meta_subflows WHERE name <> $1 ORDER BY name ASC
+	`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer subflowRows.Close()
+
+	for subflowRows.Next() {
+		dep := models.SubflowDependent{Kind: "subflow"}
+		var flowJSON []byte
+		if err := subflowRows.Scan(&dep.ID, &dep.Name, &flowJSON); err != nil {
+			return nil, err
+		}
+
+		versions := map[int]bool{}
+		if dep.Draft, err = callsSubflow(flowJSON, name, versions); err != nil {
+			return nil, err
+		}
+		if dep.Draft {
+			dep.Versions = sortedVersions(versions)
+			dependents = append(dependents, dep)
+		}
+	}
+
+	return dependents, subflowRows.Err()
+}
+
+// callsSubflow はフロー定義がサブフローを呼び出しているかどうかを返し、呼び出しているバージョンをversionsに追加する
+func callsSubflow(flowJSON []byte, name string, versions map[int]bool) (bool, error) {
+	if flowJSON == nil {
+		return false, nil
+	}
+	f, err := flow.Parse(flowJSON)
+	if err != nil {
+		return false, err
+	}
+
+	found := false
+	for _, call := range flow.SubflowCalls(f) {
+		if call.Name == name {
+			found = true
+			versions[call.Version] = true
+		}
+	}
+	return found, nil
+}
+
+func sortedVersions(versions map[int]bool) []int {
+	list := make([]int, 0, len(versions))
+	for v := range versions {
+		list = append(list, v)
+	}
+	sort.Ints(list)
+	return list
+}
+
+func (h *SubflowsHandler) getAllSubflows(ctx context.Context) ([]models.Subflow, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT `+subflowColumns+`
+		FROM meta_subflows s
+		ORDER BY s.name ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subflows := []models.Subflow{}
+	for rows.Next() {
+		subflow, err := scanSubflow(rows)
+		if err != nil {
+			return nil, err
+		}
+		subflows = append(subflows, *subflow)
+	}
+
+	return subflows, rows.Err()
+}
+
+func (h *SubflowsHandler) getSubflowByID(ctx context.Context, subflowID string) (*models.Subflow, error) {
+	return scanSubflow(h.db.QueryRowContext(ctx, `
+		SELECT `+subflowColumns+`
+		FROM meta_subflows s
+		WHERE s.id = $1
+	`, subflowID))
+}
+
+// scanSubflow はsubflowColumnsで取得した行をサブフローに変換する
+func scanSubflow(row interface{ Scan(...interface{}) error }) (*models.Subflow, error) {
+	var subflow models.Subflow
+	var inputsJSON, outputsJSON, flowJSON []byte
+
+	err := row.Scan(
+		&subflow.ID,
+		&subflow.Name,
+		&subflow.Description,
+		&inputsJSON,
+		&outputsJSON,
+		&flowJSON,
+		&subflow.CreatedAt,
+		&subflow.UpdatedAt,
+		&subflow.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := decodeParams(inputsJSON, outputsJSON, &subflow.Inputs, &subflow.Outputs); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(flowJSON, &subflow.Flow); err != nil {
+		return nil, err
+	}
+
+	return &subflow, nil
+}
+
+func respondSubflowError(w http.ResponseWriter, err error) {
+	if err == sql.ErrNoRows {
+		utils.RespondNotFound(w, "Subflow not found")
+		return
+	}
+	utils.RespondInternalError(w, fmt.Sprintf("Failed to get subflow: %v", err))
+}
@@ -57,6 +57,21 @@ func (h *TablesHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 既存のテーブル（ログイン情報を持つテーブル・システムテーブル等）をテーブル定義として登録しない
+	if models.ReadOnlyTables[req.Name] {
+		respondReadOnlyTable(w, req.Name)
+		return
+	}
+	exists, err := h.tableExists(ctx, req.Name)
+	if err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to check table: %v", err))
+		return
+	}
+	if exists {
+		utils.RespondConflict(w, "Table with the same name already exists", nil)
+		return
+	}
+
 	// MetaDBにテーブル定義を保存
 	tableID, err := h.createTableMetadata(ctx, req.Name, req.Columns)
 	if err != nil {
@@ -101,6 +116,10 @@ func (h *TablesHandler) Update(w http.ResponseWriter, r *http.Request) {
 		utils.RespondInternalError(w, fmt.Sprintf("Failed to get table: %v", err))
 		return
 	}
+	if models.ReadOnlyTables[existingTable.Name] {
+		respondReadOnlyTable(w, existingTable.Name)
+		return
+	}
 
 	// 更新処理（簡易版：カラム追加のみサポート）
 	if len(req.Columns) > 0 {
@@ -135,6 +154,10 @@ func (h *TablesHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		utils.RespondInternalError(w, fmt.Sprintf("Failed to get table: %v", err))
 		return
 	}
+	if models.ReadOnlyTables[table.Name] {
+		respondReadOnlyTable(w, table.Name)
+		return
+	}
 
 	// 実際のテーブルを削除
 	if err := h.dropActualTable(ctx, table.Name); err != nil {
@@ -191,6 +214,19 @@ func (h *TablesHandler) ImportCSV(w http.ResponseWriter, r *http.Request) {
 
 // Helper methods
 
+// respondReadOnlyTable はログイン情報を持つテーブルを変更できないエラーを返す
+// （カラムの追加でロール等を書き込めるようにしたり、削除で認証情報を失ったりしないようにする）
+func respondReadOnlyTable(w http.ResponseWriter, name string) {
+	utils.RespondForbidden(w, fmt.Sprintf("Table %q stores login data and cannot be modified", name))
+}
+
+// tableExists は同じ名前のテーブルがデータベースに存在するかを返す
+func (h *TablesHandler) tableExists(ctx context.Context, name string) (bool, error) {
+	var exists bool
+	err := h.db.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists)
+	return exists, err
+}
+
 func (h *TablesHandler) getAllTables(ctx context.Context) ([]models.Table, error) {
 	query := `
 		SELECT id, name, created_at, updated_at
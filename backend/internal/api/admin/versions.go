@@ -0,0 +1,377 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/necorox/FlowCore/backend/internal/flow"
+	"github.com/necorox/FlowCore/backend/internal/models"
+	"github.com/necorox/FlowCore/backend/internal/utils"
+)
+
+// ListVersions はエンドポイントのフローバージョン一覧を取得する
+func (h *EndpointsHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	endpointID := chi.URLParam(r, "id")
+
+	if _, err := h.getEndpointByID(ctx, endpointID); err != nil {
+		respondEndpointError(w, err)
+		return
+	}
+
+	versions, err := h.getVersions(ctx, endpointID)
+	if err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to get versions: %v", err))
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.FlowVersionsResponse{Versions: versions})
+}
+
+// GetVersion は指定バージョンのフローを取得する
+func (h *EndpointsHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	endpointID := chi.URLParam(r, "id")
+
+	version, ok := versionParam(w, chi.URLParam(r, "version"), "version")
+	if !ok {
+		return
+	}
+
+	v, err := h.getVersion(ctx, endpointID, version)
+	if err != nil {
+		respondVersionError(w, err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, v)
+}
+
+// DiffVersions は2つのバージョン間のフローの差分を取得する
+func (h *EndpointsHandler) DiffVersions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	endpointID := chi.URLParam(r, "id")
+
+	from, ok := versionParam(w, r.URL.Query().Get("from"), "from")
+	if !ok {
+		return
+	}
+	to, ok := versionParam(w, r.URL.Query().Get("to"), "to")
+	if !ok {
+		return
+	}
+
+	fromVersion, err := h.getVersion(ctx, endpointID, from)
+	if err != nil {
+		respondVersionError(w, err)
+		return
+	}
+	toVersion, err := h.getVersion(ctx, endpointID, to)
+	if err != nil {
+		respondVersionError(w, err)
+		return
+	}
+
+	diff := flow.Diff(fromVersion.Flow, toVersion.Flow)
+	diff.From = from
+	diff.To = to
+	diff.Settings = changedSettings(fromVersion, toVersion)
+
+	utils.RespondJSON(w, http.StatusOK, diff)
+}
+
+// Deploy は指定バージョンをRuntime APIで実行されるバージョンとしてデプロイする
+func (h *EndpointsHandler) Deploy(w http.ResponseWriter, r *http.Request) {
+	endpointID := chi.URLParam(r, "id")
+
+	version, ok := versionParam(w, chi.URLParam(r, "version"), "version")
+	if !ok {
+		return
+	}
+
+	h.deployVersion(w, r, endpointID, version, "deploy")
+}
+
+// Rollback は以前のバージョンを再デプロイする
+// バージョンを省略した場合は直前にデプロイされていたバージョンに戻す
+func (h *EndpointsHandler) Rollback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	endpointID := chi.URLParam(r, "id")
+
+	var req models.RollbackRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondValidationError(w, map[string]string{"body": "Invalid JSON"})
+			return
+		}
+	}
+
+	endpoint, err := h.getEndpointByID(ctx, endpointID)
+	if err != nil {
+		respondEndpointError(w, err)
+		return
+	}
+
+	version := 0
+	if req.Version != nil {
+		version = *req.Version
+	} else {
+		previous, err := h.getPreviousDeployedVersion(ctx, endpointID, endpoint.DeployedVersion)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				utils.RespondValidationError(w, map[string]string{"version": "No previous deployment to roll back to"})
+				return
+			}
+			utils.RespondInternalError(w, fmt.Sprintf("Failed to get deployments: %v", err))
+			return
+		}
+		version = previous
+	}
+
+	if endpoint.DeployedVersion != nil && *endpoint.DeployedVersion == version {
+		utils.RespondValidationError(w, map[string]string{"version": "Version is already deployed"})
+		return
+	}
+
+	h.deployVersion(w, r, endpointID, version, "rollback")
+}
+
+// ListDeployments はエンドポイントのデプロイ履歴を取得する
+func (h *EndpointsHandler) ListDeployments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	endpointID := chi.URLParam(r, "id")
+
+	if _, err := h.getEndpointByID(ctx, endpointID); err != nil {
+		respondEndpointError(w, err)
+		return
+	}
+
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT d.id, d.endpoint_id, v.version, d.action, d.created_at
+		FROM deployments d
+		JOIN flow_versions v ON v.id = d.version_id
+		WHERE d.endpoint_id = $1
+		ORDER BY d.created_at DESC
+	`, endpointID)
+	if err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to get deployments: %v", err))
+		return
+	}
+	defer rows.Close()
+
+	deployments := []models.Deployment{}
+	for rows.Next() {
+		var d models.Deployment
+		if err := rows.Scan(&d.ID, &d.EndpointID, &d.Version, &d.Action, &d.CreatedAt); err != nil {
+			utils.RespondInternalError(w, fmt.Sprintf("Failed to get deployments: %v", err))
+			return
+		}
+		deployments = append(deployments, d)
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.DeploymentsResponse{Deployments: deployments})
+}
+
+// Helper methods
+
+func (h *EndpointsHandler) deployVersion(w http.ResponseWriter, r *http.Request, endpointID string, version int, action string) {
+	ctx := r.Context()
+
+	v, err := h.getVersion(ctx, endpointID, version)
+	if err != nil {
+		respondVersionError(w, err)
+		return
+	}
+
+	// 検証導入前に保存されたバージョンもあるため、デプロイ時に再検証する
+	if err := flow.Validate(v.Flow, h.registry); err != nil {
+		utils.RespondValidationError(w, err)
+		return
+	}
+	// 呼び出すサブフローのバージョンが削除されていないことを確認する
+	if !checkSubflowCalls(w, ctx, h.db, v.Flow) {
+		return
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to begin transaction: %v", err))
+		return
+	}
+	defer tx.Rollback()
+
+	if err := lockCalledSubflows(ctx, tx, v.Flow); err != nil {
+		if !respondCalledSubflowDeleted(w, err) {
+			utils.RespondInternalError(w, fmt.Sprintf("Failed to lock subflows: %v", err))
+		}
+		return
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE meta_endpoints SET deployed_version_id = $1, updated_at = NOW() WHERE id = $2
+	`, v.ID, endpointID); err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to deploy version: %v", err))
+		return
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO deployments (endpoint_id, version_id, action) VALUES ($1, $2, $3)
+	`, endpointID, v.ID, action); err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to record deployment: %v", err))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to deploy version: %v", err))
+		return
+	}
+
+	h.notifyChange()
+
+	endpoint, err := h.getEndpointByID(ctx, endpointID)
+	if err != nil {
+		utils.RespondInternalError(w, fmt.Sprintf("Failed to get endpoint: %v", err))
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, endpoint)
+}
+
+func (h *EndpointsHandler) getVersions(ctx context.Context, endpointID string) ([]models.FlowVersion, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT v.id, v.endpoint_id, v.version, v.id = e.deployed_version_id, v.created_at
+		FROM flow_versions v
+		JOIN meta_endpoints e ON e.id = v.endpoint_id
+		WHERE v.endpoint_id = $1
+		ORDER BY v.version DESC
+	`, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []models.FlowVersion{}
+	for rows.Next() {
+		var v models.FlowVersion
+		var deployed sql.NullBool
+		if err := rows.Scan(&v.ID, &v.EndpointID, &v.Version, &deployed, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		v.Deployed = deployed.Bool
+		versions = append(versions, v)
+	}
+
+	return versions, rows.Err()
+}
+
+func (h *EndpointsHandler) getVersion(ctx context.Context, endpointID string, version int) (*models.FlowVersion, error) {
+	var v models.FlowVersion
+	var deployed sql.NullBool
+	var flowJSON, limitsJSON, authJSON, requestSchema []byte
+
+	err := h.db.QueryRowContext(ctx, `
+		SELECT v.id, v.endpoint_id, v.version, v.flow_definition, v.path, v.limits, v.auth, v.request_schema,
+			v.id = e.deployed_version_id, v.created_at
+		FROM flow_versions v
+		JOIN meta_endpoints e ON e.id = v.endpoint_id
+		WHERE v.endpoint_id = $1 AND v.version = $2
+	`, endpointID, version).Scan(&v.ID, &v.EndpointID, &v.Version, &flowJSON, &v.Path, &limitsJSON, &authJSON, &requestSchema, &deployed, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	v.Deployed = deployed.Bool
+
+	// フロー定義・実行制限・認証設定をデコード
+	if v.Flow, err = flow.Parse(flowJSON); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(limitsJSON, &v.Limits); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(authJSON, &v.Auth); err != nil {
+		return nil, err
+	}
+	if requestSchema != nil {
+		v.RequestSchema = json.RawMessage(requestSchema)
+	}
+
+	return &v, nil
+}
+
+// getPreviousDeployedVersion はデプロイ履歴から現在と異なる直近のバージョンを返す
+func (h *EndpointsHandler) getPreviousDeployedVersion(ctx context.Context, endpointID string, current *int) (int, error) {
+	currentVersion := 0
+	if current != nil {
+		currentVersion = *current
+	}
+
+	var version int
+	err := h.db.QueryRowContext(ctx, `
+		SELECT v.version
+		FROM deployments d
+		JOIN flow_versions v ON v.id = d.version_id
+		WHERE d.endpoint_id = $1 AND v.version <> $2
+		ORDER BY d.created_at DESC
+		LIMIT 1
+	`, endpointID, currentVersion).Scan(&version)
+	return version, err
+}
+
+// changedSettings は2つのバージョン間で変更された設定の名前を返す
+func changedSettings(from, to *models.FlowVersion) []string {
+	settings := []string{}
+	if from.Path != to.Path {
+		settings = append(settings, "path")
+	}
+	if !reflect.DeepEqual(from.Limits, to.Limits) {
+		settings = append(settings, "limits")
+	}
+	if !reflect.DeepEqual(from.Auth, to.Auth) {
+		settings = append(settings, "auth")
+	}
+	if !equalJSON(from.RequestSchema, to.RequestSchema) {
+		settings = append(settings, "request_schema")
+	}
+	return settings
+}
+
+// equalJSON は2つのJSONが同じ値かを返す（キーの順序・空白は区別しない）
+func equalJSON(a, b json.RawMessage) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	var av, bv interface{}
+	if json.Unmarshal(a, &av) != nil || json.Unmarshal(b, &bv) != nil {
+		return string(a) == string(b)
+	}
+	return reflect.DeepEqual(av, bv)
+}
+
+func versionParam(w http.ResponseWriter, value, name string) (int, bool) {
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		utils.RespondValidationError(w, map[string]string{name: "Version must be a positive integer"})
+		return 0, false
+	}
+	return version, true
+}
+
+func respondEndpointError(w http.ResponseWriter, err error) {
+	if err == sql.ErrNoRows {
+		utils.RespondNotFound(w, "Endpoint not found")
+		return
+	}
+	utils.RespondInternalError(w, fmt.Sprintf("Failed to get endpoint: %v", err))
+}
+
+func respondVersionError(w http.ResponseWriter, err error) {
+	if err == sql.ErrNoRows {
+		utils.RespondNotFound(w, "Version not found")
+		return
+	}
+	utils.RespondInternalError(w, fmt.Sprintf("Failed to get version: %v", err))
+}
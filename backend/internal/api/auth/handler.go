@@ -0,0 +1,228 @@
+// Package auth はエンドユーザー向けの認証API（内部IdP）のハンドラーを提供する
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/necorox/FlowCore/backend/internal/authz"
+	"github.com/necorox/FlowCore/backend/internal/identity"
+	"github.com/necorox/FlowCore/backend/internal/models"
+	"github.com/necorox/FlowCore/backend/internal/utils"
+)
+
+// maxBodyBytes は認証APIのリクエストボディの上限
+const maxBodyBytes = 64 << 10
+
+// Handler は認証APIのハンドラー
+type Handler struct {
+	identity *identity.Service
+}
+
+// NewHandler は新しいHandlerを作成する
+func NewHandler(service *identity.Service) *Handler {
+	return &Handler{identity: service}
+}
+
+// Signup はユーザーを登録し、トークンを発行する
+func (h *Handler) Signup(w http.ResponseWriter, r *http.Request) {
+	var req models.SignupRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	resp, err := h.identity.Signup(r.Context(), req.Email, req.Password)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, resp)
+}
+
+// Login はメールアドレスとパスワードでログインし、トークンを発行する
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Email == "" || req.Password == "" {
+		utils.RespondUnauthorized(w, "Invalid email or password")
+		return
+	}
+
+	resp, err := h.identity.Login(r.Context(), req.Email, req.Password)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, resp)
+}
+
+// Refresh はリフレッシュトークンをローテーションし、新しいトークンを発行する
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.RefreshToken == "" {
+		utils.RespondValidationError(w, map[string]string{"refresh_token": "Refresh token is required"})
+		return
+	}
+
+	resp, err := h.identity.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, resp)
+}
+
+// Logout はリフレッシュトークンを失効させる
+// トークンの有無を推測されないよう、存在しないトークンの場合も204を返す
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.RefreshToken == "" {
+		utils.RespondValidationError(w, map[string]string{"refresh_token": "Refresh token is required"})
+		return
+	}
+
+	if err := h.identity.Logout(r.Context(), req.RefreshToken); err != nil {
+		log.Printf("Failed to logout: %v", err)
+		utils.RespondInternalError(w, "Internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangePassword はログイン中のユーザーのパスワードを変更する
+// 変更後は、すべてのログインのリフレッシュトークンが失効する
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	token, ok := authz.BearerToken(r)
+	if !ok {
+		utils.RespondUnauthorized(w, "Authentication required")
+		return
+	}
+	claims, err := h.identity.VerifyAccessToken(r.Context(), token)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.CurrentPassword == "" {
+		utils.RespondValidationError(w, map[string]string{"current_password": "Current password is required"})
+		return
+	}
+
+	if err := h.identity.ChangePassword(r.Context(), claims.Subject, req.CurrentPassword, req.NewPassword); err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyEmail は確認メールのトークンを検証し、メールアドレスを確認済みにする
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.VerifyEmailRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Token == "" {
+		utils.RespondValidationError(w, map[string]string{"token": "Token is required"})
+		return
+	}
+
+	user, err := h.identity.VerifyEmail(r.Context(), req.Token)
+	if errors.Is(err, identity.ErrInvalidToken) {
+		utils.RespondValidationError(w, map[string]string{"token": "Token is invalid, expired or already used"})
+		return
+	}
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, user)
+}
+
+// ResendVerification は確認メールを再送する
+// メールアドレスの登録の有無を推測されないよう、常に204を返す
+func (h *Handler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req models.ResendVerificationRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Email == "" {
+		utils.RespondValidationError(w, map[string]string{"email": "Email is required"})
+		return
+	}
+
+	if err := h.identity.ResendVerification(r.Context(), req.Email); err != nil {
+		log.Printf("Failed to resend verification email: %v", err)
+		utils.RespondInternalError(w, "Internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// JWKS はアクセストークンの検証に使用する公開鍵の一覧を返す
+func (h *Handler) JWKS(w http.ResponseWriter, r *http.Request) {
+	jwks, err := h.identity.JWKS(r.Context())
+	if err != nil {
+		log.Printf("Failed to get signing keys: %v", err)
+		utils.RespondInternalError(w, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	utils.RespondJSON(w, http.StatusOK, jwks)
+}
+
+// decode はリクエストボディをJSONとして読み込む（失敗した場合はエラーレスポンスを返してfalse）
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			utils.RespondPayloadTooLarge(w, "Request body is too large")
+			return false
+		}
+		utils.RespondValidationError(w, map[string]string{"body": "Invalid JSON"})
+		return false
+	}
+	return true
+}
+
+// respondAuthError は内部IdPのエラーをレスポンスに変換する
+func respondAuthError(w http.ResponseWriter, err error) {
+	var validationErr identity.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		utils.RespondValidationError(w, map[string]string(validationErr))
+	case errors.Is(err, identity.ErrEmailTaken):
+		utils.RespondConflict(w, "Email is already registered", nil)
+	case errors.Is(err, identity.ErrInvalidCredentials):
+		utils.RespondUnauthorized(w, "Invalid email or password")
+	case errors.Is(err, identity.ErrEmailNotVerified):
+		utils.RespondError(w, http.StatusForbidden, "EMAIL_NOT_VERIFIED", "Email address is not verified", nil)
+	case errors.Is(err, identity.ErrTokenReused), errors.Is(err, identity.ErrInvalidToken):
+		utils.RespondUnauthorized(w, "Invalid or expired token")
+	default:
+		log.Printf("Authentication failed: %v", err)
+		utils.RespondInternalError(w, "Internal server error")
+	}
+}
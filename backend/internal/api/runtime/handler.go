@@ -2,79 +2,357 @@ package runtime
 
 import (
 	"context"
-	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"sync"
 
+	"github.com/necorox/FlowCore/backend/internal/authz"
 	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/flow"
+	"github.com/necorox/FlowCore/backend/internal/jsonschema"
+	"github.com/necorox/FlowCore/backend/internal/models"
+	"github.com/necorox/FlowCore/backend/internal/routing"
 	"github.com/necorox/FlowCore/backend/internal/utils"
 )
 
 // Handler はRuntime APIのハンドラー
 type Handler struct {
-	db *database.DB
+	db     *database.DB
+	engine *flow.Engine
+
+	mu        sync.Mutex
+	router    *routing.Router
+	endpoints map[string]*endpointInfo
+	stale     bool
 }
 
 // NewHandler は新しいHandlerを作成する
-func NewHandler(db *database.DB) *Handler {
-	return &Handler{db: db}
+func NewHandler(db *database.DB, engine *flow.Engine) *Handler {
+	return &Handler{db: db, engine: engine, stale: true}
+}
+
+// Invalidate はルーティングテーブルを破棄し、次のリクエストで再構築させる
+func (h *Handler) Invalidate() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stale = true
+}
+
+// Reload はMetaDBからエンドポイントを読み込み、ルーティングテーブルを再構築する
+func (h *Handler) Reload(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.reload(ctx)
+}
+
+// Resolve はリクエストにマッチするエンドポイントを取得してcontextに設定するミドルウェア
+// マッチするエンドポイントがない場合は404を返す。後続のミドルウェアはAuthRuleでエンドポイントの認証設定、
+// authz.ResourceFromでエンドポイントのID・タグ（APIキーの呼び出せる範囲の確認に使用する）を参照できる
+func (h *Handler) Resolve(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m, ok := h.resolve(w, r)
+		if !ok {
+			return
+		}
+		ctx := context.WithValue(r.Context(), matchKey{}, m)
+		ctx = authz.WithResource(ctx, &authz.Resource{EndpointID: m.endpoint.ID, Tags: m.endpoint.Tags})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// AuthRule はResolveで取得したエンドポイントの認証設定を返す
+func AuthRule(r *http.Request) *models.EndpointAuth {
+	if m, ok := r.Context().Value(matchKey{}).(*endpointMatch); ok {
+		return &m.endpoint.Auth
+	}
+	return nil
 }
 
 // Execute は動的エンドポイントを実行する
-// 現時点では簡易実装（モック）
+// 認証された呼び出し元（authz.PrincipalFrom）はリクエストの auth としてフローに渡す
 func (h *Handler) Execute(w http.ResponseWriter, r *http.Request) {
-	// リクエストパスとメソッドを取得
-	path := r.URL.Path
-	method := r.Method
+	ctx := r.Context()
+
+	// リクエストにマッチするエンドポイントを取得（Resolveを経由していない場合はここで取得する）
+	m, ok := ctx.Value(matchKey{}).(*endpointMatch)
+	if !ok {
+		if m, ok = h.resolve(w, r); !ok {
+			return
+		}
+	}
+	endpoint, params := m.endpoint, m.params
+
+	limits := h.engine.Limits().Restrict(flow.EndpointLimits(endpoint.Limits))
 
-	// エンドポイント定義を取得
-	endpoint, err := h.getEndpointByPath(r.Context(), method, path)
+	// リクエストボディのサイズを制限
+	if limits.MaxRequestBytes > 0 && r.Body != nil {
+		if r.ContentLength > limits.MaxRequestBytes {
+			respondPayloadTooLarge(w, limits.MaxRequestBytes)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limits.MaxRequestBytes)
+	}
+
+	// リクエストを解析
+	req, err := flow.NewRequest(r)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			utils.RespondNotFound(w, "Endpoint not found")
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondPayloadTooLarge(w, limits.MaxRequestBytes)
 			return
 		}
-		utils.RespondInternalError(w, fmt.Sprintf("Failed to get endpoint: %v", err))
+		utils.RespondValidationError(w, map[string]string{"body": err.Error()})
 		return
 	}
+	req.Params = params
+	req.Auth = authz.PrincipalFrom(ctx)
 
-	// TODO: フローエンジンでエンドポイントを実行
-	// 現時点ではモックレスポンスを返す
-	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
-		"message":      "Flow execution not yet implemented",
-		"endpoint_id":  endpoint.ID,
-		"endpoint_name": endpoint.Name,
-		"method":       endpoint.Method,
-		"path":         endpoint.Path,
-	})
+	// リクエストボディをJSON Schemaで検証
+	if endpoint.RequestSchema != nil {
+		if err := endpoint.RequestSchema.Validate(req.Body); err != nil {
+			respondSchemaError(w, err)
+			return
+		}
+	}
+
+	// フローを実行（実行回数・実行時間はエンドポイントの制限を適用）
+	result, err := h.engine.Run(ctx, endpoint.Flow, req, flow.Options{Limits: limits})
+	if err != nil {
+		respondFlowError(w, endpoint, err)
+		return
+	}
+
+	writeResponse(w, result.Response, limits.MaxResponseBytes)
+}
+
+// respondFlowError はフローの実行エラーを分類し、フローのerror_responsesに従ってエラーレスポンスとして返す
+// 内部のエラー（DBのエラー等）の内容はレスポンスに含めず、ログにのみ出力する
+func respondFlowError(w http.ResponseWriter, endpoint *endpointInfo, err error) {
+	mapped := flow.MapError(endpoint.Flow, err)
+	if mapped.Class == flow.ErrorInternal || mapped.Class == flow.ErrorLimit {
+		log.Printf("Flow execution failed: %s %s: %v", endpoint.Method, endpoint.Path, err)
+	}
+	utils.RespondError(w, mapped.StatusCode, mapped.Code, mapped.Message, mapped.Details)
+}
+
+// respondSchemaError はJSON Schemaの違反をJSONポインタ → メッセージのマップで返す
+func respondSchemaError(w http.ResponseWriter, err error) {
+	var schemaErr *jsonschema.ValidationError
+	if errors.As(err, &schemaErr) {
+		utils.RespondValidationError(w, schemaErr.Details())
+		return
+	}
+	utils.RespondValidationError(w, map[string]string{"body": err.Error()})
+}
+
+func respondPayloadTooLarge(w http.ResponseWriter, limit int64) {
+	utils.RespondPayloadTooLarge(w, fmt.Sprintf("Request body exceeds the limit of %d bytes", limit))
+}
+
+// writeResponse はフローのレスポンスをクライアントに書き込む
+// maxBytesが0より大きい場合、ボディがそのサイズを超えるとエラーを返す
+func writeResponse(w http.ResponseWriter, resp *flow.Response, maxBytes int64) {
+	var data []byte
+	switch body := resp.Body.(type) {
+	case nil:
+	case string:
+		if resp.Format != "" && resp.Format != flow.FormatJSON {
+			// text/html/csvはエンコード済みの文字列をそのまま書き込む
+			data = []byte(body)
+			break
+		}
+		data, _ = json.Marshal(body)
+		data = append(data, '\n')
+	default:
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			log.Printf("Failed to encode response: %v", err)
+			utils.RespondInternalError(w, "Internal server error")
+			return
+		}
+		data = append(encoded, '\n')
+	}
+	if maxBytes > 0 && int64(len(data)) > maxBytes {
+		utils.RespondError(w, http.StatusInternalServerError, "RESPONSE_TOO_LARGE",
+			fmt.Sprintf("Response body exceeds the limit of %d bytes", maxBytes), nil)
+		return
+	}
+
+	for key, values := range resp.Headers {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+
+	if data == nil {
+		w.WriteHeader(resp.StatusCode)
+		return
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(data)
 }
 
 // Helper methods
 
 type endpointInfo struct {
-	ID     string
-	Name   string
-	Method string
-	Path   string
+	ID      string
+	Name    string
+	Method  string
+	Path    string
+	Version int
+	Flow    *models.Flow
+	Limits  models.EndpointLimits
+	Auth    models.EndpointAuth
+	Tags    []string
+
+	RequestSchema *jsonschema.Schema
+}
+
+// endpointMatch はリクエストにマッチしたエンドポイントとパスパラメータ
+type endpointMatch struct {
+	endpoint *endpointInfo
+	params   map[string]interface{}
+}
+
+type matchKey struct{}
+
+// resolve はリクエストにマッチするエンドポイントを探す
+// 見つからない場合・読み込みに失敗した場合はエラーレスポンスを返してfalse
+func (h *Handler) resolve(w http.ResponseWriter, r *http.Request) (*endpointMatch, bool) {
+	endpoint, params, err := h.match(r.Context(), r.Method, routing.NormalizePath(r.URL.Path))
+	if err != nil {
+		log.Printf("Failed to load endpoints: %v", err)
+		utils.RespondInternalError(w, "Internal server error")
+		return nil, false
+	}
+	if endpoint == nil {
+		utils.RespondNotFound(w, "Endpoint not found")
+		return nil, false
+	}
+	return &endpointMatch{endpoint: endpoint, params: params}, true
+}
+
+// match はルーティングテーブルからエンドポイントを探す
+func (h *Handler) match(ctx context.Context, method, path string) (*endpointInfo, map[string]interface{}, error) {
+	h.mu.Lock()
+	if h.stale || h.router == nil {
+		if err := h.reload(ctx); err != nil {
+			h.mu.Unlock()
+			return nil, nil, err
+		}
+	}
+	router, endpoints := h.router, h.endpoints
+	h.mu.Unlock()
+
+	route, params, ok := router.Match(method, path)
+	if !ok {
+		return nil, nil, nil
+	}
+	return endpoints[route.ID], params, nil
+}
+
+// reload はh.muを保持した状態で呼び出す
+func (h *Handler) reload(ctx context.Context) error {
+	endpoints, err := h.loadEndpoints(ctx)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]*endpointInfo, len(endpoints))
+	routes := make([]routing.Route, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		pattern, err := routing.ParsePattern(endpoint.Path)
+		if err != nil {
+			log.Printf("Skipping endpoint %s (%s %s): %v", endpoint.ID, endpoint.Method, endpoint.Path, err)
+			continue
+		}
+		byID[endpoint.ID] = endpoint
+		routes = append(routes, routing.Route{ID: endpoint.ID, Method: endpoint.Method, Pattern: pattern})
+	}
+
+	router, conflicts := routing.NewRouter(routes)
+	for _, err := range conflicts {
+		log.Printf("Skipping endpoint: %v", err)
+	}
+
+	h.router = router
+	h.endpoints = byID
+	h.stale = false
+	return nil
 }
 
-func (h *Handler) getEndpointByPath(ctx context.Context, method, path string) (*endpointInfo, error) {
+func (h *Handler) loadEndpoints(ctx context.Context) ([]*endpointInfo, error) {
+	// デプロイされたバージョンのフローと設定のみを読み込む（下書きは実行しない）
+	// パスはフローが参照するパスパラメータを決めるため、デプロイされたバージョンの値を使用する
+	// メソッド・タグはバージョンに含まれないため、エンドポイントの現在の値を使用する
 	query := `
-		SELECT id, name, method, path
-		FROM meta_endpoints
-		WHERE method = $1 AND path = $2
-		LIMIT 1
+		SELECT e.id, e.name, e.method, v.path, v.limits, v.auth, e.tags, v.request_schema, v.version, v.flow_definition
+		FROM meta_endpoints e
+		JOIN flow_versions v ON v.id = e.deployed_version_id
+		ORDER BY e.created_at ASC, e.id ASC
 	`
-	var endpoint endpointInfo
-	err := h.db.QueryRowContext(ctx, query, method, path).Scan(
-		&endpoint.ID,
-		&endpoint.Name,
-		&endpoint.Method,
-		&endpoint.Path,
-	)
+	rows, err := h.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	var endpoints []*endpointInfo
+	for rows.Next() {
+		var endpoint endpointInfo
+		var flowJSON, limitsJSON, authJSON, tagsJSON, requestSchema []byte
+		if err := rows.Scan(
+			&endpoint.ID,
+			&endpoint.Name,
+			&endpoint.Method,
+			&endpoint.Path,
+			&limitsJSON,
+			&authJSON,
+			&tagsJSON,
+			&requestSchema,
+			&endpoint.Version,
+			&flowJSON,
+		); err != nil {
+			return nil, err
+		}
+
+		// フロー定義を解析
+		endpoint.Flow, err = flow.Parse(flowJSON)
+		if err != nil {
+			log.Printf("Skipping endpoint %s: %v", endpoint.ID, err)
+			continue
+		}
+		if err := json.Unmarshal(limitsJSON, &endpoint.Limits); err != nil {
+			log.Printf("Skipping endpoint %s: invalid limits: %v", endpoint.ID, err)
+			continue
+		}
+		// 認証設定を解析できないエンドポイントは公開しない
+		if err := json.Unmarshal(authJSON, &endpoint.Auth); err != nil || !models.ValidAuthModes[endpoint.Auth.Mode] {
+			log.Printf("Skipping endpoint %s: invalid auth %s", endpoint.ID, authJSON)
+			continue
+		}
+		if err := json.Unmarshal(tagsJSON, &endpoint.Tags); err != nil {
+			log.Printf("Skipping endpoint %s: invalid tags: %v", endpoint.ID, err)
+			continue
+		}
+		if requestSchema != nil {
+			if endpoint.RequestSchema, err = jsonschema.Compile(requestSchema); err != nil {
+				log.Printf("Skipping endpoint %s: %v", endpoint.ID, err)
+				continue
+			}
+		}
+		endpoints = append(endpoints, &endpoint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return &endpoint, nil
+	return endpoints, nil
 }
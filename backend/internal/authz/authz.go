@@ -0,0 +1,181 @@
+// Package authz はRuntime APIのエンドポイントごとの認証・認可を提供する
+// エンドポイントの認証方式（public・jwt・api_key・admin）に応じて資格情報を検証し、
+// 必要なロール・スコープを確認した呼び出し元をリクエストのcontextに設定する
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/necorox/FlowCore/backend/internal/models"
+	"github.com/necorox/FlowCore/backend/internal/utils"
+)
+
+var (
+	// ErrUnauthenticated は資格情報がない・不正な場合のエラー
+	ErrUnauthenticated = errors.New("authentication required")
+	// ErrForbidden は呼び出し元に必要なロール・スコープがない場合のエラー
+	ErrForbidden = errors.New("insufficient permissions")
+)
+
+// Verifier は資格情報（トークン・APIキー）を検証し、呼び出し元を返す
+// 資格情報が不正な場合はErrUnauthenticated、リクエストの対象（ResourceFrom）を呼び出せない場合はErrForbidden（をラップしたエラー）を返す
+type Verifier func(ctx context.Context, credential string) (*models.Principal, error)
+
+// Authenticator は認証方式ごとの資格情報の検証を保持する
+// 検証が設定されていない認証方式のエンドポイントは、すべてのリクエストを拒否する
+type Authenticator struct {
+	JWT    Verifier // Authorization: Bearer <アクセストークン>
+	APIKey Verifier // X-API-Key: <キー> または Authorization: Bearer <キー>
+	Admin  Verifier // Authorization: Bearer <管理者のトークン>
+}
+
+// Authenticate はエンドポイントの認証設定に従ってリクエストの呼び出し元を認証・認可する
+// publicの場合はnilを返す
+func (a *Authenticator) Authenticate(r *http.Request, rule *models.EndpointAuth) (*models.Principal, error) {
+	mode := models.AuthPublic
+	if rule != nil && rule.Mode != "" {
+		mode = rule.Mode
+	}
+
+	var verify Verifier
+	var credential string
+	switch mode {
+	case models.AuthPublic:
+		return nil, nil
+	case models.AuthJWT:
+		verify = a.JWT
+		credential, _ = BearerToken(r)
+	case models.AuthAPIKey:
+		verify = a.APIKey
+		credential = APIKey(r)
+	case models.AuthAdmin:
+		verify = a.Admin
+		credential, _ = BearerToken(r)
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q", mode)
+	}
+	if credential == "" {
+		return nil, ErrUnauthenticated
+	}
+	if verify == nil {
+		return nil, fmt.Errorf("%w: %s authentication is not configured", ErrUnauthenticated, mode)
+	}
+
+	principal, err := verify(r.Context(), credential)
+	if err != nil {
+		return nil, err
+	}
+	if !Allowed(principal, rule) {
+		return nil, ErrForbidden
+	}
+	return principal, nil
+}
+
+// Allowed は呼び出し元が設定のロール（いずれか1つ）・スコープ（すべて）を持つかどうかを返す
+func Allowed(principal *models.Principal, rule *models.EndpointAuth) bool {
+	if len(rule.Roles) > 0 && !slices.ContainsFunc(rule.Roles, func(role string) bool {
+		return slices.Contains(principal.Roles, role)
+	}) {
+		return false
+	}
+	for _, scope := range rule.Scopes {
+		if !slices.Contains(principal.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware はruleが返すエンドポイントの認証設定に従ってリクエストを認証・認可するミドルウェアを返す
+// 認証に失敗した場合は401、権限がない場合は403を返し、成功した場合は呼び出し元をcontextに設定する
+func (a *Authenticator) Middleware(rule func(*http.Request) *models.EndpointAuth) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := a.Authenticate(r, rule(r))
+			if err != nil {
+				RespondError(w, err)
+				return
+			}
+			if principal != nil {
+				r = r.WithContext(WithPrincipal(r.Context(), principal))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Rule は常に同じ認証設定を返す（Middlewareに渡す、ルートごとに固定の認証設定）
+func Rule(rule *models.EndpointAuth) func(*http.Request) *models.EndpointAuth {
+	return func(*http.Request) *models.EndpointAuth { return rule }
+}
+
+// RespondError は認証・認可のエラーをレスポンスとして返す（その他のエラーの内容はレスポンスに含めず、ログにのみ出力する）
+func RespondError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrUnauthenticated):
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		utils.RespondUnauthorized(w, "Authentication required")
+	case errors.Is(err, ErrForbidden):
+		utils.RespondForbidden(w, "Insufficient permissions")
+	default:
+		log.Printf("Authentication failed: %v", err)
+		utils.RespondInternalError(w, "Internal server error")
+	}
+}
+
+type principalKey struct{}
+
+// WithPrincipal は呼び出し元を設定したcontextを返す
+func WithPrincipal(ctx context.Context, principal *models.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom はcontextの呼び出し元を返す（認証されていない場合はnil）
+func PrincipalFrom(ctx context.Context) *models.Principal {
+	principal, _ := ctx.Value(principalKey{}).(*models.Principal)
+	return principal
+}
+
+// Resource はリクエストの対象のエンドポイント（APIキーの呼び出せる範囲の確認に使用する）
+type Resource struct {
+	EndpointID string
+	Tags       []string
+}
+
+type resourceKey struct{}
+
+// WithResource はリクエストの対象を設定したcontextを返す
+func WithResource(ctx context.Context, resource *Resource) context.Context {
+	return context.WithValue(ctx, resourceKey{}, resource)
+}
+
+// ResourceFrom はcontextのリクエストの対象を返す（設定されていない場合はnil）
+func ResourceFrom(ctx context.Context) *Resource {
+	resource, _ := ctx.Value(resourceKey{}).(*Resource)
+	return resource
+}
+
+// BearerToken はAuthorizationヘッダーのBearerトークンを返す
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	token = strings.TrimSpace(token)
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// APIKey はX-API-KeyヘッダーまたはAuthorizationヘッダーのBearerトークンからAPIキーを返す
+func APIKey(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get("X-API-Key")); key != "" {
+		return key
+	}
+	key, _ := BearerToken(r)
+	return key
+}
@@ -3,12 +3,20 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+// Querier はDB接続とトランザクションに共通するクエリ操作
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 // DB はデータベース接続を管理する
 type DB struct {
 	conn *sql.DB
@@ -32,6 +40,11 @@ func New(dsn string) (*DB, error) {
 	return &DB{conn: conn}, nil
 }
 
+// NewFromConn は既存の接続からDBを作成する（テストで独自のドライバーを使用する場合など）
+func NewFromConn(conn *sql.DB) *DB {
+	return &DB{conn: conn}
+}
+
 // Close はDB接続を閉じる
 func (db *DB) Close() error {
 	return db.conn.Close()
@@ -42,6 +55,11 @@ func (db *DB) Conn() *sql.DB {
 	return db.conn
 }
 
+// BeginTx はトランザクションを開始する
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return db.conn.BeginTx(ctx, opts)
+}
+
 // ExecContext はクエリを実行する
 func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
 	return db.conn.ExecContext(ctx, query, args...)
@@ -56,3 +74,37 @@ func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{
 func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
 	return db.conn.QueryRowContext(ctx, query, args...)
 }
+
+// IsUniqueViolation は一意制約違反のエラーかどうかを返す
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// IsSerializationFailure はシリアライゼーション失敗・デッドロックのエラー（再試行で成功しうるエラー）かどうかを返す
+func IsSerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && (pqErr.Code == "40001" || pqErr.Code == "40P01")
+}
+
+// IsConflict は既存のデータとの競合（一意制約・外部キー制約・排他制約の違反）のエラーかどうかを返す
+func IsConflict(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	switch pqErr.Code {
+	case "23505", "23503", "23P01":
+		return true
+	}
+	return false
+}
+
+// IsInvalidData は値が不正なエラー（データ例外・NOT NULL制約・CHECK制約の違反）かどうかを返す
+func IsInvalidData(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code.Class() == "22" || pqErr.Code == "23502" || pqErr.Code == "23514"
+}
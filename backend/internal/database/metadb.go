@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// TableByName はテーブル名でMetaDBのテーブル定義を取得する
+// テーブルが存在しない場合はsql.ErrNoRowsを返す
+func (db *DB) TableByName(ctx context.Context, name string) (*models.Table, error) {
+	var table models.Table
+	err := db.QueryRowContext(ctx, `
+		SELECT id, name, created_at, updated_at
+		FROM meta_tables
+		WHERE name = $1
+	`, name).Scan(&table.ID, &table.Name, &table.CreatedAt, &table.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, table_id, name, type, required, created_at, updated_at
+		FROM meta_columns
+		WHERE table_id = $1
+		ORDER BY created_at ASC
+	`, table.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var col models.Column
+		if err := rows.Scan(&col.ID, &col.TableID, &col.Name, &col.Type, &col.Required, &col.CreatedAt, &col.UpdatedAt); err != nil {
+			return nil, err
+		}
+		table.Columns = append(table.Columns, col)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &table, nil
+}
+
+// SubflowVersion はサブフロー名とバージョンでMetaDBのサブフローのバージョンを取得する
+// 存在しない場合はsql.ErrNoRowsを返す
+func (db *DB) SubflowVersion(ctx context.Context, name string, version int) (*models.SubflowVersion, error) {
+	var sv models.SubflowVersion
+	var inputsJSON, outputsJSON, flowJSON []byte
+	err := db.QueryRowContext(ctx, `
+		SELECT v.id, v.subflow_id, s.name, v.version, v.inputs, v.outputs, v.flow_definition, v.created_at
+		FROM subflow_versions v
+		JOIN meta_subflows s ON s.id = v.subflow_id
+		WHERE s.name = $1 AND v.version = $2
+	`, name, version).Scan(&sv.ID, &sv.SubflowID, &sv.Name, &sv.Version, &inputsJSON, &outputsJSON, &flowJSON, &sv.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(inputsJSON, &sv.Inputs); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(outputsJSON, &sv.Outputs); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(flowJSON, &sv.Flow); err != nil {
+		return nil, err
+	}
+	return &sv, nil
+}
+
+// AuthSettings はMetaDBの認証設定を取得する
+// 保存された設定で省略された項目はmodels.DefaultAuthConfigの値とする。設定が存在しない場合はsql.ErrNoRowsを返す
+func (db *DB) AuthSettings(ctx context.Context) (*models.AuthSettings, error) {
+	var settings models.AuthSettings
+	var configJSON []byte
+	err := db.QueryRowContext(ctx, `
+		SELECT id, method, config, created_at, updated_at
+		FROM meta_auth_settings
+		ORDER BY created_at
+		LIMIT 1
+	`).Scan(&settings.ID, &settings.Method, &configJSON, &settings.CreatedAt, &settings.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	settings.Config = models.DefaultAuthConfig
+	if err := json.Unmarshal(configJSON, &settings.Config); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
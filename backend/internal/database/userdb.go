@@ -0,0 +1,392 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// ユーザーテーブルに対する操作の種類
+const (
+	OpSelect = "select"
+	OpInsert = "insert"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// validOperators はWHERE条件で使用できる演算子
+var validOperators = map[string]string{
+	"=":           "=",
+	"!=":          "<>",
+	"<>":          "<>",
+	"<":           "<",
+	"<=":          "<=",
+	">":           ">",
+	">=":          ">=",
+	"like":        "LIKE",
+	"ilike":       "ILIKE",
+	"in":          "= ANY",
+	"is null":     "IS NULL",
+	"is not null": "IS NOT NULL",
+}
+
+// Condition はWHERE条件を表す
+type Condition struct {
+	Column   string
+	Operator string
+	Value    interface{}
+}
+
+// Order はORDER BY句の要素を表す
+type Order struct {
+	Column string
+	Desc   bool
+}
+
+// Query はユーザーテーブルに対する動的クエリを表す
+// 識別子はすべてテーブル定義（MetaDB）のホワイトリストと照合される
+type Query struct {
+	Operation string
+	Table     *models.Table
+	Columns   []string                 // SELECT/RETURNINGするカラム（空なら全カラム）
+	Values    []map[string]interface{} // INSERTする行、またはUPDATEする値（先頭のみ）
+	Where     []Condition
+	OrderBy   []Order
+	Limit     int
+	Offset    int
+}
+
+// Build はパラメータ化されたSQLと引数を生成する
+func (q *Query) Build() (string, []interface{}, error) {
+	columns, err := q.resultColumns()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if q.Operation != OpSelect && models.ReadOnlyTables[q.Table.Name] {
+		return "", nil, fmt.Errorf("table %q is read-only", q.Table.Name)
+	}
+
+	var sb strings.Builder
+	var args []interface{}
+	table := pq.QuoteIdentifier(q.Table.Name)
+
+	switch q.Operation {
+	case OpSelect:
+		fmt.Fprintf(&sb, "SELECT %s FROM %s", quoteColumns(columns), table)
+		if err := q.writeWhere(&sb, &args); err != nil {
+			return "", nil, err
+		}
+		if err := q.writeOrderBy(&sb); err != nil {
+			return "", nil, err
+		}
+		if q.Limit > 0 {
+			args = append(args, q.Limit)
+			fmt.Fprintf(&sb, " LIMIT $%d", len(args))
+		}
+		if q.Offset > 0 {
+			args = append(args, q.Offset)
+			fmt.Fprintf(&sb, " OFFSET $%d", len(args))
+		}
+
+	case OpInsert:
+		if len(q.Values) == 0 {
+			return "", nil, fmt.Errorf("insert requires at least one row")
+		}
+		insertColumns, err := q.valueColumns()
+		if err != nil {
+			return "", nil, err
+		}
+		fmt.Fprintf(&sb, "INSERT INTO %s (%s) VALUES ", table, quoteColumns(insertColumns))
+		for i, row := range q.Values {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString("(")
+			for j, col := range insertColumns {
+				if j > 0 {
+					sb.WriteString(", ")
+				}
+				value, ok := row[col]
+				if !ok {
+					sb.WriteString("DEFAULT")
+					continue
+				}
+				args = append(args, toParam(value))
+				fmt.Fprintf(&sb, "$%d", len(args))
+			}
+			sb.WriteString(")")
+		}
+		fmt.Fprintf(&sb, " RETURNING %s", quoteColumns(columns))
+
+	case OpUpdate:
+		if len(q.Values) == 0 || len(q.Values[0]) == 0 {
+			return "", nil, fmt.Errorf("update requires at least one value")
+		}
+		if len(q.Where) == 0 {
+			return "", nil, fmt.Errorf("update requires at least one where condition")
+		}
+		updateColumns, err := q.valueColumns()
+		if err != nil {
+			return "", nil, err
+		}
+		fmt.Fprintf(&sb, "UPDATE %s SET ", table)
+		for i, col := range updateColumns {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			args = append(args, toParam(q.Values[0][col]))
+			fmt.Fprintf(&sb, "%s = $%d", pq.QuoteIdentifier(col), len(args))
+		}
+		if err := q.writeWhere(&sb, &args); err != nil {
+			return "", nil, err
+		}
+		fmt.Fprintf(&sb, " RETURNING %s", quoteColumns(columns))
+
+	case OpDelete:
+		if len(q.Where) == 0 {
+			return "", nil, fmt.Errorf("delete requires at least one where condition")
+		}
+		fmt.Fprintf(&sb, "DELETE FROM %s", table)
+		if err := q.writeWhere(&sb, &args); err != nil {
+			return "", nil, err
+		}
+		fmt.Fprintf(&sb, " RETURNING %s", quoteColumns(columns))
+
+	default:
+		return "", nil, fmt.Errorf("unsupported operation %q", q.Operation)
+	}
+
+	return sb.String(), args, nil
+}
+
+// Run はクエリを実行し、結果の行を返す
+func (q *Query) Run(ctx context.Context, db Querier) ([]map[string]interface{}, error) {
+	query, args, err := q.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			row[col] = q.fromColumn(col, values[i])
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// column はテーブル定義からカラムを探す
+func (q *Query) column(name string) (*models.Column, bool) {
+	for i := range q.Table.Columns {
+		if q.Table.Columns[i].Name == name {
+			return &q.Table.Columns[i], true
+		}
+	}
+	return nil, false
+}
+
+func (q *Query) resultColumns() ([]string, error) {
+	if len(q.Columns) == 0 {
+		columns := make([]string, 0, len(q.Table.Columns))
+		for _, col := range q.Table.Columns {
+			columns = append(columns, col.Name)
+		}
+		if len(columns) == 0 {
+			return nil, fmt.Errorf("table %q has no columns", q.Table.Name)
+		}
+		return columns, nil
+	}
+
+	for _, name := range q.Columns {
+		if _, ok := q.column(name); !ok {
+			return nil, fmt.Errorf("unknown column %q in table %q", name, q.Table.Name)
+		}
+	}
+	return q.Columns, nil
+}
+
+// valueColumns はINSERT/UPDATEする値のカラムを名前順で返す
+func (q *Query) valueColumns() ([]string, error) {
+	seen := map[string]bool{}
+	for _, row := range q.Values {
+		for name := range row {
+			if _, ok := q.column(name); !ok {
+				return nil, fmt.Errorf("unknown column %q in table %q", name, q.Table.Name)
+			}
+			seen[name] = true
+		}
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("no values to write")
+	}
+
+	columns := make([]string, 0, len(seen))
+	for name := range seen {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+	return columns, nil
+}
+
+func (q *Query) writeWhere(sb *strings.Builder, args *[]interface{}) error {
+	for i, cond := range q.Where {
+		column, ok := q.column(cond.Column)
+		if !ok {
+			return fmt.Errorf("unknown column %q in table %q", cond.Column, q.Table.Name)
+		}
+		op := strings.ToLower(strings.TrimSpace(cond.Operator))
+		if op == "" {
+			op = "="
+		}
+		sqlOp, ok := validOperators[op]
+		if !ok {
+			return fmt.Errorf("unsupported operator %q", cond.Operator)
+		}
+
+		if i == 0 {
+			sb.WriteString(" WHERE ")
+		} else {
+			sb.WriteString(" AND ")
+		}
+
+		col := pq.QuoteIdentifier(cond.Column)
+		switch op {
+		case "is null", "is not null":
+			fmt.Fprintf(sb, "%s %s", col, sqlOp)
+		case "in":
+			values, ok := cond.Value.([]interface{})
+			if !ok {
+				return fmt.Errorf("operator in requires an array value for column %q", cond.Column)
+			}
+			// 要素はカラムの型の配列として渡し、カラムをキャストしない（インデックスを使用できるようにする）
+			params := make([]string, 0, len(values))
+			for _, v := range values {
+				// NULLはどの値とも一致しないため除外する
+				if v != nil {
+					params = append(params, arrayElement(v, column.Type))
+				}
+			}
+			*args = append(*args, pq.Array(params))
+			sqlType, ok := models.ValidColumnTypes[column.Type]
+			if !ok {
+				fmt.Fprintf(sb, "%s::text = ANY($%d::text[])", col, len(*args))
+				break
+			}
+			fmt.Fprintf(sb, "%s = ANY($%d::%s[])", col, len(*args), sqlType)
+		default:
+			*args = append(*args, toParam(cond.Value))
+			fmt.Fprintf(sb, "%s %s $%d", col, sqlOp, len(*args))
+		}
+	}
+	return nil
+}
+
+func (q *Query) writeOrderBy(sb *strings.Builder) error {
+	for i, order := range q.OrderBy {
+		if _, ok := q.column(order.Column); !ok {
+			return fmt.Errorf("unknown column %q in table %q", order.Column, q.Table.Name)
+		}
+		if i == 0 {
+			sb.WriteString(" ORDER BY ")
+		} else {
+			sb.WriteString(", ")
+		}
+		direction := "ASC"
+		if order.Desc {
+			direction = "DESC"
+		}
+		fmt.Fprintf(sb, "%s %s", pq.QuoteIdentifier(order.Column), direction)
+	}
+	return nil
+}
+
+// arrayElement はin演算子の値を配列の要素の文字列に変換する
+// 数値は指数表記にせず（1e+06ではなく1000000）、jsonカラムの値はJSONにする
+func arrayElement(value interface{}, columnType string) string {
+	if columnType == "json" {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(data)
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	}
+	return fmt.Sprint(value)
+}
+
+// toParam はGoの値をクエリパラメータに変換する
+func toParam(value interface{}) interface{} {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil
+		}
+		return string(data)
+	}
+	return value
+}
+
+// fromColumn はスキャンした値をJSONに変換可能な値にする
+func (q *Query) fromColumn(column string, value interface{}) interface{} {
+	data, ok := value.([]byte)
+	if !ok {
+		return value
+	}
+
+	if col, ok := q.column(column); ok && col.Type == "json" {
+		var decoded interface{}
+		if err := json.Unmarshal(data, &decoded); err == nil {
+			return decoded
+		}
+	}
+	return string(data)
+}
+
+func quoteColumns(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = pq.QuoteIdentifier(col)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,209 @@
+package database
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+func testTable() *models.Table {
+	return &models.Table{
+		Name: "u_items",
+		Columns: []models.Column{
+			{Name: "id", Type: "integer"},
+			{Name: "name", Type: "text"},
+			{Name: "data", Type: "json"},
+		},
+	}
+}
+
+func TestQueryBuild(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    Query
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{
+			name:    "select all columns",
+			query:   Query{Operation: OpSelect},
+			wantSQL: `SELECT "id", "name", "data" FROM "u_items"`,
+		},
+		{
+			name: "select with where, order, limit and offset",
+			query: Query{
+				Operation: OpSelect,
+				Columns:   []string{"id", "name"},
+				Where:     []Condition{{Column: "id", Operator: ">=", Value: 10}, {Column: "name", Operator: "ILIKE", Value: "a%"}},
+				OrderBy:   []Order{{Column: "name"}, {Column: "id", Desc: true}},
+				Limit:     20,
+				Offset:    40,
+			},
+			wantSQL:  `SELECT "id", "name" FROM "u_items" WHERE "id" >= $1 AND "name" ILIKE $2 ORDER BY "name" ASC, "id" DESC LIMIT $3 OFFSET $4`,
+			wantArgs: []interface{}{10, "a%", 20, 40},
+		},
+		{
+			name:     "default operator is equality",
+			query:    Query{Operation: OpSelect, Columns: []string{"id"}, Where: []Condition{{Column: "name", Value: "x"}}},
+			wantSQL:  `SELECT "id" FROM "u_items" WHERE "name" = $1`,
+			wantArgs: []interface{}{"x"},
+		},
+		{
+			name:     "not equal",
+			query:    Query{Operation: OpSelect, Columns: []string{"id"}, Where: []Condition{{Column: "name", Operator: "!=", Value: "x"}}},
+			wantSQL:  `SELECT "id" FROM "u_items" WHERE "name" <> $1`,
+			wantArgs: []interface{}{"x"},
+		},
+		{
+			name:     "in",
+			query:    Query{Operation: OpSelect, Columns: []string{"id"}, Where: []Condition{{Column: "id", Operator: "in", Value: []interface{}{1, "2", nil}}}},
+			wantSQL:  `SELECT "id" FROM "u_items" WHERE "id" = ANY($1::INTEGER[])`,
+			wantArgs: []interface{}{pq.Array([]string{"1", "2"})},
+		},
+		{
+			name:     "in with large JSON numbers",
+			query:    Query{Operation: OpSelect, Columns: []string{"id"}, Where: []Condition{{Column: "id", Operator: "in", Value: []interface{}{float64(1000000), float64(12345678901)}}}},
+			wantSQL:  `SELECT "id" FROM "u_items" WHERE "id" = ANY($1::INTEGER[])`,
+			wantArgs: []interface{}{pq.Array([]string{"1000000", "12345678901"})},
+		},
+		{
+			name:     "in on text column",
+			query:    Query{Operation: OpSelect, Columns: []string{"id"}, Where: []Condition{{Column: "name", Operator: "IN", Value: []interface{}{"a", "b"}}}},
+			wantSQL:  `SELECT "id" FROM "u_items" WHERE "name" = ANY($1::TEXT[])`,
+			wantArgs: []interface{}{pq.Array([]string{"a", "b"})},
+		},
+		{
+			name:     "in on json column",
+			query:    Query{Operation: OpSelect, Columns: []string{"id"}, Where: []Condition{{Column: "data", Operator: "in", Value: []interface{}{map[string]interface{}{"k": 1}, "x"}}}},
+			wantSQL:  `SELECT "id" FROM "u_items" WHERE "data" = ANY($1::JSONB[])`,
+			wantArgs: []interface{}{pq.Array([]string{`{"k":1}`, `"x"`})},
+		},
+		{
+			name:    "is null",
+			query:   Query{Operation: OpSelect, Columns: []string{"id"}, Where: []Condition{{Column: "data", Operator: "is null"}}},
+			wantSQL: `SELECT "id" FROM "u_items" WHERE "data" IS NULL`,
+		},
+		{
+			name: "insert rows with missing values as default",
+			query: Query{
+				Operation: OpInsert,
+				Columns:   []string{"id"},
+				Values:    []map[string]interface{}{{"name": "a", "data": map[string]interface{}{"k": 1}}, {"name": "b"}},
+			},
+			wantSQL:  `INSERT INTO "u_items" ("data", "name") VALUES ($1, $2), (DEFAULT, $3) RETURNING "id"`,
+			wantArgs: []interface{}{`{"k":1}`, "a", "b"},
+		},
+		{
+			name: "update",
+			query: Query{
+				Operation: OpUpdate,
+				Columns:   []string{"id"},
+				Values:    []map[string]interface{}{{"name": "b"}},
+				Where:     []Condition{{Column: "id", Value: 1}},
+			},
+			wantSQL:  `UPDATE "u_items" SET "name" = $1 WHERE "id" = $2 RETURNING "id"`,
+			wantArgs: []interface{}{"b", 1},
+		},
+		{
+			name:     "delete",
+			query:    Query{Operation: OpDelete, Columns: []string{"id"}, Where: []Condition{{Column: "id", Value: 1}}},
+			wantSQL:  `DELETE FROM "u_items" WHERE "id" = $1 RETURNING "id"`,
+			wantArgs: []interface{}{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.query
+			q.Table = testTable()
+			gotSQL, gotArgs, err := q.Build()
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if gotSQL != tt.wantSQL {
+				t.Errorf("Build() sql = %s\nwant %s", gotSQL, tt.wantSQL)
+			}
+			if len(gotArgs) != len(tt.wantArgs) || (len(gotArgs) > 0 && !reflect.DeepEqual(gotArgs, tt.wantArgs)) {
+				t.Errorf("Build() args = %#v, want %#v", gotArgs, tt.wantArgs)
+			}
+		})
+	}
+}
+
+// TestQueryBuildRejectsUnknownIdentifiers はテーブル定義にない識別子・演算子がSQLに含まれないことを確認する
+func TestQueryBuildRejectsUnknownIdentifiers(t *testing.T) {
+	injection := `name"; DROP TABLE u_items; --`
+	tests := []struct {
+		name    string
+		query   Query
+		wantErr string
+	}{
+		{name: "select column", query: Query{Operation: OpSelect, Columns: []string{injection}}, wantErr: "unknown column"},
+		{name: "where column", query: Query{Operation: OpSelect, Where: []Condition{{Column: injection, Value: 1}}}, wantErr: "unknown column"},
+		{name: "order by column", query: Query{Operation: OpSelect, OrderBy: []Order{{Column: injection}}}, wantErr: "unknown column"},
+		{name: "insert column", query: Query{Operation: OpInsert, Values: []map[string]interface{}{{injection: 1}}}, wantErr: "unknown column"},
+		{name: "update column", query: Query{Operation: OpUpdate, Values: []map[string]interface{}{{injection: 1}}, Where: []Condition{{Column: "id", Value: 1}}}, wantErr: "unknown column"},
+		{name: "operator", query: Query{Operation: OpSelect, Where: []Condition{{Column: "id", Operator: "= 1 OR 1 =", Value: 1}}}, wantErr: "unsupported operator"},
+		{name: "in without array", query: Query{Operation: OpSelect, Where: []Condition{{Column: "id", Operator: "in", Value: "1,2"}}}, wantErr: "requires an array"},
+		{name: "operation", query: Query{Operation: "truncate"}, wantErr: "unsupported operation"},
+		{name: "update without where", query: Query{Operation: OpUpdate, Values: []map[string]interface{}{{"name": "a"}}}, wantErr: "where condition"},
+		{name: "delete without where", query: Query{Operation: OpDelete}, wantErr: "where condition"},
+		{name: "insert without rows", query: Query{Operation: OpInsert}, wantErr: "at least one row"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.query
+			q.Table = testTable()
+			sql, _, err := q.Build()
+			if err == nil {
+				t.Fatalf("Build() = %s, want error", sql)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Build() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestQueryBuildReadOnlyTable はログイン情報を持つテーブルに書き込むクエリを生成しないことを確認する
+func TestQueryBuildReadOnlyTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   Query
+		wantErr bool
+	}{
+		{name: "select", query: Query{Operation: OpSelect}},
+		{name: "insert", query: Query{Operation: OpInsert, Values: []map[string]interface{}{{"email": "a@example.com"}}}, wantErr: true},
+		{name: "update", query: Query{Operation: OpUpdate, Values: []map[string]interface{}{{"email": "a@example.com"}}, Where: []Condition{{Column: "id", Value: "u1"}}}, wantErr: true},
+		{name: "delete", query: Query{Operation: OpDelete, Where: []Condition{{Column: "id", Value: "u1"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.query
+			q.Table = &models.Table{Name: "users", Columns: []models.Column{{Name: "id", Type: "uuid"}, {Name: "email", Type: "text"}}}
+			sql, _, err := q.Build()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "read-only") {
+					t.Errorf("Build() = %s, error = %v, want read-only error", sql, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Build() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestQueryBuildQuotesTableName(t *testing.T) {
+	q := Query{Operation: OpSelect, Table: &models.Table{Name: `u_"x`, Columns: []models.Column{{Name: "id"}}}}
+	got, _, err := q.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if want := `SELECT "id" FROM "u_""x"`; got != want {
+		t.Errorf("Build() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,109 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// RunBody はノードの本体（サブグラフ）を1回実行し、Collectノードが受け取った値を返す
+// varsは本体のItemノードが出力する値（出力ピンのラベル → 値）、indexはトレースに記録する実行番号
+// 本体のノードもフロー全体のステップ数・実行時間の上限に含まれる
+// ctxをキャンセルすると本体の実行を中断する（並列実行で他の要素が失敗した場合など）
+func (ec *ExecutionContext) RunBody(ctx context.Context, body *models.Flow, index int, vars map[string]interface{}) (interface{}, error) {
+	return ec.runBody(ctx, body, index, vars, ec.querier, ec.inTx, ec.queryMu)
+}
+
+// runBody はクエリ先を指定して本体を実行する
+// queryMuは本体のクエリを直列化するロック（新しいトランザクション・セーブポイントの場合は本体専用のロック）
+func (ec *ExecutionContext) runBody(ctx context.Context, body *models.Flow, index int, vars map[string]interface{}, querier database.Querier, inTx bool, queryMu *sync.Mutex) (interface{}, error) {
+	if body == nil {
+		return nil, fmt.Errorf("node has no body")
+	}
+	g, err := newGraph(body)
+	if err != nil {
+		return nil, err
+	}
+	if vars == nil {
+		vars = map[string]interface{}{}
+	}
+
+	child := ec.newChild(ctx, querier, inTx, queryMu, vars)
+
+	trace, err := ec.engine.runGraph(child, g)
+	if ec.trace {
+		ec.recordIteration(index, trace)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return child.state.collected, nil
+}
+
+// newChild はノードの本体・サブフローを実行するための子のExecutionContextを作成する
+// 出力などのグラフの状態は子ごとに持ち、リクエスト・ステップ数・ワーカーの枠は親と共有する
+func (ec *ExecutionContext) newChild(ctx context.Context, querier database.Querier, inTx bool, queryMu *sync.Mutex, vars map[string]interface{}) *ExecutionContext {
+	return &ExecutionContext{
+		ctx:          ctx,
+		db:           ec.db,
+		querier:      querier,
+		inTx:         inTx,
+		queryMu:      queryMu,
+		Request:      ec.Request,
+		state:        newGraphState(),
+		engine:       ec.engine,
+		trace:        ec.trace,
+		scriptLimits: ec.scriptLimits,
+		steps:        ec.steps,
+		maxSteps:     ec.maxSteps,
+		workers:      ec.workers,
+		depth:        ec.depth,
+		scope:        vars,
+		savepoints:   ec.savepoints,
+		iterations:   &iterationTrace{},
+	}
+}
+
+// Scope は本体の実行時に渡された値を返す（Itemノード用）
+func (ec *ExecutionContext) Scope(name string) (interface{}, bool) {
+	if ec.scope == nil {
+		return nil, false
+	}
+	value, ok := ec.scope[name]
+	return value, ok
+}
+
+// Collect は本体の実行結果を設定する（Collectノード用）
+func (ec *ExecutionContext) Collect(value interface{}) error {
+	if ec.scope == nil {
+		return fmt.Errorf("collect can only be used inside a body")
+	}
+	ec.state.mu.Lock()
+	defer ec.state.mu.Unlock()
+	ec.state.collected = value
+	return nil
+}
+
+// recordIteration は本体の1回分の実行トレースを記録する（並列実行に対応）
+func (ec *ExecutionContext) recordIteration(index int, trace []models.TraceEntry) {
+	ec.iterations.mu.Lock()
+	defer ec.iterations.mu.Unlock()
+
+	for len(ec.iterations.iterations) <= index {
+		ec.iterations.iterations = append(ec.iterations.iterations, nil)
+	}
+	ec.iterations.iterations[index] = trace
+}
+
+// takeIterations は記録された本体のトレースを取り出し、次のノードのためにリセットする
+func (ec *ExecutionContext) takeIterations() [][]models.TraceEntry {
+	ec.iterations.mu.Lock()
+	defer ec.iterations.mu.Unlock()
+
+	iterations := ec.iterations.iterations
+	ec.iterations.iterations = nil
+	return iterations
+}
@@ -0,0 +1,450 @@
+package flow
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/flow/script"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// Request はフローに渡されるHTTPリクエスト情報
+type Request struct {
+	Method  string
+	Path    string
+	Params  map[string]interface{}
+	Query   map[string][]string
+	Headers map[string]string
+	Cookies map[string]string
+	Body    interface{}         // JSONボディ
+	Form    map[string][]string // application/x-www-form-urlencoded・multipart/form-dataのフィールド
+	Files   map[string][]*File  // multipart/form-dataのファイル
+	Auth    *models.Principal   // 認証された呼び出し元（publicのエンドポイントではnil）
+}
+
+// File はmultipart/form-dataでアップロードされたファイル
+type File struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// Map はファイルをノードに渡すためのマップに変換する（内容はBase64エンコードする）
+func (f *File) Map() map[string]interface{} {
+	return map[string]interface{}{
+		"filename":     f.Filename,
+		"content_type": f.ContentType,
+		"size":         len(f.Content),
+		"content":      base64.StdEncoding.EncodeToString(f.Content),
+	}
+}
+
+// maxMultipartMemory はmultipart/form-dataの解析時にメモリに保持する最大サイズ
+// ボディ全体のサイズはRuntime APIのmax_request_bytesで制限される
+const maxMultipartMemory = 32 << 20
+
+// NewRequest はhttp.RequestからRequestを作成する
+// JSON・application/x-www-form-urlencoded・multipart/form-dataのボディを解析する
+func NewRequest(r *http.Request) (*Request, error) {
+	req := &Request{
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Params:  map[string]interface{}{},
+		Query:   map[string][]string(r.URL.Query()),
+		Headers: make(map[string]string, len(r.Header)),
+		Cookies: map[string]string{},
+		Form:    map[string][]string{},
+		Files:   map[string][]*File{},
+	}
+	for key := range r.Header {
+		req.Headers[key] = r.Header.Get(key)
+	}
+	for _, cookie := range r.Cookies() {
+		req.Cookies[cookie.Name] = cookie.Value
+	}
+
+	if r.Body == nil {
+		return req, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &req.Body); err != nil {
+				return nil, fmt.Errorf("invalid JSON body: %w", err)
+			}
+		}
+
+	case "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return nil, fmt.Errorf("invalid form body: %w", err)
+		}
+		req.Form = map[string][]string(r.PostForm)
+
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(maxMultipartMemory); err != nil {
+			return nil, fmt.Errorf("invalid multipart body: %w", err)
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		req.Form = r.MultipartForm.Value
+		for name, headers := range r.MultipartForm.File {
+			for _, header := range headers {
+				file, err := readFile(header)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read file %q: %w", name, err)
+				}
+				req.Files[name] = append(req.Files[name], file)
+			}
+		}
+	}
+
+	return req, nil
+}
+
+func readFile(header *multipart.FileHeader) (*File, error) {
+	f, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return &File{
+		Filename:    header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Content:     content,
+	}, nil
+}
+
+// QueryValue はクエリパラメータの最初の値を返す
+func (r *Request) QueryValue(name string) (string, bool) {
+	values, ok := r.Query[name]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// FormValue はフォームフィールドの最初の値を返す
+func (r *Request) FormValue(name string) (string, bool) {
+	values, ok := r.Form[name]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// Map はリクエストをノードに渡すためのマップに変換する
+// ファイルはメタデータのみを含む（内容は開始ノードのfileパラメータで取得する）
+func (r *Request) Map() map[string]interface{} {
+	params := make(map[string]interface{}, len(r.Params))
+	for key, value := range r.Params {
+		params[key] = value
+	}
+	headers := make(map[string]interface{}, len(r.Headers))
+	for key, value := range r.Headers {
+		headers[key] = value
+	}
+	cookies := make(map[string]interface{}, len(r.Cookies))
+	for key, value := range r.Cookies {
+		cookies[key] = value
+	}
+	files := make(map[string]interface{}, len(r.Files))
+	for key, list := range r.Files {
+		items := make([]interface{}, 0, len(list))
+		for _, file := range list {
+			items = append(items, map[string]interface{}{
+				"filename":     file.Filename,
+				"content_type": file.ContentType,
+				"size":         len(file.Content),
+			})
+		}
+		files[key] = items
+	}
+
+	var auth interface{}
+	if m := r.AuthMap(); m != nil {
+		auth = m
+	}
+
+	return map[string]interface{}{
+		"method":  r.Method,
+		"path":    r.Path,
+		"params":  params,
+		"query":   valuesMap(r.Query),
+		"headers": headers,
+		"cookies": cookies,
+		"body":    r.Body,
+		"form":    valuesMap(r.Form),
+		"files":   files,
+		"auth":    auth,
+	}
+}
+
+// AuthMap は認証された呼び出し元をノードに渡すためのマップに変換する（認証されていない場合はnil）
+func (r *Request) AuthMap() map[string]interface{} {
+	p := r.Auth
+	if p == nil {
+		return nil
+	}
+	roles := make([]interface{}, len(p.Roles))
+	for i, role := range p.Roles {
+		roles[i] = role
+	}
+	scopes := make([]interface{}, len(p.Scopes))
+	for i, scope := range p.Scopes {
+		scopes[i] = scope
+	}
+	m := map[string]interface{}{
+		"type":      p.Type,
+		"sub":       p.Subject,
+		"tenant_id": nil,
+		"roles":     roles,
+		"scopes":    scopes,
+		"email":     nil,
+	}
+	if p.TenantID != "" {
+		m["tenant_id"] = p.TenantID
+	}
+	if p.Email != "" {
+		m["email"] = p.Email
+	}
+	return m
+}
+
+// valuesMap は複数値のマップを、値が1つの場合は文字列、複数の場合は配列のマップに変換する
+func valuesMap(values map[string][]string) map[string]interface{} {
+	m := make(map[string]interface{}, len(values))
+	for key, list := range values {
+		if len(list) == 1 {
+			m[key] = list[0]
+		} else {
+			m[key] = list
+		}
+	}
+	return m
+}
+
+// レスポンスボディの形式
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+	FormatHTML = "html"
+	FormatCSV  = "csv"
+)
+
+// Response はフローが生成したHTTPレスポンス
+type Response struct {
+	StatusCode int
+	Headers    http.Header
+	Body       interface{}
+	// Format はボディの形式（空の場合はjson）。json以外の場合、Bodyはそのまま書き込む文字列
+	Format string
+}
+
+// ExecutionContext はフロー実行中の状態を保持する
+// ノードの実行ごとにコピーされ（forNode）、グラフ内で共有する状態はstateが保持する
+type ExecutionContext struct {
+	ctx     context.Context
+	db      *database.DB
+	querier database.Querier
+	inTx    bool        // querierがトランザクションの場合はtrue
+	queryMu *sync.Mutex // トランザクション内で並列に実行されるノードのクエリを直列化する
+	Request *Request
+	pins    map[string]*models.Pin
+	state   *graphState
+
+	engine       *Engine
+	trace        bool
+	scriptLimits script.Limits
+	steps        *atomic.Int64 // ノードの本体の実行と共有する
+	maxSteps     int
+	workers      chan struct{} // 並列実行で追加するワーカーの枠（リクエスト全体で共有する）。nilの場合は順に実行する
+	depth        int           // サブフローの呼び出しの深さ
+
+	// ノードの本体（サブグラフ）の実行状態
+	scope      map[string]interface{}
+	savepoints *atomic.Int64 // 入れ子のトランザクションのセーブポイント名の採番
+	iterations *iterationTrace
+}
+
+// graphState は1つのグラフ（フロー全体・ノードの本体）の実行中にノード間で共有する状態
+type graphState struct {
+	mu        sync.RWMutex
+	outputs   map[string]map[string]interface{}
+	inactive  map[string]bool // 選択されなかった分岐・実行されなかったノードの出力ピン
+	response  *Response
+	collected interface{}
+}
+
+func newGraphState() *graphState {
+	return &graphState{
+		outputs:  make(map[string]map[string]interface{}),
+		inactive: make(map[string]bool),
+	}
+}
+
+// setOutputs はノードの出力を記録する
+func (s *graphState) setOutputs(nodeID string, outputs map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outputs[nodeID] = outputs
+}
+
+// deactivate は出力ピンを非アクティブにする
+func (s *graphState) deactivate(pinIDs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range pinIDs {
+		s.inactive[id] = true
+	}
+}
+
+// iterationTrace はノードの本体の実行ごとのトレース（ForEachノードの並列実行に対応）
+type iterationTrace struct {
+	mu         sync.Mutex
+	iterations [][]models.TraceEntry
+}
+
+func newExecutionContext(ctx context.Context, db *database.DB, req *Request) *ExecutionContext {
+	return &ExecutionContext{
+		ctx:        ctx,
+		db:         db,
+		querier:    db,
+		Request:    req,
+		state:      newGraphState(),
+		steps:      &atomic.Int64{},
+		savepoints: &atomic.Int64{},
+		iterations: &iterationTrace{},
+	}
+}
+
+// forNode はノード1つを実行するためのExecutionContextを返す
+// グラフの共有状態は元のExecutionContextと共有し、本体のトレースはノードごとに記録する
+func (ec *ExecutionContext) forNode(ctx context.Context) *ExecutionContext {
+	node := *ec
+	node.ctx = ctx
+	node.iterations = &iterationTrace{}
+	return &node
+}
+
+// Context は実行中のcontext.Contextを返す
+func (ec *ExecutionContext) Context() context.Context {
+	return ec.ctx
+}
+
+// DB はデータベース接続を返す
+func (ec *ExecutionContext) DB() *database.DB {
+	return ec.db
+}
+
+// Querier はユーザーテーブルへのクエリに使用する接続を返す
+// テスト実行ではロールバックされるトランザクションが返される
+func (ec *ExecutionContext) Querier() database.Querier {
+	return ec.querier
+}
+
+// LockQuerier はQuerierを使用する間、同じトランザクションを使用する他のノードのクエリを待たせる
+// トランザクションは同時に1つのクエリしか実行できないため、並列に実行されるノードはクエリの前後で呼び出す
+// トランザクション外の場合は何もしない
+func (ec *ExecutionContext) LockQuerier() (unlock func()) {
+	if !ec.inTx || ec.queryMu == nil {
+		return func() {}
+	}
+	ec.queryMu.Lock()
+	return ec.queryMu.Unlock
+}
+
+// InTransaction はユーザーテーブルへのクエリがトランザクション内で実行されるかどうかを返す
+func (ec *ExecutionContext) InTransaction() bool {
+	return ec.inTx
+}
+
+// ParallelEnabled は並列実行が有効（FLOW_MAX_PARALLELISMが2以上）かどうかを返す
+func (ec *ExecutionContext) ParallelEnabled() bool {
+	return ec.workers != nil
+}
+
+// TryAcquireWorker はリクエスト全体で共有するワーカーの枠を取得できた場合にtrueを返す（空きがない場合は待たない）
+// 取得した枠は、その枠で開始した処理が終了したらReleaseWorkerで返す
+func (ec *ExecutionContext) TryAcquireWorker() bool {
+	if ec.workers == nil {
+		return false
+	}
+	select {
+	case ec.workers <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// ReleaseWorker はTryAcquireWorkerで取得したワーカーの枠を返す
+func (ec *ExecutionContext) ReleaseWorker() {
+	<-ec.workers
+}
+
+// ScriptLimits はスクリプト実行の制限を返す
+func (ec *ExecutionContext) ScriptLimits() script.Limits {
+	return ec.scriptLimits
+}
+
+// step はノードの実行回数を数え、上限を超えた場合はErrStepLimitを返す
+func (ec *ExecutionContext) step() error {
+	steps := ec.steps.Add(1)
+	if ec.maxSteps > 0 && steps > int64(ec.maxSteps) {
+		return ErrStepLimit
+	}
+	return nil
+}
+
+// Outputs は実行済みノードの出力（ピンID → 値）を返す
+func (ec *ExecutionContext) Outputs(nodeID string) (map[string]interface{}, bool) {
+	ec.state.mu.RLock()
+	defer ec.state.mu.RUnlock()
+	outputs, ok := ec.state.outputs[nodeID]
+	return outputs, ok
+}
+
+// OutputPin は実行済みノードの出力ピンとその値を返す
+func (ec *ExecutionContext) OutputPin(pinID string) (*models.Pin, interface{}, bool) {
+	pin, ok := ec.pins[pinID]
+	if !ok || pin.Type != "output" {
+		return nil, nil, false
+	}
+	outputs, ok := ec.Outputs(pin.NodeID)
+	if !ok {
+		return nil, nil, false
+	}
+	value, ok := outputs[pinID]
+	return pin, value, ok
+}
+
+// SetResponse はフローのレスポンスを設定する
+func (ec *ExecutionContext) SetResponse(resp *Response) error {
+	ec.state.mu.Lock()
+	defer ec.state.mu.Unlock()
+	if ec.state.response != nil {
+		return fmt.Errorf("response has already been set")
+	}
+	ec.state.response = resp
+	return nil
+}
@@ -0,0 +1,99 @@
+package flow
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// Diff は2つのフロー定義のノードと接続の差分を返す
+func Diff(from, to *models.Flow) models.FlowDiff {
+	nodes := models.NodesDiff{
+		Added:   []models.Node{},
+		Removed: []models.Node{},
+		Changed: []models.NodeChange{},
+	}
+
+	before := make(map[string]models.Node, len(from.Nodes))
+	for _, node := range from.Nodes {
+		before[node.ID] = node
+	}
+	after := make(map[string]models.Node, len(to.Nodes))
+	for _, node := range to.Nodes {
+		after[node.ID] = node
+	}
+
+	for _, node := range from.Nodes {
+		if _, ok := after[node.ID]; !ok {
+			nodes.Removed = append(nodes.Removed, node)
+		}
+	}
+	for _, node := range to.Nodes {
+		old, ok := before[node.ID]
+		if !ok {
+			nodes.Added = append(nodes.Added, node)
+			continue
+		}
+		if fields := changedNodeFields(old, node); len(fields) > 0 {
+			nodes.Changed = append(nodes.Changed, models.NodeChange{ID: node.ID, Fields: fields, Before: old, After: node})
+		}
+	}
+
+	connections := models.ConnectionsDiff{
+		Added:   []models.Connection{},
+		Removed: []models.Connection{},
+	}
+	// 接続は接続元と接続先の組で比較する
+	key := func(c models.Connection) models.Connection {
+		return models.Connection{From: c.From, To: c.To}
+	}
+	oldConns := map[models.Connection]bool{}
+	for _, conn := range from.Connections {
+		oldConns[key(conn)] = true
+	}
+	newConns := map[models.Connection]bool{}
+	for _, conn := range to.Connections {
+		newConns[key(conn)] = true
+		if !oldConns[key(conn)] {
+			connections.Added = append(connections.Added, conn)
+		}
+	}
+	for _, conn := range from.Connections {
+		if !newConns[key(conn)] {
+			connections.Removed = append(connections.Removed, conn)
+		}
+	}
+
+	return models.FlowDiff{Nodes: nodes, Connections: connections}
+}
+
+func changedNodeFields(a, b models.Node) []string {
+	var fields []string
+	if a.Type != b.Type {
+		fields = append(fields, "type")
+	}
+	if a.Label != b.Label {
+		fields = append(fields, "label")
+	}
+	if a.X != b.X || a.Y != b.Y {
+		fields = append(fields, "position")
+	}
+	if !jsonEqual(a.Config, b.Config) {
+		fields = append(fields, "config")
+	}
+	if !jsonEqual(a.Pins, b.Pins) {
+		fields = append(fields, "pins")
+	}
+	return fields
+}
+
+// jsonEqual はJSONとして等しいかを比較する
+func jsonEqual(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return string(ja) == string(jb)
+}
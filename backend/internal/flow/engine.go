@@ -0,0 +1,386 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/flow/script"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// ErrNoResponse はフローがレスポンスを生成しなかった場合のエラー
+var ErrNoResponse = errors.New("flow finished without a response")
+
+// RequestError はリクエストがフローで宣言されたパラメータを満たさない場合のエラー
+// Runtime APIは400 VALIDATION_ERRORとして返す
+type RequestError struct {
+	Details map[string]string // パラメータ名 → エラーメッセージ
+}
+
+func (e *RequestError) Error() string {
+	names := make([]string, 0, len(e.Details))
+	for name := range e.Details {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, name+": "+e.Details[name])
+	}
+	return "invalid request: " + strings.Join(parts, "; ")
+}
+
+// Config はエンジンの実行設定
+type Config struct {
+	Script script.Limits
+	Limits Limits
+}
+
+// Engine はフロー定義を実行するエンジン
+type Engine struct {
+	db       *database.DB
+	registry *Registry
+	config   Config
+}
+
+// NewEngine は新しいEngineを作成する
+func NewEngine(db *database.DB, registry *Registry, config Config) *Engine {
+	return &Engine{db: db, registry: registry, config: config}
+}
+
+// Registry はエンジンが使用するノードレジストリを返す
+func (e *Engine) Registry() *Registry {
+	return e.registry
+}
+
+// Limits はエンジン全体の実行制限を返す
+func (e *Engine) Limits() Limits {
+	return e.config.Limits
+}
+
+// Parse はflow_definitionのJSONをフロー定義に変換する
+func Parse(data []byte) (*models.Flow, error) {
+	var f models.Flow
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid flow definition: %w", err)
+	}
+	return &f, nil
+}
+
+// Options はフロー実行ごとのオプション
+type Options struct {
+	// Trace がtrueの場合、ノードごとの実行トレースを記録する
+	Trace bool
+	// Querier を指定した場合、ユーザーテーブルへのクエリをこのトランザクションで実行する
+	Querier database.Querier
+	// Limits はエンジン全体の制限より厳しい制限を適用する（エンドポイントごとの制限）
+	Limits Limits
+}
+
+// Result はフローの実行結果
+type Result struct {
+	Response *Response
+	Trace    []models.TraceEntry
+}
+
+// Execute はフローを実行し、レスポンスノードが生成したレスポンスを返す
+func (e *Engine) Execute(ctx context.Context, f *models.Flow, req *Request) (*Response, error) {
+	result, err := e.Run(ctx, f, req, Options{})
+	if err != nil {
+		return nil, err
+	}
+	return result.Response, nil
+}
+
+// Run はオプションを指定してフローを実行する
+// エラーが発生した場合も、それまでのトレースを含むResultを返す
+func (e *Engine) Run(ctx context.Context, f *models.Flow, req *Request, opts Options) (*Result, error) {
+	result := &Result{}
+
+	g, err := newGraph(f)
+	if err != nil {
+		return result, err
+	}
+
+	// 実行時間の上限はコンテキストのキャンセルとしてDBクエリ・スクリプトに伝播する
+	limits := e.config.Limits.Restrict(opts.Limits)
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, limits.Timeout, ErrTimeout)
+		defer cancel()
+	}
+
+	ec := newExecutionContext(ctx, e.db, req)
+	ec.engine = e
+	ec.trace = opts.Trace
+	ec.scriptLimits = e.config.Script
+	ec.maxSteps = limits.MaxSteps
+	if opts.Querier != nil {
+		ec.querier = opts.Querier
+		ec.inTx = true
+		ec.queryMu = &sync.Mutex{}
+	}
+	// 最初のワーカーは実行中のgoroutineが担うため、追加のワーカーの枠は上限より1つ少ない
+	if limits.MaxParallelism > 1 {
+		ec.workers = make(chan struct{}, limits.MaxParallelism-1)
+	}
+
+	trace, err := e.runGraph(ec, g)
+	result.Trace = trace
+	if err != nil {
+		return result, err
+	}
+
+	if ec.state.response == nil {
+		return result, ErrNoResponse
+	}
+	result.Response = ec.state.response
+	return result, nil
+}
+
+// runGraph はグラフのノードを依存関係順に実行し、トレースを記録する（フロー全体とノードの本体で共通）
+// 並列実行が有効な場合は、互いに依存しないノード（独立した分岐）を並列に実行する
+// トレースは並列実行の場合も実行順序（g.order）で返し、エラーが発生した場合もそれまでのトレースを返す
+func (e *Engine) runGraph(ec *ExecutionContext, g *graph) ([]models.TraceEntry, error) {
+	ec.pins = g.pins
+	r := &graphRun{
+		engine:  e,
+		ec:      ec,
+		g:       g,
+		started: time.Now(),
+		entries: make([]*models.TraceEntry, len(g.order)),
+	}
+
+	var err error
+	if ec.workers == nil {
+		err = r.runSequential()
+	} else {
+		err = r.runParallel()
+	}
+	return r.trace(), err
+}
+
+// graphRun は1つのグラフの実行状態
+type graphRun struct {
+	engine  *Engine
+	ec      *ExecutionContext
+	g       *graph
+	started time.Time
+	entries []*models.TraceEntry // g.orderの順のトレース（実行されなかったノードはnil）
+}
+
+// runSequential はノードを実行順序（g.order）に1つずつ実行する
+func (r *graphRun) runSequential() error {
+	for i, node := range r.g.order {
+		if err := contextError(r.ec.ctx); err != nil {
+			return err
+		}
+		if r.skipInactive(node) {
+			continue
+		}
+		if err := r.runNode(r.ec.ctx, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipInactive は選択されなかった分岐上のノードであれば、実行せずにその出力ピンを非アクティブにしてtrueを返す
+func (r *graphRun) skipInactive(node *models.Node) bool {
+	if r.g.active(r.ec, node.ID) {
+		return false
+	}
+	var pins []string
+	for _, pin := range node.Pins {
+		if pin.Type == "output" {
+			pins = append(pins, pin.ID)
+		}
+	}
+	r.ec.state.deactivate(pins...)
+	return true
+}
+
+// runNode はg.order[i]のノードを実行し、出力とトレースを記録する
+// ctxは並列実行で他のノードが失敗した場合にキャンセルされる
+// エラー出力ピンで処理されなかったエラーを*NodeErrorとして返す
+func (r *graphRun) runNode(ctx context.Context, i int) error {
+	ec, node := r.ec, r.g.order[i]
+
+	if err := ec.step(); err != nil {
+		return &NodeError{NodeID: node.ID, NodeType: node.Type, Err: err}
+	}
+
+	nodeType, ok := r.engine.registry.Lookup(node.Type)
+	if !ok {
+		return &NodeError{NodeID: node.ID, NodeType: node.Type, Err: fmt.Errorf("unsupported node type")}
+	}
+
+	inputs := r.g.collectInputs(ec, node.ID)
+	nodeEC := ec.forNode(ctx)
+	nodeStarted := time.Now()
+
+	// 設定の式を評価する（評価のエラーもノードのエラーとして扱う）
+	config, evaluated, err := r.evalConfig(ec, node, nodeType, inputs)
+	in := &NodeInput{Node: node, Config: config, Inputs: inputs}
+	errPin := errorPin(node)
+	var outputs map[string]interface{}
+	var sp *savepoint
+	if err == nil && errPin != nil && ec.inTx {
+		// DBのエラーで中断されたトランザクションで実行を続けないよう、エラーを処理するノードはセーブポイント内で実行する
+		sp, err = nodeEC.beginSavepoint()
+	}
+	if err == nil {
+		outputs, err = nodeType.Executor.Execute(nodeEC, in)
+	}
+	// セーブポイントまでロールバックできなかった場合はトランザクションを続行できないため、エラーを処理しない
+	recoverable := true
+	if sp != nil {
+		if spErr := sp.end(err); spErr != nil {
+			err, recoverable = spErr, false
+		}
+	}
+	if err != nil && contextError(ec.ctx) == ErrTimeout {
+		err = ErrTimeout
+	}
+	// エラー出力ピンがある場合はエラーをそのピンに出力し、通常の出力ピンを非アクティブにして実行を続ける
+	caught := err != nil && errPin != nil && recoverable && catchable(err) && contextError(ctx) == nil
+	if caught {
+		outputs = map[string]interface{}{errPin.ID: errorValue(node.ID, err)}
+	}
+	if ec.trace {
+		entry := &models.TraceEntry{
+			NodeID:     node.ID,
+			NodeType:   node.Type,
+			Label:      node.Label,
+			Status:     models.TraceExecuted,
+			Inputs:     in.Inputs,
+			Outputs:    outputs,
+			StartedMs:  milliseconds(nodeStarted.Sub(r.started)),
+			DurationMs: milliseconds(time.Since(nodeStarted)),
+			Iterations: nodeEC.takeIterations(),
+		}
+		if evaluated {
+			entry.Config = config
+		}
+		if err != nil {
+			entry.Status = models.TraceFailed
+			entry.Error = err.Error()
+		}
+		r.entries[i] = entry
+	}
+	if err != nil && !caught {
+		return &NodeError{NodeID: node.ID, NodeType: node.Type, Err: err}
+	}
+
+	if outputs == nil {
+		outputs = map[string]interface{}{}
+	}
+	var inactive []string
+	for _, pin := range node.Pins {
+		if pin.Type != "output" {
+			continue
+		}
+		_, ok := outputs[pin.ID]
+		switch {
+		case caught && pin.ID != errPin.ID,
+			!caught && errPin != nil && pin.ID == errPin.ID,
+			!caught && nodeType.Branching && !ok:
+			inactive = append(inactive, pin.ID)
+		}
+	}
+	if errPin != nil && !caught {
+		delete(outputs, errPin.ID)
+	}
+	ec.state.deactivate(inactive...)
+	ec.state.setOutputs(node.ID, outputs)
+	return nil
+}
+
+// trace は実行順序（g.order）のトレースを返す（実行されなかったノードはskipped）
+func (r *graphRun) trace() []models.TraceEntry {
+	if !r.ec.trace {
+		return nil
+	}
+	trace := make([]models.TraceEntry, 0, len(r.entries))
+	for i, entry := range r.entries {
+		if entry == nil {
+			node := r.g.order[i]
+			entry = &models.TraceEntry{
+				NodeID:   node.ID,
+				NodeType: node.Type,
+				Label:    node.Label,
+				Status:   models.TraceSkipped,
+			}
+		}
+		trace = append(trace, *entry)
+	}
+	return trace
+}
+
+// contextError はコンテキストのエラーを返す
+// 実行時間の上限によるキャンセルの場合はErrTimeoutを返す
+func contextError(ctx context.Context) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	if cause := context.Cause(ctx); errors.Is(cause, ErrTimeout) {
+		return ErrTimeout
+	}
+	return ctx.Err()
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// active はノードを実行するかどうかを返す
+// 接続されたすべての入力ピンに、アクティブな接続が1つ以上ある場合に実行する
+// （複数の分岐を1つの入力ピンに接続すると、選択された分岐の値で合流できる）
+func (g *graph) active(ec *ExecutionContext, nodeID string) bool {
+	ec.state.mu.RLock()
+	defer ec.state.mu.RUnlock()
+
+	pins := map[string]bool{}
+	for _, conn := range g.incoming[nodeID] {
+		pins[conn.To.PinID] = pins[conn.To.PinID] || !ec.state.inactive[conn.From.PinID]
+	}
+	for _, active := range pins {
+		if !active {
+			return false
+		}
+	}
+	return true
+}
+
+// collectInputs は接続元ノードの出力から入力ピンの値を集める
+// 1つの入力ピンに複数の接続がある場合は、アクティブな接続のうち最初のものの値を使用する
+func (g *graph) collectInputs(ec *ExecutionContext, nodeID string) map[string]interface{} {
+	ec.state.mu.RLock()
+	defer ec.state.mu.RUnlock()
+
+	inputs := make(map[string]interface{})
+	for _, conn := range g.incoming[nodeID] {
+		if ec.state.inactive[conn.From.PinID] {
+			continue
+		}
+		if _, exists := inputs[conn.To.PinID]; exists {
+			continue
+		}
+		outputs, ok := ec.state.outputs[conn.From.NodeID]
+		if !ok {
+			continue
+		}
+		if value, ok := outputs[conn.From.PinID]; ok {
+			inputs[conn.To.PinID] = value
+		}
+	}
+	return inputs
+}
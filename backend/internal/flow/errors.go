@@ -0,0 +1,170 @@
+package flow
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// エラーの分類
+const (
+	ErrorValidation = "validation"
+	ErrorNotFound   = "not_found"
+	ErrorConflict   = "conflict"
+	ErrorTimeout    = "timeout"
+	ErrorLimit      = "limit"
+	ErrorInternal   = "internal"
+)
+
+// defaultErrorMappings は分類ごとの既定のレスポンス
+// internalのメッセージは内部のエラー（DBのエラー等）を含めない固定の文言にする
+var defaultErrorMappings = map[string]models.ErrorMapping{
+	ErrorValidation: {StatusCode: http.StatusBadRequest, Code: "VALIDATION_ERROR", Message: "Invalid request parameters"},
+	ErrorNotFound:   {StatusCode: http.StatusNotFound, Code: "NOT_FOUND", Message: "Resource not found"},
+	ErrorConflict:   {StatusCode: http.StatusConflict, Code: "CONFLICT", Message: "Resource conflicts with existing data"},
+	ErrorTimeout:    {StatusCode: http.StatusGatewayTimeout, Code: "TIMEOUT", Message: "Flow execution timed out"},
+	ErrorLimit:      {StatusCode: http.StatusInternalServerError, Code: "STEP_LIMIT_EXCEEDED", Message: "Flow exceeded the maximum number of node executions"},
+	ErrorInternal:   {StatusCode: http.StatusInternalServerError, Code: "INTERNAL_ERROR", Message: "Internal server error"},
+}
+
+// IsErrorClass はエラーの分類として有効な名前かどうかを返す
+func IsErrorClass(name string) bool {
+	_, ok := defaultErrorMappings[name]
+	return ok
+}
+
+// Error は分類とAPI利用者に返してよいメッセージを持つエラー
+// ノードは内部のエラーをErrに保持し、Messageには外部に公開できる文言のみを設定する
+type Error struct {
+	Class   string
+	Code    string // 省略時は分類の既定のコード
+	Message string // 省略時は分類の既定のメッセージ
+	Details interface{}
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	if e.Message != "" {
+		return e.Message
+	}
+	return e.Class
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyError はエラーを分類し、API利用者に返してよい情報のみを持つ*Errorを返す
+func ClassifyError(err error) *Error {
+	var flowErr *Error
+	var reqErr *RequestError
+	switch {
+	case errors.As(err, &flowErr):
+		classified := *flowErr
+		if !IsErrorClass(classified.Class) {
+			classified.Class = ErrorInternal
+		}
+		return &classified
+	case errors.As(err, &reqErr):
+		return &Error{Class: ErrorValidation, Details: reqErr.Details, Err: err}
+	case errors.Is(err, ErrTimeout):
+		return &Error{Class: ErrorTimeout, Err: err}
+	case errors.Is(err, ErrStepLimit):
+		return &Error{Class: ErrorLimit, Err: err}
+	case errors.Is(err, ErrSubflowDepth):
+		return &Error{Class: ErrorLimit, Code: "SUBFLOW_DEPTH_EXCEEDED", Message: "Flow exceeded the maximum subflow call depth", Err: err}
+	case errors.Is(err, ErrNoResponse):
+		return &Error{Class: ErrorInternal, Message: "Flow did not produce a response", Err: err}
+	case errors.Is(err, sql.ErrNoRows):
+		return &Error{Class: ErrorNotFound, Err: err}
+	case database.IsConflict(err):
+		return &Error{Class: ErrorConflict, Err: err}
+	case database.IsInvalidData(err):
+		return &Error{Class: ErrorValidation, Message: "Invalid data", Err: err}
+	default:
+		return &Error{Class: ErrorInternal, Err: err}
+	}
+}
+
+// HTTPError はフローのエラーに対応するエラーレスポンス
+type HTTPError struct {
+	Class      string
+	StatusCode int
+	Code       string
+	Message    string
+	Details    interface{}
+}
+
+// MapError はエラーを分類し、フローのerror_responsesと既定の対応付けからエラーレスポンスを決定する
+func MapError(f *models.Flow, err error) *HTTPError {
+	classified := ClassifyError(err)
+	mapping := defaultErrorMappings[classified.Class]
+	if classified.Code != "" {
+		mapping.Code = classified.Code
+	}
+	if classified.Message != "" {
+		mapping.Message = classified.Message
+	}
+
+	if f != nil {
+		if custom, ok := f.ErrorResponses[classified.Class]; ok {
+			if custom.StatusCode != 0 {
+				mapping.StatusCode = custom.StatusCode
+			}
+			if custom.Code != "" {
+				mapping.Code = custom.Code
+			}
+			if custom.Message != "" {
+				mapping.Message = custom.Message
+			}
+		}
+	}
+
+	return &HTTPError{
+		Class:      classified.Class,
+		StatusCode: mapping.StatusCode,
+		Code:       mapping.Code,
+		Message:    mapping.Message,
+		Details:    classified.Details,
+	}
+}
+
+// errorValue はエラー出力ピンに出力する値（code, message, class, node_id）を生成する
+func errorValue(nodeID string, err error) map[string]interface{} {
+	mapped := MapError(nil, err)
+	value := map[string]interface{}{
+		"class":   mapped.Class,
+		"code":    mapped.Code,
+		"message": mapped.Message,
+		"node_id": nodeID,
+	}
+	if mapped.Details != nil {
+		value["details"] = mapped.Details
+	}
+	return value
+}
+
+// catchable はエラー出力ピンで処理できるエラーかどうかを返す
+// 実行時間・ステップ数・サブフローの深さの上限はフロー全体の制限のため処理できない
+func catchable(err error) bool {
+	return !errors.Is(err, ErrTimeout) && !errors.Is(err, ErrStepLimit) && !errors.Is(err, ErrSubflowDepth)
+}
+
+// errorPin はノードのエラー出力ピン（data_typeがerrorの出力ピン）を返す
+func errorPin(node *models.Node) *models.Pin {
+	for i := range node.Pins {
+		if pin := &node.Pins[i]; pin.Type == "output" && pin.DataType == ErrorDataType {
+			return pin
+		}
+	}
+	return nil
+}
+
+// ErrorDataType はエラー出力ピンのデータ型
+const ErrorDataType = "error"
@@ -0,0 +1,101 @@
+package flow
+
+import (
+	"fmt"
+
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// NodeExecutor はノードタイプごとの実行処理
+// 入力（ピンID → 値）と設定を受け取り、出力（ピンID → 値）を返す
+type NodeExecutor interface {
+	Execute(ec *ExecutionContext, in *NodeInput) (map[string]interface{}, error)
+}
+
+// ExecutorFunc は関数をNodeExecutorとして扱うためのアダプタ
+type ExecutorFunc func(ec *ExecutionContext, in *NodeInput) (map[string]interface{}, error)
+
+// Execute はfを呼び出す
+func (f ExecutorFunc) Execute(ec *ExecutionContext, in *NodeInput) (map[string]interface{}, error) {
+	return f(ec, in)
+}
+
+// NodeInput はノード実行時の入力
+type NodeInput struct {
+	Node   *models.Node
+	Config map[string]interface{}
+	Inputs map[string]interface{} // 入力ピンID → 値
+}
+
+// NodeError はノード実行中に発生したエラー
+type NodeError struct {
+	NodeID   string
+	NodeType string
+	Err      error
+}
+
+func (e *NodeError) Error() string {
+	return fmt.Sprintf("node %s (%s): %v", e.NodeID, e.NodeType, e.Err)
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// InputPins はノードの入力ピンを返す
+func (in *NodeInput) InputPins() []models.Pin {
+	return pinsOfType(in.Node, "input")
+}
+
+// OutputPins はノードの出力ピンを返す
+func (in *NodeInput) OutputPins() []models.Pin {
+	return pinsOfType(in.Node, "output")
+}
+
+// First は最初に接続されている入力ピンの値を返す
+func (in *NodeInput) First() (interface{}, bool) {
+	for _, pin := range in.InputPins() {
+		if value, ok := in.Inputs[pin.ID]; ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// Named はピンIDまたはラベルで入力値を取得する
+func (in *NodeInput) Named(name string) (interface{}, bool) {
+	for _, pin := range in.InputPins() {
+		if pin.ID == name || pin.Label == name {
+			value, ok := in.Inputs[pin.ID]
+			return value, ok
+		}
+	}
+	return nil, false
+}
+
+// ConfigString は設定値を文字列として返す
+func (in *NodeInput) ConfigString(key string) string {
+	if value, ok := in.Config[key].(string); ok {
+		return value
+	}
+	return ""
+}
+
+// OutputAll はすべての出力ピンに同じ値を設定した出力を返す
+func (in *NodeInput) OutputAll(value interface{}) map[string]interface{} {
+	outputs := make(map[string]interface{})
+	for _, pin := range in.OutputPins() {
+		outputs[pin.ID] = value
+	}
+	return outputs
+}
+
+func pinsOfType(node *models.Node, pinType string) []models.Pin {
+	var pins []models.Pin
+	for _, pin := range node.Pins {
+		if pin.Type == pinType {
+			pins = append(pins, pin)
+		}
+	}
+	return pins
+}
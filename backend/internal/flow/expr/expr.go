@@ -0,0 +1,485 @@
+// Package expr はノード設定値に埋め込む式（{{ ... }}）の構文解析と評価を提供する
+// 式は値の参照（request.query.limit, nodes.db-1.output[0].id）・リテラル・フィルター（| default(20)）のみで構成され、
+// 任意のコードの実行や副作用を持たない
+package expr
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MaxTemplateBytes は式を含む設定値の最大サイズ
+const MaxTemplateBytes = 16 << 10
+
+// Env は式のルート名（request, nodes 等）に対応する値を返す
+// 値が存在しない場合はfalseを返す
+type Env func(root string) (interface{}, bool)
+
+// Template は式を含む設定値の文字列を構文解析したもの
+type Template struct {
+	parts []part
+}
+
+// part は固定の文字列、または {{ }} 内の1つの式
+type part struct {
+	text string
+	expr *expression
+}
+
+// expression は値（参照・リテラル）とフィルターの列
+type expression struct {
+	value   operand
+	filters []filterCall
+}
+
+// operand は参照またはリテラル
+type operand struct {
+	path    *Path
+	literal interface{}
+}
+
+type filterCall struct {
+	name string
+	fn   filterFunc
+	args []operand
+}
+
+// Path は値の参照（ルート名と、フィールド名・インデックスの列）
+type Path struct {
+	Root     string
+	Segments []interface{} // string（フィールド名）またはint（インデックス）
+}
+
+// String は参照を式の形式で返す
+func (p *Path) String() string {
+	var b strings.Builder
+	b.WriteString(p.Root)
+	for _, seg := range p.Segments {
+		switch s := seg.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", s)
+		default:
+			fmt.Fprintf(&b, ".%s", s)
+		}
+	}
+	return b.String()
+}
+
+// Field はi番目のセグメントがフィールド名の場合にその名前を返す
+func (p *Path) Field(i int) (string, bool) {
+	if i >= len(p.Segments) {
+		return "", false
+	}
+	s, ok := p.Segments[i].(string)
+	return s, ok
+}
+
+// HasTemplate は文字列が式（{{ }}）を含むかどうかを返す
+func HasTemplate(s string) bool {
+	return strings.Contains(s, "{{")
+}
+
+// Compile は式を含む文字列を構文解析する
+func Compile(s string) (*Template, error) {
+	if len(s) > MaxTemplateBytes {
+		return nil, fmt.Errorf("expression is too large")
+	}
+
+	t := &Template{}
+	rest := s
+	for {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			if rest != "" {
+				t.parts = append(t.parts, part{text: rest})
+			}
+			return t, nil
+		}
+		if start > 0 {
+			t.parts = append(t.parts, part{text: rest[:start]})
+		}
+
+		p := &parser{src: rest, pos: start + 2, base: len(s) - len(rest)}
+		e, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if !strings.HasPrefix(p.src[p.pos:], "}}") {
+			return nil, p.errorf("expected }}")
+		}
+		t.parts = append(t.parts, part{expr: e})
+		rest = rest[p.pos+2:]
+	}
+}
+
+// Paths は式が参照するすべての値（フィルターの引数を含む）を返す
+func (t *Template) Paths() []*Path {
+	var paths []*Path
+	for _, p := range t.parts {
+		if p.expr == nil {
+			continue
+		}
+		if p.expr.value.path != nil {
+			paths = append(paths, p.expr.value.path)
+		}
+		for _, f := range p.expr.filters {
+			for _, arg := range f.args {
+				if arg.path != nil {
+					paths = append(paths, arg.path)
+				}
+			}
+		}
+	}
+	return paths
+}
+
+// Eval は式を評価する
+// 文字列全体が1つの式の場合はその値（型を保持する）を、それ以外の場合は値を埋め込んだ文字列を返す
+// 文字列全体が1つの式で値が未定義の場合はfalseを返す（埋め込みの場合、未定義の値は空文字列になる）
+func (t *Template) Eval(env Env) (interface{}, bool, error) {
+	if len(t.parts) == 1 && t.parts[0].expr != nil {
+		return t.parts[0].expr.eval(env)
+	}
+
+	var b strings.Builder
+	for _, p := range t.parts {
+		if p.expr == nil {
+			b.WriteString(p.text)
+			continue
+		}
+		value, defined, err := p.expr.eval(env)
+		if err != nil {
+			return nil, false, err
+		}
+		if defined {
+			b.WriteString(toString(value))
+		}
+	}
+	return b.String(), true, nil
+}
+
+func (e *expression) eval(env Env) (interface{}, bool, error) {
+	value, defined := e.value.eval(env)
+	for _, f := range e.filters {
+		args := make([]interface{}, len(f.args))
+		for i, arg := range f.args {
+			args[i], _ = arg.eval(env)
+		}
+		var err error
+		value, defined, err = f.fn(value, defined, args)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", f.name, err)
+		}
+	}
+	return value, defined, nil
+}
+
+func (o operand) eval(env Env) (interface{}, bool) {
+	if o.path == nil {
+		return o.literal, true
+	}
+	value, ok := env(o.path.Root)
+	if !ok {
+		return nil, false
+	}
+	for _, seg := range o.path.Segments {
+		if value, ok = index(value, seg); !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// index はマップのフィールド、または配列の要素（負のインデックスは末尾から）を取得する
+func index(value interface{}, seg interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		key, ok := seg.(string)
+		if !ok {
+			return nil, false
+		}
+		item, ok := v[key]
+		return item, ok
+	case []interface{}:
+		i, ok := sliceIndex(seg, len(v))
+		if !ok {
+			return nil, false
+		}
+		return v[i], true
+	}
+
+	// DBの行（[]map[string]interface{}）などの型付きのマップ・配列
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Map:
+		key, ok := seg.(string)
+		if !ok || rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		item := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+		if !item.IsValid() {
+			return nil, false
+		}
+		return item.Interface(), true
+	case reflect.Slice, reflect.Array:
+		i, ok := sliceIndex(seg, rv.Len())
+		if !ok {
+			return nil, false
+		}
+		return rv.Index(i).Interface(), true
+	}
+	return nil, false
+}
+
+// sliceIndex はセグメントを長さnの配列のインデックスに変換する（数字のフィールド名も受け付ける）
+func sliceIndex(seg interface{}, n int) (int, bool) {
+	var i int
+	switch s := seg.(type) {
+	case int:
+		i = s
+	case string:
+		parsed, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, false
+		}
+		i = parsed
+	}
+	if i < 0 {
+		i += n
+	}
+	if i < 0 || i >= n {
+		return 0, false
+	}
+	return i, true
+}
+
+// toString は埋め込む値を文字列に変換する（オブジェクト・配列はJSON）
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// parser は {{ }} 内の式の構文解析器
+//
+//	expression = operand { "|" filter }
+//	filter     = name [ "(" [ operand { "," operand } ] ")" ]
+//	operand    = literal | path
+//	path       = name { "." field | "[" ( integer | string ) "]" }
+type parser struct {
+	src  string
+	pos  int
+	base int // srcの設定値の文字列全体での位置（エラーメッセージ用）
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid expression at offset %d: %s", p.base+p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\n' || p.src[p.pos] == '\r') {
+		p.pos++
+	}
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) parseExpression() (*expression, error) {
+	value, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	e := &expression{value: value}
+
+	for {
+		p.skipSpace()
+		if p.peek() != '|' {
+			return e, nil
+		}
+		p.pos++
+		p.skipSpace()
+
+		name := p.scanName(false)
+		if name == "" {
+			return nil, p.errorf("expected filter name")
+		}
+		def, ok := filters[name]
+		if !ok {
+			return nil, p.errorf("unknown filter %q", name)
+		}
+		call := filterCall{name: name, fn: def.fn}
+
+		p.skipSpace()
+		if p.peek() == '(' {
+			p.pos++
+			p.skipSpace()
+			for p.peek() != ')' {
+				arg, err := p.parseOperand()
+				if err != nil {
+					return nil, err
+				}
+				call.args = append(call.args, arg)
+				p.skipSpace()
+				if p.peek() == ',' {
+					p.pos++
+					p.skipSpace()
+					continue
+				}
+				if p.peek() != ')' {
+					return nil, p.errorf("expected , or )")
+				}
+			}
+			p.pos++
+		}
+		if len(call.args) < def.minArgs || len(call.args) > def.maxArgs {
+			return nil, p.errorf("filter %q takes %s", name, def.arity())
+		}
+		e.filters = append(e.filters, call)
+	}
+}
+
+func (p *parser) parseOperand() (operand, error) {
+	p.skipSpace()
+	c := p.peek()
+	switch {
+	case c == '"' || c == '\'':
+		s, err := p.scanString()
+		return operand{literal: s}, err
+	case c == '-' || (c >= '0' && c <= '9'):
+		n, err := p.scanNumber()
+		return operand{literal: n}, err
+	}
+
+	name := p.scanName(false)
+	if name == "" {
+		return operand{}, p.errorf("expected a value")
+	}
+	switch name {
+	case "true":
+		return operand{literal: true}, nil
+	case "false":
+		return operand{literal: false}, nil
+	case "null":
+		return operand{literal: nil}, nil
+	}
+
+	path := &Path{Root: name}
+	for {
+		switch p.peek() {
+		case '.':
+			p.pos++
+			field := p.scanName(true)
+			if field == "" {
+				return operand{}, p.errorf("expected field name after .")
+			}
+			path.Segments = append(path.Segments, field)
+		case '[':
+			p.pos++
+			p.skipSpace()
+			switch c := p.peek(); {
+			case c == '"' || c == '\'':
+				s, err := p.scanString()
+				if err != nil {
+					return operand{}, err
+				}
+				path.Segments = append(path.Segments, s)
+			default:
+				n, err := p.scanNumber()
+				if err != nil {
+					return operand{}, err
+				}
+				if n != float64(int(n)) {
+					return operand{}, p.errorf("index must be an integer")
+				}
+				path.Segments = append(path.Segments, int(n))
+			}
+			p.skipSpace()
+			if p.peek() != ']' {
+				return operand{}, p.errorf("expected ]")
+			}
+			p.pos++
+		default:
+			return operand{path: path}, nil
+		}
+	}
+}
+
+// scanName は名前（英字または_で始まり、英数字・_・-を含む）を読む
+// fieldがtrueの場合は数字で始まる名前（配列のインデックス・IDの一部）も受け付ける
+func (p *parser) scanName(field bool) string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := rune(p.src[p.pos])
+		first := p.pos == start
+		if c == '_' || unicode.IsLetter(c) && c < unicode.MaxASCII ||
+			(!first || field) && (unicode.IsDigit(c) || c == '-') {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *parser) scanNumber() (float64, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.src) && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '.') {
+		p.pos++
+	}
+	text := p.src[start:p.pos]
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		p.pos = start
+		return 0, p.errorf("invalid number %q", text)
+	}
+	return n, nil
+}
+
+// scanString は ' または " で囲まれた文字列を読む（\ で引用符と\をエスケープできる）
+func (p *parser) scanString() (string, error) {
+	quote := p.src[p.pos]
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.src):
+			b.WriteByte(p.src[p.pos+1])
+			p.pos += 2
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
@@ -0,0 +1,141 @@
+package expr
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testEnv() Env {
+	values := map[string]interface{}{
+		"request": map[string]interface{}{
+			"query": map[string]interface{}{"limit": "50", "name": "  Alice ", "empty": nil},
+			"body": map[string]interface{}{
+				"items": []interface{}{
+					map[string]interface{}{"id": float64(1)},
+					map[string]interface{}{"id": float64(2)},
+				},
+				"tags":  []interface{}{"a", "b", "c"},
+				"attrs": map[string]interface{}{"b": true, "a": "x", "my key": "spaced"},
+			},
+		},
+		"nodes": map[string]interface{}{
+			"db-1": map[string]interface{}{
+				"output": []map[string]interface{}{{"id": 10, "name": "row"}},
+			},
+		},
+	}
+	return func(root string) (interface{}, bool) {
+		value, ok := values[root]
+		return value, ok
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{name: "unterminated", src: "{{ request.query.limit", wantErr: "expected }}"},
+		{name: "empty", src: "{{ }}", wantErr: "expected a value"},
+		{name: "missing field name", src: "{{ request. }}", wantErr: "expected field name after ."},
+		{name: "unknown filter", src: "{{ request | exec('rm') }}", wantErr: `unknown filter "exec"`},
+		{name: "missing filter name", src: "{{ request | }}", wantErr: "expected filter name"},
+		{name: "filter arity", src: "{{ request | default }}", wantErr: `filter "default" takes 1 argument(s)`},
+		{name: "too many arguments", src: "{{ request | upper(1) }}", wantErr: `filter "upper" takes no arguments`},
+		{name: "unclosed arguments", src: "{{ request | default(1 2) }}", wantErr: "expected , or )"},
+		{name: "unterminated string", src: `{{ "abc }}`, wantErr: "unterminated string"},
+		{name: "fractional index", src: "{{ request.items[1.5] }}", wantErr: "index must be an integer"},
+		{name: "unclosed index", src: "{{ request.items[1 }}", wantErr: "expected ]"},
+		{name: "invalid number", src: "{{ 1.2.3 }}", wantErr: `invalid number "1.2.3"`},
+		{name: "offset of second expression", src: "x {{ a }} {{ | }}", wantErr: "offset 13"},
+		{name: "too large", src: "{{ a }}" + strings.Repeat(" ", MaxTemplateBytes), wantErr: "too large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Compile(%q) error = %v, want %q", tt.src, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTemplateEval(t *testing.T) {
+	tests := []struct {
+		name        string
+		src         string
+		want        interface{}
+		wantDefined bool
+		wantErr     string
+	}{
+		{name: "plain text", src: "hello", want: "hello", wantDefined: true},
+		{name: "whole expression keeps type", src: "{{ request.body.items }}", want: []interface{}{map[string]interface{}{"id": float64(1)}, map[string]interface{}{"id": float64(2)}}, wantDefined: true},
+		{name: "index", src: "{{ request.body.items[1].id }}", want: float64(2), wantDefined: true},
+		{name: "negative index", src: "{{ request.body.tags[-1] }}", want: "c", wantDefined: true},
+		{name: "numeric field as index", src: "{{ request.body.tags.0 }}", want: "a", wantDefined: true},
+		{name: "quoted field", src: `{{ request.body.attrs["my key"] }}`, want: "spaced", wantDefined: true},
+		{name: "typed rows", src: "{{ nodes.db-1.output[0].name }}", want: "row", wantDefined: true},
+		{name: "undefined root", src: "{{ missing }}", wantDefined: false},
+		{name: "undefined field", src: "{{ request.query.offset }}", wantDefined: false},
+		{name: "index out of range", src: "{{ request.body.tags[3] }}", wantDefined: false},
+		{name: "literals", src: "{{ 'it\\'s' }}", want: "it's", wantDefined: true},
+		{name: "null literal", src: "{{ null }}", want: nil, wantDefined: true},
+		{name: "default for undefined", src: "{{ request.query.offset | default(0) }}", want: float64(0), wantDefined: true},
+		{name: "default for null", src: "{{ request.query.empty | default('none') }}", want: "none", wantDefined: true},
+		{name: "default keeps value", src: "{{ request.query.limit | default(20) }}", want: "50", wantDefined: true},
+		{name: "default with path argument", src: "{{ request.query.offset | default(request.query.limit) }}", want: "50", wantDefined: true},
+		{name: "int", src: "{{ request.query.limit | int }}", want: 50, wantDefined: true},
+		{name: "chained filters", src: "{{ request.query.name | trim | upper }}", want: "ALICE", wantDefined: true},
+		{name: "filters keep undefined", src: "{{ request.query.offset | int }}", wantDefined: false},
+		{name: "length", src: "{{ request.body.tags | length }}", want: float64(3), wantDefined: true},
+		{name: "first and last", src: "{{ request.body.items | last }}", want: map[string]interface{}{"id": float64(2)}, wantDefined: true},
+		{name: "keys", src: "{{ request.body.attrs | keys | join('-') }}", want: "a-b-my key", wantDefined: true},
+		{name: "json", src: "{{ request.body.tags | json }}", want: `["a","b","c"]`, wantDefined: true},
+		{name: "interpolation", src: "limit={{ request.query.limit }}&tags={{ request.body.tags }}", want: `limit=50&tags=["a","b","c"]`, wantDefined: true},
+		{name: "undefined interpolates as empty", src: "[{{ request.query.offset }}]", want: "[]", wantDefined: true},
+		{name: "conversion error", src: "{{ request.query.name | int }}", wantErr: `int: cannot convert "  Alice " to an integer`},
+		{name: "error inside interpolation", src: "n={{ request.body.attrs | join }}", wantErr: "join: value of type map[string]interface {} is not an array"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Compile(tt.src)
+			if err != nil {
+				t.Fatalf("Compile(%q) error = %v", tt.src, err)
+			}
+			got, defined, err := tmpl.Eval(testEnv())
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Eval() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Eval() error = %v", err)
+			}
+			if defined != tt.wantDefined {
+				t.Errorf("Eval() defined = %v, want %v", defined, tt.wantDefined)
+			}
+			if tt.wantDefined && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Eval() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplatePaths(t *testing.T) {
+	tmpl, err := Compile("{{ nodes.db-1.output[0].id }}/{{ request.query.limit | default(request.body['max']) }}")
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	var got []string
+	for _, p := range tmpl.Paths() {
+		got = append(got, p.String())
+	}
+	want := []string{"nodes.db-1.output[0].id", "request.query.limit", "request.body.max"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Paths() = %v, want %v", got, want)
+	}
+}
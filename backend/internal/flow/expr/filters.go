@@ -0,0 +1,201 @@
+package expr
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// filterFunc はフィルターの処理
+// 値が未定義（definedがfalse）の場合、default以外のフィルターは未定義のまま返す
+type filterFunc func(value interface{}, defined bool, args []interface{}) (interface{}, bool, error)
+
+type filterDef struct {
+	fn      filterFunc
+	minArgs int
+	maxArgs int
+}
+
+func (d filterDef) arity() string {
+	switch {
+	case d.maxArgs == 0:
+		return "no arguments"
+	case d.minArgs == d.maxArgs:
+		return fmt.Sprintf("%d argument(s)", d.minArgs)
+	default:
+		return fmt.Sprintf("%d to %d arguments", d.minArgs, d.maxArgs)
+	}
+}
+
+// filters は式で使用できるフィルター
+var filters = map[string]filterDef{
+	// default(v) は値が未定義またはnullの場合にvを返す
+	"default": {fn: func(value interface{}, defined bool, args []interface{}) (interface{}, bool, error) {
+		if !defined || value == nil {
+			return args[0], true, nil
+		}
+		return value, true, nil
+	}, minArgs: 1, maxArgs: 1},
+	"int":    {fn: defined(toInt)},
+	"number": {fn: defined(toNumber)},
+	"string": {fn: defined(func(value interface{}) (interface{}, error) {
+		return toString(value), nil
+	})},
+	"bool":  {fn: defined(toBool)},
+	"upper": {fn: defined(stringFilter(strings.ToUpper))},
+	"lower": {fn: defined(stringFilter(strings.ToLower))},
+	"trim":  {fn: defined(stringFilter(strings.TrimSpace))},
+	"length": {fn: defined(func(value interface{}) (interface{}, error) {
+		if s, ok := value.(string); ok {
+			return float64(utf8.RuneCountInString(s)), nil
+		}
+		rv := reflect.ValueOf(value)
+		switch rv.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return float64(rv.Len()), nil
+		}
+		return nil, fmt.Errorf("value of type %T has no length", value)
+	})},
+	"first": {fn: func(value interface{}, defined bool, args []interface{}) (interface{}, bool, error) {
+		if !defined || value == nil {
+			return nil, false, nil
+		}
+		item, ok := index(value, 0)
+		return item, ok, nil
+	}},
+	"last": {fn: func(value interface{}, defined bool, args []interface{}) (interface{}, bool, error) {
+		if !defined || value == nil {
+			return nil, false, nil
+		}
+		item, ok := index(value, -1)
+		return item, ok, nil
+	}},
+	// keys はオブジェクトのキーを名前順に返す
+	"keys": {fn: defined(func(value interface{}) (interface{}, error) {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("value of type %T is not an object", value)
+		}
+		keys := make([]string, 0, len(m))
+		for key := range m {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		list := make([]interface{}, len(keys))
+		for i, key := range keys {
+			list[i] = key
+		}
+		return list, nil
+	})},
+	// join(sep) は配列の要素を区切り文字（既定は ,）で連結する
+	"join": {fn: func(value interface{}, defined bool, args []interface{}) (interface{}, bool, error) {
+		if !defined {
+			return nil, false, nil
+		}
+		sep := ","
+		if len(args) > 0 {
+			sep = toString(args[0])
+		}
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return nil, false, fmt.Errorf("value of type %T is not an array", value)
+		}
+		items := make([]string, rv.Len())
+		for i := range items {
+			items[i] = toString(rv.Index(i).Interface())
+		}
+		return strings.Join(items, sep), true, nil
+	}, maxArgs: 1},
+	"json": {fn: defined(func(value interface{}) (interface{}, error) {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	})},
+}
+
+// defined は値が定義されている場合にのみ適用する引数なしのフィルターを作成する
+func defined(fn func(value interface{}) (interface{}, error)) filterFunc {
+	return func(value interface{}, defined bool, args []interface{}) (interface{}, bool, error) {
+		if !defined {
+			return nil, false, nil
+		}
+		result, err := fn(value)
+		if err != nil {
+			return nil, false, err
+		}
+		return result, true, nil
+	}
+}
+
+func stringFilter(fn func(string) string) func(value interface{}) (interface{}, error) {
+	return func(value interface{}) (interface{}, error) {
+		if value == nil {
+			return nil, nil
+		}
+		return fn(toString(value)), nil
+	}
+}
+
+func toNumber(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %q to a number", v)
+		}
+		return n, nil
+	case bool:
+		if v {
+			return float64(1), nil
+		}
+		return float64(0), nil
+	}
+	return nil, fmt.Errorf("cannot convert %T to a number", value)
+}
+
+func toInt(value interface{}) (interface{}, error) {
+	if s, ok := value.(string); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %q to an integer", s)
+		}
+		return n, nil
+	}
+	n, err := toNumber(value)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	return int(n.(float64)), nil
+}
+
+func toBool(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	case float64:
+		return v != 0, nil
+	case int:
+		return v != 0, nil
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %q to a boolean", v)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("cannot convert %T to a boolean", value)
+}
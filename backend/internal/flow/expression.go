@@ -0,0 +1,388 @@
+package flow
+
+import (
+	"container/list"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/necorox/FlowCore/backend/internal/flow/expr"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// 式で参照できる値のルート
+const (
+	exprRequest = "request" // リクエスト全体（method, path, params, query, headers, cookies, body, form, files）
+	exprParams  = "params"  // request.params の省略形
+	exprQuery   = "query"   // request.query の省略形
+	exprBody    = "body"    // request.body の省略形
+	exprInput   = "input"   // このノードの入力（ピンのラベルまたはID → 値）
+	exprNodes   = "nodes"   // 実行済みのノードの出力（ノードID → 出力ピンのラベルまたはID → 値）
+	exprAuth    = "auth"    // request.auth の省略形（認証された呼び出し元。publicのエンドポイントではnull）
+)
+
+var exprRoots = map[string]bool{
+	exprRequest: true, exprParams: true, exprQuery: true, exprBody: true, exprInput: true, exprNodes: true, exprAuth: true,
+}
+
+// maxCachedTemplates は構文解析済みの式をキャッシュする最大数
+const maxCachedTemplates = 4096
+
+// templates は構文解析済みの式のキャッシュ（フロー定義の文字列 → *expr.Template）
+// 編集・テスト実行・検証で使われた式も含まれるため、最近使われていない式から破棄する
+var templates = newTemplateCache(maxCachedTemplates)
+
+// templateCache は最大数を超えると最も長く使われていない式を破棄するキャッシュ
+type templateCache struct {
+	mu    sync.Mutex
+	max   int
+	order *list.List               // 最近使われた順（先頭が最新）。要素の値は*templateEntry
+	items map[string]*list.Element // 式の文字列 → orderの要素
+}
+
+type templateEntry struct {
+	source   string
+	template *expr.Template
+}
+
+func newTemplateCache(max int) *templateCache {
+	return &templateCache{max: max, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *templateCache) get(s string) (*expr.Template, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[s]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*templateEntry).template, true
+}
+
+func (c *templateCache) add(s string, t *expr.Template) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[s]; ok {
+		c.order.MoveToFront(e)
+		return
+	}
+	c.items[s] = c.order.PushFront(&templateEntry{source: s, template: t})
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*templateEntry).source)
+	}
+}
+
+// compileTemplate は式を含む設定値を構文解析する（結果はキャッシュする）
+func compileTemplate(s string) (*expr.Template, error) {
+	if t, ok := templates.get(s); ok {
+		return t, nil
+	}
+	t, err := expr.Compile(s)
+	if err != nil {
+		return nil, err
+	}
+	templates.add(s, t)
+	return t, nil
+}
+
+// rawConfigKeys は式を評価しない設定のキーを返す
+func rawConfigKeys(schema []models.ConfigField) map[string]bool {
+	var raw map[string]bool
+	for _, field := range schema {
+		if field.Raw {
+			if raw == nil {
+				raw = map[string]bool{}
+			}
+			raw[field.Key] = true
+		}
+	}
+	return raw
+}
+
+// evalConfig はノード設定に含まれる式（{{ }}）を評価した設定を返す
+// 値が未定義になった式のキーは設定から除き（配列の要素の場合はnull）、ノードは元の設定（in.Node.Config）と比較して判別できる
+// 式を含まない場合は元の設定をそのまま返す
+func (r *graphRun) evalConfig(ec *ExecutionContext, node *models.Node, nodeType NodeType, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	if node.Config == nil {
+		return map[string]interface{}{}, false, nil
+	}
+
+	env := r.exprEnv(ec, node, inputs)
+	raw := rawConfigKeys(nodeType.ConfigSchema)
+
+	var config map[string]interface{}
+	for _, key := range sortedConfigKeys(node.Config) {
+		if raw[key] {
+			continue
+		}
+		value, defined, changed, err := evalConfigValue(node.Config[key], env)
+		if err != nil {
+			return nil, false, &Error{Class: ErrorValidation, Err: fmt.Errorf("config %q: %w", key, err)}
+		}
+		if !changed {
+			continue
+		}
+		if config == nil {
+			config = copyConfig(node.Config)
+		}
+		if defined {
+			config[key] = value
+		} else {
+			delete(config, key)
+		}
+	}
+	if config == nil {
+		return node.Config, false, nil
+	}
+	return config, true, nil
+}
+
+// evalConfigValue は設定値（入れ子のオブジェクト・配列を含む）の式を評価する
+// changedは式を含んでいた場合にtrue、definedは値が未定義の式の場合にfalse
+func evalConfigValue(value interface{}, env expr.Env) (result interface{}, defined, changed bool, err error) {
+	switch v := value.(type) {
+	case string:
+		if !expr.HasTemplate(v) {
+			return v, true, false, nil
+		}
+		t, err := compileTemplate(v)
+		if err != nil {
+			return nil, false, false, err
+		}
+		result, defined, err := t.Eval(env)
+		return result, defined, true, err
+
+	case map[string]interface{}:
+		var copied map[string]interface{}
+		for _, key := range sortedConfigKeys(v) {
+			item, itemDefined, itemChanged, err := evalConfigValue(v[key], env)
+			if err != nil {
+				return nil, false, false, fmt.Errorf("%s: %w", key, err)
+			}
+			if !itemChanged {
+				continue
+			}
+			if copied == nil {
+				copied = copyConfig(v)
+			}
+			if itemDefined {
+				copied[key] = item
+			} else {
+				delete(copied, key)
+			}
+		}
+		if copied == nil {
+			return v, true, false, nil
+		}
+		return copied, true, true, nil
+
+	case []interface{}:
+		var copied []interface{}
+		for i, item := range v {
+			item, _, itemChanged, err := evalConfigValue(item, env)
+			if err != nil {
+				return nil, false, false, fmt.Errorf("[%d]: %w", i, err)
+			}
+			if !itemChanged {
+				continue
+			}
+			if copied == nil {
+				copied = append([]interface{}(nil), v...)
+			}
+			copied[i] = item
+		}
+		if copied == nil {
+			return v, true, false, nil
+		}
+		return copied, true, true, nil
+	}
+	return value, true, false, nil
+}
+
+// exprEnv はノードの設定の式を評価する環境を返す
+// nodesはそのグラフ（フロー全体・ノードの本体）で実行済みのノードのみを参照できる
+func (r *graphRun) exprEnv(ec *ExecutionContext, node *models.Node, inputs map[string]interface{}) expr.Env {
+	var request map[string]interface{}
+	requestMap := func() map[string]interface{} {
+		if request == nil {
+			request = map[string]interface{}{}
+			if ec.Request != nil {
+				request = ec.Request.Map()
+			}
+		}
+		return request
+	}
+
+	return func(root string) (interface{}, bool) {
+		switch root {
+		case exprRequest:
+			return requestMap(), true
+		case exprParams, exprQuery, exprBody, exprAuth:
+			value, ok := requestMap()[root]
+			return value, ok
+		case exprInput:
+			values := map[string]interface{}{}
+			for _, pin := range node.Pins {
+				if value, ok := inputs[pin.ID]; ok && pin.Type == "input" {
+					pinValues(values, &pin, value)
+				}
+			}
+			return values, true
+		case exprNodes:
+			nodes := map[string]interface{}{}
+			for id, n := range r.g.nodes {
+				outputs, ok := ec.Outputs(id)
+				if !ok {
+					continue
+				}
+				values := map[string]interface{}{}
+				for _, pin := range n.Pins {
+					if value, ok := outputs[pin.ID]; ok {
+						pinValues(values, &pin, value)
+					}
+				}
+				nodes[id] = values
+			}
+			return nodes, true
+		}
+		return nil, false
+	}
+}
+
+// pinValues はピンの値をIDとラベル（IDと重複しない場合）のキーで設定する
+func pinValues(values map[string]interface{}, pin *models.Pin, value interface{}) {
+	values[pin.ID] = value
+	if _, exists := values[pin.Label]; pin.Label != "" && !exists {
+		values[pin.Label] = value
+	}
+}
+
+// validateExpressions はノード設定の式を構文解析し、参照するルートと入力ピンを検証する
+// nodes.<id> の参照はグラフの構築後にvalidateNodeReferencesで検証する
+func validateExpressions(v *ValidationErrors, node *models.Node, schema []models.ConfigField) {
+	raw := rawConfigKeys(schema)
+	walkTemplates(node.Config, raw, func(key string, t *expr.Template, err error) {
+		if err != nil {
+			v.node(node.ID, "config %q: %v", key, err)
+			return
+		}
+		for _, path := range t.Paths() {
+			if !exprRoots[path.Root] {
+				v.node(node.ID, "config %q: unknown reference %q", key, path.Root)
+				continue
+			}
+			if path.Root != exprInput {
+				continue
+			}
+			name, ok := path.Field(0)
+			if ok && !hasPin(node, "input", name) {
+				v.node(node.ID, "config %q: %q references unknown input pin %q", key, path.String(), name)
+			}
+		}
+	})
+}
+
+// validateNodeReferences は式の nodes.<id> の参照が、同じグラフでそのノードより前に実行されるノードの出力ピンであることを検証する
+func validateNodeReferences(v *ValidationErrors, f *models.Flow, g *graph, registry *Registry) {
+	for i := range f.Nodes {
+		node := &f.Nodes[i]
+		nodeType, ok := registry.Lookup(node.Type)
+		if !ok {
+			continue
+		}
+		var ancestors map[string]bool
+		walkTemplates(node.Config, rawConfigKeys(nodeType.ConfigSchema), func(key string, t *expr.Template, err error) {
+			if err != nil {
+				return
+			}
+			for _, path := range t.Paths() {
+				if path.Root != exprNodes {
+					continue
+				}
+				id, ok := path.Field(0)
+				if !ok {
+					continue
+				}
+				target, exists := g.nodes[id]
+				if !exists {
+					v.node(node.ID, "config %q: %q references unknown node %q", key, path.String(), id)
+					continue
+				}
+				if ancestors == nil {
+					ancestors = g.ancestorsOf(node.ID)
+				}
+				if !ancestors[id] {
+					v.node(node.ID, "config %q: node %q is not connected upstream of this node", key, id)
+					continue
+				}
+				if pin, ok := path.Field(1); ok && !hasPin(target, "output", pin) {
+					v.node(node.ID, "config %q: %q references unknown output pin %q of node %q", key, path.String(), pin, id)
+				}
+			}
+		})
+	}
+}
+
+// walkTemplates は設定値に含まれる式（{{ }}）を含む文字列を構文解析してfnに渡す
+// keyは設定内の位置（例: where.user_id, cookies[0].value）
+func walkTemplates(config map[string]interface{}, raw map[string]bool, fn func(key string, t *expr.Template, err error)) {
+	var walk func(key string, value interface{})
+	walk = func(key string, value interface{}) {
+		switch v := value.(type) {
+		case string:
+			if expr.HasTemplate(v) {
+				t, err := compileTemplate(v)
+				fn(key, t, err)
+			}
+		case map[string]interface{}:
+			for _, k := range sortedConfigKeys(v) {
+				walk(key+"."+k, v[k])
+			}
+		case []interface{}:
+			for i, item := range v {
+				walk(fmt.Sprintf("%s[%d]", key, i), item)
+			}
+		}
+	}
+	for _, key := range sortedConfigKeys(config) {
+		if !raw[key] {
+			walk(key, config[key])
+		}
+	}
+}
+
+// IsTemplate は設定値が実行時に評価される式（{{ }}）を含む文字列かどうかを返す
+// ノードのValidateは、式を含む値の検証を実行時に行う
+func IsTemplate(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && expr.HasTemplate(s)
+}
+
+func hasPin(node *models.Node, pinType, name string) bool {
+	for _, pin := range node.Pins {
+		if pin.Type == pinType && (pin.ID == name || pin.Label == name) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedConfigKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func copyConfig(m map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,55 @@
+package flow
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/necorox/FlowCore/backend/internal/flow/expr"
+)
+
+// TestTemplateCacheEvictsLeastRecentlyUsed はキャッシュが最大数を超えた場合に最も長く使われていない式を破棄することを確認する
+func TestTemplateCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	tests := []struct {
+		name    string
+		use     []string // 順に追加（既存の場合は参照）する式
+		want    []string // キャッシュに残る式
+		evicted []string // 破棄される式
+	}{
+		{name: "within limit", use: []string{"a", "b", "c"}, want: []string{"a", "b", "c"}},
+		{name: "oldest evicted", use: []string{"a", "b", "c", "d"}, want: []string{"b", "c", "d"}, evicted: []string{"a"}},
+		{name: "recently used kept", use: []string{"a", "b", "c", "a", "d"}, want: []string{"a", "c", "d"}, evicted: []string{"b"}},
+		{name: "many distinct", use: []string{"a", "b", "c", "d", "e", "f"}, want: []string{"d", "e", "f"}, evicted: []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := newTemplateCache(3)
+			for _, s := range tt.use {
+				if _, ok := cache.get(s); !ok {
+					cache.add(s, mustCompile(t, s))
+				}
+			}
+			if got := len(cache.items); got != len(tt.want) {
+				t.Errorf("cached %d templates, want %d", got, len(tt.want))
+			}
+			for _, s := range tt.want {
+				if _, ok := cache.items[s]; !ok {
+					t.Errorf("%q was evicted, want cached", s)
+				}
+			}
+			for _, s := range tt.evicted {
+				if _, ok := cache.items[s]; ok {
+					t.Errorf("%q is cached, want evicted", s)
+				}
+			}
+		})
+	}
+}
+
+func mustCompile(t *testing.T, name string) *expr.Template {
+	t.Helper()
+	tmpl, err := expr.Compile(fmt.Sprintf("{{ request.%s }}", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tmpl
+}
@@ -0,0 +1,104 @@
+package flow
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// ErrCycle はフローに循環がある場合のエラー
+var ErrCycle = errors.New("flow contains a cycle")
+
+// graph は実行用に解析されたフローグラフ
+type graph struct {
+	flow     *models.Flow
+	nodes    map[string]*models.Node
+	pins     map[string]*models.Pin
+	incoming map[string][]models.Connection // 接続先ノードID → 接続
+	outgoing map[string][]models.Connection // 接続元ノードID → 接続
+	order    []*models.Node
+}
+
+// newGraph はフロー定義からグラフを構築し、実行順序を決定する
+func newGraph(f *models.Flow) (*graph, error) {
+	g := &graph{
+		flow:     f,
+		nodes:    make(map[string]*models.Node, len(f.Nodes)),
+		pins:     make(map[string]*models.Pin),
+		incoming: make(map[string][]models.Connection),
+		outgoing: make(map[string][]models.Connection),
+	}
+
+	for i := range f.Nodes {
+		node := &f.Nodes[i]
+		if _, exists := g.nodes[node.ID]; exists {
+			return nil, fmt.Errorf("duplicate node id %q", node.ID)
+		}
+		g.nodes[node.ID] = node
+		for j := range node.Pins {
+			g.pins[node.Pins[j].ID] = &node.Pins[j]
+		}
+	}
+
+	for _, conn := range f.Connections {
+		if _, ok := g.nodes[conn.From.NodeID]; !ok {
+			return nil, fmt.Errorf("connection %q references unknown node %q", conn.ID, conn.From.NodeID)
+		}
+		if _, ok := g.nodes[conn.To.NodeID]; !ok {
+			return nil, fmt.Errorf("connection %q references unknown node %q", conn.ID, conn.To.NodeID)
+		}
+		g.incoming[conn.To.NodeID] = append(g.incoming[conn.To.NodeID], conn)
+		g.outgoing[conn.From.NodeID] = append(g.outgoing[conn.From.NodeID], conn)
+	}
+
+	order, err := g.topologicalSort()
+	if err != nil {
+		return nil, err
+	}
+	g.order = order
+
+	return g, nil
+}
+
+// topologicalSort はノードを依存関係順に並べる
+// 実行可能なノードが複数ある場合は定義順を優先し、結果を決定的にする
+func (g *graph) topologicalSort() ([]*models.Node, error) {
+	indegree := make(map[string]int, len(g.nodes))
+	for _, conn := range g.flow.Connections {
+		indegree[conn.To.NodeID]++
+	}
+
+	done := make(map[string]bool, len(g.nodes))
+	order := make([]*models.Node, 0, len(g.nodes))
+
+	for len(order) < len(g.flow.Nodes) {
+		// 実行可能なノードのうち定義順で最初のものを選ぶ
+		// Startノードはリクエストの検証を行うため、実行可能であれば最優先する
+		var next *models.Node
+		for i := range g.flow.Nodes {
+			node := &g.flow.Nodes[i]
+			if done[node.ID] || indegree[node.ID] != 0 {
+				continue
+			}
+			if node.Type == "start" {
+				next = node
+				break
+			}
+			if next == nil {
+				next = node
+			}
+		}
+		if next == nil {
+			return nil, ErrCycle
+		}
+
+		done[next.ID] = true
+		order = append(order, next)
+		for _, conn := range g.outgoing[next.ID] {
+			indegree[conn.To.NodeID]--
+		}
+	}
+
+	return order, nil
+}
@@ -0,0 +1,55 @@
+package flow
+
+import (
+	"errors"
+	"time"
+
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+var (
+	// ErrStepLimit はノードの実行回数が上限を超えた場合のエラー
+	ErrStepLimit = errors.New("flow exceeded the maximum number of node executions")
+	// ErrTimeout はフロー全体の実行時間が上限を超えた場合のエラー
+	ErrTimeout = errors.New("flow execution timed out")
+)
+
+// Limits はフロー実行の制限（0は無制限）
+type Limits struct {
+	MaxSteps         int
+	Timeout          time.Duration
+	MaxRequestBytes  int64
+	MaxResponseBytes int64
+	// MaxParallelism は1リクエストで同時に実行するノードの数（1以下の場合は順に実行する）
+	MaxParallelism int
+}
+
+// EndpointLimits はエンドポイントの制限設定をLimitsに変換する
+func EndpointLimits(l models.EndpointLimits) Limits {
+	return Limits{
+		MaxSteps:         l.MaxSteps,
+		Timeout:          time.Duration(l.TimeoutMs) * time.Millisecond,
+		MaxRequestBytes:  int64(l.MaxRequestBytes),
+		MaxResponseBytes: int64(l.MaxResponseBytes),
+		MaxParallelism:   l.MaxParallelism,
+	}
+}
+
+// Restrict はoで指定された項目を適用した制限を返す
+// oの0の項目はlの値を使用し、lの値を超える項目はlの値に切り詰める
+func (l Limits) Restrict(o Limits) Limits {
+	return Limits{
+		MaxSteps:         restrict(l.MaxSteps, o.MaxSteps),
+		Timeout:          restrict(l.Timeout, o.Timeout),
+		MaxRequestBytes:  restrict(l.MaxRequestBytes, o.MaxRequestBytes),
+		MaxResponseBytes: restrict(l.MaxResponseBytes, o.MaxResponseBytes),
+		MaxParallelism:   restrict(l.MaxParallelism, o.MaxParallelism),
+	}
+}
+
+func restrict[T int | int64 | time.Duration](base, override T) T {
+	if override <= 0 || (base > 0 && override > base) {
+		return base
+	}
+	return override
+}
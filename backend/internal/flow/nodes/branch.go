@@ -0,0 +1,155 @@
+package nodes
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/necorox/FlowCore/backend/internal/flow"
+	"github.com/necorox/FlowCore/backend/internal/flow/script"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// defaultBranch はSwitchノードでどのケースにも一致しなかった場合の出力ピンのラベル
+const defaultBranch = "default"
+
+// If は条件を評価し、ラベルが "true" または "false" の出力ピンのうち一致した方にのみ入力データを出力する
+// conditionを省略した場合は最初の入力値の真偽で分岐する
+func If(ec *flow.ExecutionContext, in *flow.NodeInput) (map[string]interface{}, error) {
+	vars := scriptVars(ec, in)
+
+	result := vars["data"]
+	if source := in.ConfigString("condition"); source != "" {
+		var err error
+		result, err = script.Run(ec.Context(), source, vars, script.Options{
+			Limits: scriptLimits(ec, in),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return branchOutputs(in, strconv.FormatBool(truthy(result)), vars["data"]), nil
+}
+
+// Switch は値を評価し、ラベルが一致するケースの出力ピンにのみ入力データを出力する
+// どのケースにも一致しない場合はラベルが "default" の出力ピンに出力する（ない場合はどの分岐も実行しない）
+// valueを省略した場合は最初の入力値で分岐する
+func Switch(ec *flow.ExecutionContext, in *flow.NodeInput) (map[string]interface{}, error) {
+	vars := scriptVars(ec, in)
+
+	value := vars["data"]
+	if source := in.ConfigString("value"); source != "" {
+		var err error
+		value, err = script.Run(ec.Context(), source, vars, script.Options{
+			Limits: scriptLimits(ec, in),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cases, _ := in.Config["cases"].([]interface{})
+	branch := defaultBranch
+	key := caseKey(value)
+	for _, c := range cases {
+		if caseKey(c) == key {
+			branch = key
+			break
+		}
+	}
+
+	return branchOutputs(in, branch, vars["data"]), nil
+}
+
+// branchOutputs はラベルが一致する出力ピンにのみ値を設定する
+// 出力に含まれないピンはエンジンによって非アクティブな分岐として扱われる
+func branchOutputs(in *flow.NodeInput, label string, value interface{}) map[string]interface{} {
+	outputs := make(map[string]interface{})
+	for _, pin := range in.OutputPins() {
+		if pin.Label == label {
+			outputs[pin.ID] = value
+		}
+	}
+	return outputs
+}
+
+// caseKey はケースの値を比較用の文字列に変換する（クエリパラメータの "1" と数値の1は一致する）
+func caseKey(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// validateIf はIfノードの出力ピンのラベルを検証する
+func validateIf(node *models.Node) []string {
+	var errs []string
+	outputs := 0
+	for _, pin := range node.Pins {
+		if pin.Type != "output" {
+			continue
+		}
+		outputs++
+		if pin.Label != "true" && pin.Label != "false" {
+			errs = append(errs, fmt.Sprintf("output pin %q must be labeled \"true\" or \"false\"", pin.ID))
+		}
+	}
+	if outputs == 0 {
+		errs = append(errs, "at least one output pin labeled \"true\" or \"false\" is required")
+	}
+	return errs
+}
+
+// validateSwitch はSwitchノードのケースと出力ピンの対応を検証する
+func validateSwitch(node *models.Node) []string {
+	var errs []string
+
+	cases, ok := node.Config["cases"].([]interface{})
+	if !ok || len(cases) == 0 {
+		return []string{"cases must be a non-empty array"}
+	}
+
+	keys := make(map[string]bool, len(cases))
+	for i, c := range cases {
+		switch c.(type) {
+		case string, float64, bool, nil:
+		default:
+			errs = append(errs, fmt.Sprintf("cases[%d] must be a string, number, boolean or null", i))
+			continue
+		}
+		key := caseKey(c)
+		if key == defaultBranch {
+			errs = append(errs, fmt.Sprintf("cases[%d] must not be %q", i, defaultBranch))
+		}
+		if keys[key] {
+			errs = append(errs, fmt.Sprintf("duplicate case %q", key))
+		}
+		keys[key] = true
+	}
+
+	labels := map[string]bool{}
+	for _, pin := range node.Pins {
+		if pin.Type != "output" {
+			continue
+		}
+		labels[pin.Label] = true
+		if !keys[pin.Label] && pin.Label != defaultBranch {
+			errs = append(errs, fmt.Sprintf("output pin %q does not match any case", pin.ID))
+		}
+	}
+	for _, c := range cases {
+		if key := caseKey(c); !labels[key] && key != defaultBranch {
+			errs = append(errs, fmt.Sprintf("case %q has no output pin", key))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,35 @@
+package nodes
+
+import (
+	"fmt"
+
+	"github.com/necorox/FlowCore/backend/internal/flow"
+)
+
+// errorFields はCatchノードが個別の出力ピンに展開するエラーの項目
+var errorFields = map[string]bool{
+	"class": true, "code": true, "message": true, "node_id": true, "details": true,
+}
+
+// Catch はエラー出力ピンから受け取ったエラーを出力ピンに展開する
+// ラベルが class・code・message・node_id・details の出力ピンにはその項目、それ以外の出力ピンにはエラー全体を出力する
+func Catch(ec *flow.ExecutionContext, in *flow.NodeInput) (map[string]interface{}, error) {
+	value, ok := in.First()
+	if !ok {
+		return nil, fmt.Errorf("catch node requires an error input")
+	}
+	errValue, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("catch node input must be an error, got %T", value)
+	}
+
+	outputs := make(map[string]interface{})
+	for _, pin := range in.OutputPins() {
+		if errorFields[pin.Label] {
+			outputs[pin.ID] = errValue[pin.Label]
+		} else {
+			outputs[pin.ID] = errValue
+		}
+	}
+	return outputs, nil
+}
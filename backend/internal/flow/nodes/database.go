@@ -0,0 +1,332 @@
+package nodes
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/flow"
+)
+
+// Database はユーザーテーブルに対してSELECT/INSERT/UPDATE/DELETEを実行する
+// テーブル名・カラム名はMetaDBの定義と照合し、値はすべてプレースホルダで渡す
+// 設定の式（{{ params.id }} 等）はエンジンが評価済みで、値が未定義の式は元の設定と比較して判別する
+func Database(ec *flow.ExecutionContext, in *flow.NodeInput) (map[string]interface{}, error) {
+	ctx := ec.Context()
+
+	tableName := in.ConfigString("table")
+	if tableName == "" {
+		return nil, fmt.Errorf("table is required")
+	}
+	table, err := ec.DB().TableByName(ctx, tableName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("unknown table %q", tableName)
+		}
+		return nil, fmt.Errorf("failed to load table %q: %w", tableName, err)
+	}
+
+	operation := strings.ToLower(in.ConfigString("operation"))
+	if operation == "" {
+		operation = database.OpSelect
+	}
+
+	columns, err := stringList(in.Config["columns"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid columns: %w", err)
+	}
+
+	q := &database.Query{
+		Operation: operation,
+		Table:     table,
+	}
+
+	if q.Where, err = whereConditions(ec, in); err != nil {
+		return nil, err
+	}
+
+	switch operation {
+	case database.OpSelect:
+		q.Columns = columns
+		if q.OrderBy, err = orderBy(in.Config["orderBy"]); err != nil {
+			return nil, err
+		}
+		if q.Limit, err = intConfig(in, "limit"); err != nil {
+			return nil, err
+		}
+		if q.Offset, err = intConfig(in, "offset"); err != nil {
+			return nil, err
+		}
+	case database.OpInsert, database.OpUpdate:
+		if q.Values, err = writeValues(in, columns); err != nil {
+			return nil, err
+		}
+	}
+
+	unlock := ec.LockQuerier()
+	rows, err := q.Run(ctx, ec.Querier())
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return in.OutputAll(rows), nil
+}
+
+// whereConditions は設定からWHERE条件を組み立てる
+// {"column": value} 形式、または [{"column", "op", "value" | "pin" | "param"}] 形式をサポートする
+func whereConditions(ec *flow.ExecutionContext, in *flow.NodeInput) ([]database.Condition, error) {
+	switch where := in.Config["where"].(type) {
+	case nil:
+		return nil, nil
+
+	case map[string]interface{}:
+		raw, _ := in.Node.Config["where"].(map[string]interface{})
+		columns := sortedKeys(where)
+		if raw != nil {
+			columns = sortedKeys(raw)
+		}
+		conditions := make([]database.Condition, 0, len(columns))
+		for _, column := range columns {
+			value, ok := configValue(where, raw, column)
+			if !ok {
+				return nil, fmt.Errorf("missing value for where condition on %q", column)
+			}
+			conditions = append(conditions, database.Condition{Column: column, Operator: "=", Value: value})
+		}
+		return conditions, nil
+
+	case []interface{}:
+		raw, _ := in.Node.Config["where"].([]interface{})
+		conditions := make([]database.Condition, 0, len(where))
+		for i, item := range where {
+			cond, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("where[%d] must be an object", i)
+			}
+			column, _ := cond["column"].(string)
+			operator, _ := cond["op"].(string)
+			optional, _ := cond["optional"].(bool)
+
+			var value interface{}
+			var found bool
+			switch {
+			case cond["pin"] != nil:
+				value, found = in.Named(fmt.Sprint(cond["pin"]))
+			case cond["param"] != nil:
+				value = lookupParam(ec.Request, fmt.Sprint(cond["param"]))
+				found = value != nil
+			default:
+				value, found = configValue(cond, rawItem(raw, i), "value")
+			}
+
+			if !found && !isNullOperator(operator) {
+				if optional {
+					continue
+				}
+				return nil, fmt.Errorf("missing value for where condition on %q", column)
+			}
+			conditions = append(conditions, database.Condition{Column: column, Operator: operator, Value: value})
+		}
+		return conditions, nil
+
+	default:
+		return nil, fmt.Errorf("where must be an object or an array")
+	}
+}
+
+// writeValues はINSERT/UPDATEする値を設定または入力ピンから取得する
+func writeValues(in *flow.NodeInput, columns []string) ([]map[string]interface{}, error) {
+	var source, raw interface{}
+	if values, ok := in.Config["values"]; ok {
+		source = values
+		raw = in.Node.Config["values"]
+	} else if _, configured := in.Node.Config["values"]; configured {
+		return nil, fmt.Errorf("missing value for values")
+	} else {
+		for _, pin := range in.InputPins() {
+			if pin.DataType == "trigger" {
+				continue
+			}
+			if value, ok := in.Inputs[pin.ID]; ok {
+				source = value
+				break
+			}
+		}
+	}
+
+	var rows []map[string]interface{}
+	switch v := source.(type) {
+	case map[string]interface{}:
+		rows = []map[string]interface{}{v}
+	case []interface{}:
+		for i, item := range v {
+			row, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("values[%d] must be an object", i)
+			}
+			rows = append(rows, row)
+		}
+	case nil:
+		return nil, fmt.Errorf("no values to write")
+	default:
+		return nil, fmt.Errorf("values must be an object or an array of objects")
+	}
+
+	// columnsが指定されていれば書き込むカラムを制限する
+	allowed := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		allowed[col] = true
+	}
+
+	result := make([]map[string]interface{}, 0, len(rows))
+	for i, row := range rows {
+		// 設定の値の場合、式の値が未定義のカラムは元の設定にのみ残っている
+		rawRow, _ := raw.(map[string]interface{})
+		if list, ok := raw.([]interface{}); ok {
+			rawRow = rawItem(list, i)
+		}
+		keys := sortedKeys(row)
+		if rawRow != nil {
+			keys = sortedKeys(rawRow)
+		}
+
+		resolved := make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			if len(allowed) > 0 && !allowed[key] {
+				continue
+			}
+			v, ok := configValue(row, rawRow, key)
+			if !ok {
+				return nil, fmt.Errorf("missing value for column %q", key)
+			}
+			resolved[key] = v
+		}
+		result = append(result, resolved)
+	}
+	return result, nil
+}
+
+// configValue は評価済みの設定のキーの値を返す
+// 元の設定にあるキーが評価後にない場合（式の値が未定義）はfalseを返し、元の設定にもないキーはnullとして扱う
+func configValue(resolved, raw map[string]interface{}, key string) (interface{}, bool) {
+	if value, ok := resolved[key]; ok {
+		return value, true
+	}
+	_, configured := raw[key]
+	return nil, !configured
+}
+
+// rawItem は元の設定の配列のi番目のオブジェクトを返す
+func rawItem(list []interface{}, i int) map[string]interface{} {
+	if i >= len(list) {
+		return nil
+	}
+	item, _ := list[i].(map[string]interface{})
+	return item
+}
+
+// orderBy は "col DESC, col2" 形式の文字列、または配列からORDER BYを組み立てる
+func orderBy(value interface{}) ([]database.Order, error) {
+	var items []interface{}
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				items = append(items, part)
+			}
+		}
+	case []interface{}:
+		items = v
+	default:
+		return nil, fmt.Errorf("orderBy must be a string or an array")
+	}
+
+	orders := make([]database.Order, 0, len(items))
+	for _, item := range items {
+		var column, direction string
+		switch v := item.(type) {
+		case string:
+			fields := strings.Fields(v)
+			if len(fields) == 0 || len(fields) > 2 {
+				return nil, fmt.Errorf("invalid orderBy %q", v)
+			}
+			column = fields[0]
+			if len(fields) == 2 {
+				direction = fields[1]
+			}
+		case map[string]interface{}:
+			column, _ = v["column"].(string)
+			direction, _ = v["direction"].(string)
+		default:
+			return nil, fmt.Errorf("invalid orderBy item %v", item)
+		}
+
+		switch strings.ToUpper(direction) {
+		case "", "ASC":
+			orders = append(orders, database.Order{Column: column})
+		case "DESC":
+			orders = append(orders, database.Order{Column: column, Desc: true})
+		default:
+			return nil, fmt.Errorf("invalid order direction %q", direction)
+		}
+	}
+	return orders, nil
+}
+
+// intConfig は設定値を0以上の整数として取得する
+// 式の値が未定義・nullの場合は指定なし（0）として扱う
+func intConfig(in *flow.NodeInput, key string) (int, error) {
+	value, ok := in.Config[key]
+	if !ok || value == nil {
+		return 0, nil
+	}
+
+	var n int
+	switch v := value.(type) {
+	case float64:
+		n = int(v)
+	case int:
+		n = v
+	case string:
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("%s must be an integer", key)
+		}
+		n = parsed
+	default:
+		return 0, fmt.Errorf("%s must be an integer", key)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%s must not be negative", key)
+	}
+	return n, nil
+}
+
+func stringList(value interface{}) ([]string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected an array of strings")
+	}
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected an array of strings")
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+func isNullOperator(op string) bool {
+	op = strings.ToLower(strings.TrimSpace(op))
+	return op == "is null" || op == "is not null"
+}
@@ -0,0 +1,102 @@
+package nodes
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// fakeTable はfakeDBのユーザーテーブル（カラムはテーブル定義の順）
+type fakeTable struct {
+	columns []models.Column
+	rows    [][]driver.Value
+}
+
+// fakeDB はDatabaseノードのSELECTが発行するクエリだけを扱うインメモリのDB
+// テーブル定義（meta_tables/meta_columns）を返し、ユーザーテーブルのSELECTにはすべての行を返す（WHERE等は無視する）
+type fakeDB struct {
+	tables map[string]*fakeTable
+}
+
+// newFakeDB はfakeDBに接続したDBを作成する
+func newFakeDB(t *testing.T, tables map[string]*fakeTable) *database.DB {
+	t.Helper()
+	conn := sql.OpenDB(fakeConnector{db: &fakeDB{tables: tables}})
+	t.Cleanup(func() { conn.Close() })
+	return database.NewFromConn(conn)
+}
+
+func (db *fakeDB) query(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+	switch {
+	case strings.Contains(query, "FROM meta_tables"):
+		name, _ := args[0].Value.(string)
+		if _, ok := db.tables[name]; !ok {
+			return []string{"id", "name", "created_at", "updated_at"}, nil, nil
+		}
+		return []string{"id", "name", "created_at", "updated_at"}, [][]driver.Value{{name, name, time.Now(), time.Now()}}, nil
+	case strings.Contains(query, "FROM meta_columns"):
+		name, _ := args[0].Value.(string)
+		var rows [][]driver.Value
+		for _, col := range db.tables[name].columns {
+			rows = append(rows, []driver.Value{col.Name, name, col.Name, col.Type, col.Required, time.Now(), time.Now()})
+		}
+		return []string{"id", "table_id", "name", "type", "required", "created_at", "updated_at"}, rows, nil
+	case strings.HasPrefix(query, "SELECT "):
+		for name, table := range db.tables {
+			if !strings.Contains(query, `FROM "`+name+`"`) {
+				continue
+			}
+			columns := make([]string, len(table.columns))
+			for i, col := range table.columns {
+				columns[i] = col.Name
+			}
+			return columns, table.rows, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("unexpected query: %s", query)
+}
+
+type fakeConnector struct{ db *fakeDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn(c), nil }
+func (c fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	columns, rows, err := c.db.query(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: columns, rows: rows}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,189 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/necorox/FlowCore/backend/internal/flow"
+)
+
+// maxForEachConcurrency はForEachノードで同時に実行する要素数の上限
+const maxForEachConcurrency = 16
+
+// ForEach は配列の要素ごとに本体（body）のサブグラフを実行し、結果を配列で出力する
+// 本体のItemノードは要素（item）・インデックス（index）と、ForEachノードのその他の入力をラベル名で出力し、
+// Collectノードが受け取った値がその要素の結果になる
+// concurrencyを指定した場合は最大その数の要素を並列に実行する（結果の順序は要素の順序と同じ）
+// 並列に実行する要素もリクエスト全体のワーカーの枠（FLOW_MAX_PARALLELISM）の範囲内で実行する
+func ForEach(ec *flow.ExecutionContext, in *flow.NodeInput) (map[string]interface{}, error) {
+	itemsPin, value, ok := forEachItems(in)
+	if !ok || value == nil {
+		return in.OutputAll([]interface{}{}), nil
+	}
+	items, ok := toList(value)
+	if !ok {
+		return nil, fmt.Errorf("input must be an array, got %T", value)
+	}
+
+	// 要素以外の入力は、すべての要素の実行で共通の値としてItemノードに渡す
+	shared := make(map[string]interface{})
+	for _, pin := range in.InputPins() {
+		if value, ok := in.Inputs[pin.ID]; ok && pin.ID != itemsPin {
+			shared[pin.Label] = value
+		}
+	}
+	vars := func(i int) map[string]interface{} {
+		v := make(map[string]interface{}, len(shared)+2)
+		for key, value := range shared {
+			v[key] = value
+		}
+		v["item"] = items[i]
+		v["index"] = i
+		return v
+	}
+
+	results := make([]interface{}, len(items))
+	concurrency := forEachConcurrency(in.Config)
+	if ec.InTransaction() || !ec.ParallelEnabled() {
+		// 1つのトランザクションの接続は並列に使用できないため、要素を順に実行する
+		concurrency = 1
+	}
+
+	if concurrency <= 1 {
+		for i := range items {
+			result, err := ec.RunBody(ec.Context(), in.Node.Body, i, vars(i))
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			results[i] = result
+		}
+		return in.OutputAll(results), nil
+	}
+
+	// 1つの要素が失敗した場合は、実行中の他の要素をキャンセルして最初のエラーを返す
+	ctx, cancel := context.WithCancel(ec.Context())
+	defer cancel()
+
+	type itemResult struct {
+		index  int
+		result interface{}
+		err    error
+		token  bool
+	}
+	done := make(chan itemResult)
+	var firstErr error
+	// 1つの要素はこのノードを実行しているワーカーの枠で実行する（ノード自体は要素の完了を待つだけのため）
+	// 追加の要素は、リクエスト全体で共有するワーカーの枠を取得できた場合にのみ開始する
+	ownSlot := true
+	running, next := 0, 0
+	for {
+		for next < len(items) && running < concurrency && ctx.Err() == nil {
+			token := false
+			if ownSlot {
+				ownSlot = false
+			} else if ec.TryAcquireWorker() {
+				token = true
+			} else {
+				break
+			}
+
+			i := next
+			next++
+			running++
+			go func() {
+				result, err := ec.RunBody(ctx, in.Node.Body, i, vars(i))
+				if token {
+					ec.ReleaseWorker()
+				}
+				done <- itemResult{index: i, result: result, err: err, token: token}
+			}()
+		}
+		if running == 0 {
+			break
+		}
+
+		res := <-done
+		running--
+		if !res.token {
+			ownSlot = true
+		}
+		if res.err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("item %d: %w", res.index, res.err)
+				cancel()
+			}
+			continue
+		}
+		results[res.index] = res.result
+	}
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ec.Context().Err(); err != nil {
+		return nil, err
+	}
+	return in.OutputAll(results), nil
+}
+
+// forEachItems は要素の配列を受け取る入力ピン（ラベルが items のピン、なければ最初の入力）とその値を返す
+func forEachItems(in *flow.NodeInput) (string, interface{}, bool) {
+	for _, pin := range in.InputPins() {
+		if pin.Label == "items" {
+			value, ok := in.Inputs[pin.ID]
+			return pin.ID, value, ok
+		}
+	}
+	for _, pin := range in.InputPins() {
+		if pin.DataType == "trigger" {
+			continue
+		}
+		value, ok := in.Inputs[pin.ID]
+		return pin.ID, value, ok
+	}
+	return "", nil, false
+}
+
+func forEachConcurrency(config map[string]interface{}) int {
+	n, _ := config["concurrency"].(float64)
+	switch {
+	case n < 1:
+		return 1
+	case n > maxForEachConcurrency:
+		return maxForEachConcurrency
+	default:
+		return int(n)
+	}
+}
+
+// Item はForEachノードの本体で、現在の要素・インデックスなどを出力ピンのラベル名で出力する
+func Item(ec *flow.ExecutionContext, in *flow.NodeInput) (map[string]interface{}, error) {
+	outputs := make(map[string]interface{})
+	for _, pin := range in.OutputPins() {
+		if value, ok := ec.Scope(pin.Label); ok {
+			outputs[pin.ID] = value
+		}
+	}
+	return outputs, nil
+}
+
+// Collect はForEachノードの本体で、入力の値をその要素の結果とする
+// 入力ピンが1つならその値、複数ならラベルをキーにしたオブジェクトを結果とする
+func Collect(ec *flow.ExecutionContext, in *flow.NodeInput) (map[string]interface{}, error) {
+	var result interface{}
+	pins := in.InputPins()
+	if len(pins) == 1 {
+		result = in.Inputs[pins[0].ID]
+	} else {
+		fields := make(map[string]interface{}, len(pins))
+		for _, pin := range pins {
+			fields[pin.Label] = in.Inputs[pin.ID]
+		}
+		result = fields
+	}
+
+	if err := ec.Collect(result); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
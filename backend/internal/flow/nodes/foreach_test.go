@@ -0,0 +1,203 @@
+package nodes
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/necorox/FlowCore/backend/internal/flow"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// concurrencyProbe は同時に実行されている数の最大値を記録するノード
+type concurrencyProbe struct {
+	mu      sync.Mutex
+	current int
+	max     int
+}
+
+func (p *concurrencyProbe) execute(ec *flow.ExecutionContext, in *flow.NodeInput) (map[string]interface{}, error) {
+	p.mu.Lock()
+	p.current++
+	if p.current > p.max {
+		p.max = p.current
+	}
+	p.mu.Unlock()
+
+	time.Sleep(2 * time.Millisecond)
+
+	p.mu.Lock()
+	p.current--
+	p.mu.Unlock()
+	return in.OutputAll(true), nil
+}
+
+// TestForEachRespectsMaxParallelism は入れ子のForEachの要素もMaxParallelismの範囲内で実行されることを確認する
+func TestForEachRespectsMaxParallelism(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxParallelism int
+	}{
+		{name: "sequential", maxParallelism: 1},
+		{name: "two workers", maxParallelism: 2},
+		{name: "four workers", maxParallelism: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probe := &concurrencyProbe{}
+			registry := flow.NewRegistry()
+			if err := Register(registry); err != nil {
+				t.Fatal(err)
+			}
+			if err := registry.Register(flow.NodeType{
+				NodeTypeInfo: models.NodeTypeInfo{Type: "probe"},
+				Executor:     flow.ExecutorFunc(probe.execute),
+			}); err != nil {
+				t.Fatal(err)
+			}
+			engine := flow.NewEngine(nil, registry, flow.Config{
+				Limits: flow.Limits{MaxParallelism: tt.maxParallelism, Timeout: 10 * time.Second},
+			})
+
+			result, err := engine.Run(context.Background(), nestedForEachFlow(), &flow.Request{Method: "GET"}, flow.Options{})
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if result.Response == nil || result.Response.StatusCode != 200 {
+				t.Fatalf("Run() response = %+v", result.Response)
+			}
+			if probe.max > tt.maxParallelism {
+				t.Errorf("max concurrent nodes = %d, want <= %d", probe.max, tt.maxParallelism)
+			}
+		})
+	}
+}
+
+// TestForEachDatabaseRows はDatabaseノードのSELECTの結果（[]map[string]interface{}）を要素ごとに処理できることを確認する
+func TestForEachDatabaseRows(t *testing.T) {
+	tests := []struct {
+		name string
+		rows [][]driver.Value
+		want []interface{}
+	}{
+		{name: "rows", rows: [][]driver.Value{{int64(1), "apple"}, {int64(2), "banana"}}, want: []interface{}{"1:apple", "2:banana"}},
+		{name: "no rows", want: []interface{}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB(t, map[string]*fakeTable{
+				"u_items": {columns: []models.Column{{Name: "id", Type: "number"}, {Name: "name", Type: "string"}}, rows: tt.rows},
+			})
+			registry := flow.NewRegistry()
+			if err := Register(registry); err != nil {
+				t.Fatal(err)
+			}
+			engine := flow.NewEngine(db, registry, flow.Config{Limits: flow.Limits{Timeout: 10 * time.Second}})
+
+			body := &models.Flow{
+				Nodes: []models.Node{
+					testNode("item", "item", nil, testPin("item-item", "output", "item")),
+					testNode("label", "process", map[string]interface{}{"script": "item.id + ':' + item.name"},
+						testPin("label-in", "input", "item"), testPin("label-out", "output", "result")),
+					testNode("collect", "collect", nil, testPin("collect-in", "input", "result")),
+				},
+				Connections: []models.Connection{
+					testConnection("item", "item-item", "label", "label-in"),
+					testConnection("label", "label-out", "collect", "collect-in"),
+				},
+			}
+			forEach := testNode("each", "foreach", nil, testPin("each-items", "input", "items"), testPin("each-out", "output", "results"))
+			forEach.Body = body
+			f := &models.Flow{
+				Nodes: []models.Node{
+					testNode("db", "database", map[string]interface{}{"table": "u_items"}, testPin("db-out", "output", "rows")),
+					forEach,
+					testNode("response", "response", nil, testPin("response-in", "input", "results")),
+				},
+				Connections: []models.Connection{
+					testConnection("db", "db-out", "each", "each-items"),
+					testConnection("each", "each-out", "response", "response-in"),
+				},
+			}
+
+			result, err := engine.Run(context.Background(), f, &flow.Request{Method: "GET"}, flow.Options{})
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if result.Response == nil || !reflect.DeepEqual(result.Response.Body, tt.want) {
+				t.Errorf("Run() response = %+v, want body %v", result.Response, tt.want)
+			}
+		})
+	}
+}
+
+// nestedForEachFlow は8×8の要素をconcurrency 16の入れ子のForEachで処理するフローを返す
+func nestedForEachFlow() *models.Flow {
+	inner := &models.Flow{
+		Nodes: []models.Node{
+			testNode("inner-item", "item", nil, testPin("inner-item-item", "output", "item")),
+			testNode("probe", "probe", nil, testPin("probe-in", "input", "value"), testPin("probe-out", "output", "result")),
+			testNode("inner-collect", "collect", nil, testPin("inner-collect-in", "input", "result")),
+		},
+		Connections: []models.Connection{
+			testConnection("inner-item", "inner-item-item", "probe", "probe-in"),
+			testConnection("probe", "probe-out", "inner-collect", "inner-collect-in"),
+		},
+	}
+
+	innerForEach := testNode("inner", "foreach", map[string]interface{}{"concurrency": float64(16)},
+		testPin("inner-items", "input", "items"), testPin("inner-out", "output", "results"))
+	innerForEach.Body = inner
+	outer := &models.Flow{
+		Nodes: []models.Node{
+			testNode("outer-item", "item", nil, testPin("outer-item-item", "output", "item")),
+			innerForEach,
+			testNode("outer-collect", "collect", nil, testPin("outer-collect-in", "input", "results")),
+		},
+		Connections: []models.Connection{
+			testConnection("outer-item", "outer-item-item", "inner", "inner-items"),
+			testConnection("inner", "inner-out", "outer-collect", "outer-collect-in"),
+		},
+	}
+
+	outerForEach := testNode("outer", "foreach", map[string]interface{}{"concurrency": float64(16)},
+		testPin("outer-items", "input", "items"), testPin("outer-out", "output", "results"))
+	outerForEach.Body = outer
+	return &models.Flow{
+		Nodes: []models.Node{
+			testNode("items", "process", map[string]interface{}{"script": "Array.from({ length: 8 }, () => [1, 2, 3, 4, 5, 6, 7, 8])"},
+				testPin("items-out", "output", "items")),
+			outerForEach,
+			testNode("response", "response", nil, testPin("response-in", "input", "results")),
+		},
+		Connections: []models.Connection{
+			testConnection("items", "items-out", "outer", "outer-items"),
+			testConnection("outer", "outer-out", "response", "response-in"),
+		},
+	}
+}
+
+func testNode(id, nodeType string, config map[string]interface{}, pins ...models.Pin) models.Node {
+	for i := range pins {
+		pins[i].NodeID = id
+	}
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	return models.Node{ID: id, Type: nodeType, Label: id, Config: config, Pins: pins}
+}
+
+func testPin(id, pinType, label string) models.Pin {
+	return models.Pin{ID: id, Type: pinType, DataType: "any", Label: label}
+}
+
+func testConnection(fromNode, fromPin, toNode, toPin string) models.Connection {
+	return models.Connection{
+		ID:   fromPin + "->" + toPin,
+		From: models.PinRef{NodeID: fromNode, PinID: fromPin},
+		To:   models.PinRef{NodeID: toNode, PinID: toPin},
+	}
+}
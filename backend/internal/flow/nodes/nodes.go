@@ -0,0 +1,219 @@
+// Package nodes は組み込みノードの実装を提供する
+package nodes
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/necorox/FlowCore/backend/internal/flow"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// Register は組み込みノードタイプをレジストリに登録する
+func Register(r *flow.Registry) error {
+	builtins := []flow.NodeType{
+		{
+			NodeTypeInfo: models.NodeTypeInfo{
+				Type:        "start",
+				Label:       "開始",
+				Description: "リクエストパラメータを出力ピンに展開する",
+				ConfigSchema: []models.ConfigField{
+					{Key: "method", Type: "string", Enum: []string{"GET", "POST", "PUT", "DELETE", "PATCH"}, Description: "HTTPメソッド"},
+					{Key: "params", Type: "array", Raw: true, Description: "リクエストパラメータ（名前、または {name, in, type, required, default}）"},
+				},
+			},
+			Executor: flow.ExecutorFunc(Start),
+			Validate: validateStart,
+		},
+		{
+			NodeTypeInfo: models.NodeTypeInfo{
+				Type:        "database",
+				Label:       "データベース",
+				Description: "ユーザーテーブルに対してクエリを実行し、結果の行を配列で出力する",
+				ConfigSchema: []models.ConfigField{
+					{Key: "table", Type: "string", Required: true, Description: "対象テーブル"},
+					{Key: "operation", Type: "string", Default: "select", Enum: []string{"select", "insert", "update", "delete"}, Description: "操作"},
+					{Key: "columns", Type: "array", Description: "取得・書き込みするカラム"},
+					{Key: "where", Type: "any", Description: "WHERE条件（オブジェクトまたは条件の配列）"},
+					{Key: "values", Type: "object", Description: "INSERT/UPDATEする値（省略時は入力ピンの値）"},
+					{Key: "orderBy", Type: "any", Description: "並び順（例: \"rarity DESC\"）"},
+					{Key: "limit", Type: "any", Description: "取得件数の上限"},
+					{Key: "offset", Type: "any", Description: "取得開始位置"},
+				},
+			},
+			Executor: flow.ExecutorFunc(Database),
+		},
+		{
+			NodeTypeInfo: models.NodeTypeInfo{
+				Type:        "process",
+				Label:       "処理",
+				Description: "JavaScriptを実行し、結果を出力する",
+				ConfigSchema: []models.ConfigField{
+					{Key: "processType", Type: "string", Default: "script", Enum: []string{"script", "condition", "random"}, Description: "処理タイプ"},
+					{Key: "script", Type: "string", Raw: true, Description: "処理スクリプト (JavaScript)"},
+					{Key: "timeoutMs", Type: "number", Description: "スクリプトのタイムアウト（ミリ秒）"},
+				},
+			},
+			Executor: flow.ExecutorFunc(Process),
+		},
+		{
+			NodeTypeInfo: models.NodeTypeInfo{
+				Type:        "filter",
+				Label:       "フィルター",
+				Description: "配列データを map/filter/reduce/sort で変換する",
+				ConfigSchema: []models.ConfigField{
+					{Key: "transformType", Type: "string", Default: "map", Enum: []string{"map", "filter", "reduce", "sort"}, Description: "変換タイプ"},
+					{Key: "script", Type: "string", Required: true, Raw: true, Description: "変換スクリプト (JavaScript)"},
+					{Key: "initial", Type: "any", Description: "reduceの初期値"},
+					{Key: "timeoutMs", Type: "number", Description: "スクリプトのタイムアウト（ミリ秒）"},
+				},
+			},
+			Executor: flow.ExecutorFunc(Filter),
+		},
+		{
+			NodeTypeInfo: models.NodeTypeInfo{
+				Type:        "if",
+				Label:       "条件分岐",
+				Description: "条件を評価し、true または false の分岐にのみ入力データを渡す",
+				ConfigSchema: []models.ConfigField{
+					{Key: "condition", Type: "string", Raw: true, Description: "条件式 (JavaScript)。省略時は入力値の真偽"},
+					{Key: "timeoutMs", Type: "number", Description: "スクリプトのタイムアウト（ミリ秒）"},
+				},
+				Branching: true,
+			},
+			Executor: flow.ExecutorFunc(If),
+			Validate: validateIf,
+		},
+		{
+			NodeTypeInfo: models.NodeTypeInfo{
+				Type:        "switch",
+				Label:       "スイッチ",
+				Description: "値が一致するケースの分岐にのみ入力データを渡す",
+				ConfigSchema: []models.ConfigField{
+					{Key: "value", Type: "string", Raw: true, Description: "分岐する値の式 (JavaScript)。省略時は入力値"},
+					{Key: "cases", Type: "array", Required: true, Raw: true, Description: "ケースの値（出力ピンのラベルと対応。一致しない場合は default）"},
+					{Key: "timeoutMs", Type: "number", Description: "スクリプトのタイムアウト（ミリ秒）"},
+				},
+				Branching: true,
+			},
+			Executor: flow.ExecutorFunc(Switch),
+			Validate: validateSwitch,
+		},
+		{
+			NodeTypeInfo: models.NodeTypeInfo{
+				Type:        "foreach",
+				Label:       "繰り返し",
+				Description: "配列の要素ごとに本体のノードを実行し、結果を配列で出力する",
+				ConfigSchema: []models.ConfigField{
+					{Key: "concurrency", Type: "number", Default: 1, Description: "同時に実行する要素数（最大16）"},
+				},
+				HasBody: true,
+			},
+			Executor: flow.ExecutorFunc(ForEach),
+		},
+		{
+			NodeTypeInfo: models.NodeTypeInfo{
+				Type:        "transaction",
+				Label:       "トランザクション",
+				Description: "本体のノードを1つのトランザクション内で実行し、失敗した場合はロールバックする",
+				ConfigSchema: []models.ConfigField{
+					{Key: "isolation", Type: "string", Default: "read_committed", Enum: []string{"read_committed", "repeatable_read", "serializable"}, Description: "分離レベル"},
+					{Key: "maxRetries", Type: "number", Default: defaultTxRetries, Description: "シリアライゼーション失敗・デッドロック時の再実行回数"},
+				},
+				HasBody: true,
+			},
+			Executor: flow.ExecutorFunc(Transaction),
+			Validate: validateTransaction,
+		},
+		{
+			NodeTypeInfo: models.NodeTypeInfo{
+				Type:        "subflow",
+				Label:       "サブフロー",
+				Description: "指定したバージョンのサブフローを実行し、宣言された出力を出力する",
+				ConfigSchema: []models.ConfigField{
+					{Key: "subflow", Type: "string", Required: true, Raw: true, Description: "サブフロー名"},
+					{Key: "version", Type: "number", Required: true, Raw: true, Description: "呼び出すバージョン"},
+				},
+			},
+			Executor: flow.ExecutorFunc(Subflow),
+			Validate: validateSubflow,
+		},
+		{
+			NodeTypeInfo: models.NodeTypeInfo{
+				Type:         "item",
+				Label:        "要素",
+				Description:  "繰り返し・トランザクションの本体やサブフローで、現在の要素（item）・インデックス（index）・本体を持つノードの入力・サブフローの入力を出力する",
+				ConfigSchema: []models.ConfigField{},
+			},
+			Executor: flow.ExecutorFunc(Item),
+		},
+		{
+			NodeTypeInfo: models.NodeTypeInfo{
+				Type:         "collect",
+				Label:        "結果",
+				Description:  "繰り返し・トランザクションの本体やサブフローで、入力を本体の実行結果（サブフローの出力）とする",
+				ConfigSchema: []models.ConfigField{},
+			},
+			Executor: flow.ExecutorFunc(Collect),
+		},
+		{
+			NodeTypeInfo: models.NodeTypeInfo{
+				Type:         "catch",
+				Label:        "エラー処理",
+				Description:  "ノードのエラー出力ピンからエラーを受け取り、分類・コード・メッセージを出力する",
+				ConfigSchema: []models.ConfigField{},
+			},
+			Executor: flow.ExecutorFunc(Catch),
+		},
+		{
+			NodeTypeInfo: models.NodeTypeInfo{
+				Type:        "response",
+				Label:       "レスポンス",
+				Description: "入力データからHTTPレスポンスを生成する",
+				ConfigSchema: []models.ConfigField{
+					{Key: "statusCode", Type: "any", Default: 200, Description: "ステータスコード（100〜599）"},
+					{Key: "statusPin", Type: "string", Raw: true, Description: "ステータスコードとして使用する入力ピン（IDまたはラベル）"},
+					{Key: "headers", Type: "object", Description: "レスポンスヘッダー（名前 → 値）"},
+					{Key: "cookies", Type: "array", Description: "Set-Cookie（{name, value, path, domain, maxAge, secure, httpOnly, sameSite}）"},
+					{Key: "selectedFields", Type: "array", Raw: true, Description: "ボディに含めるピンまたはフィールド"},
+					{Key: "format", Type: "string", Default: "json", Enum: []string{"json", "text", "html", "csv"}, Description: "ボディの形式"},
+				},
+			},
+			Executor: flow.ExecutorFunc(Response),
+			Validate: validateResponse,
+		},
+	}
+
+	for _, t := range builtins {
+		if err := r.Register(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// toList は配列の値を[]interface{}に変換する
+// DatabaseノードのSELECTの結果（[]map[string]interface{}）などの型付きのスライスも受け付ける
+func toList(value interface{}) ([]interface{}, bool) {
+	if list, ok := value.([]interface{}); ok {
+		return list, true
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	list := make([]interface{}, rv.Len())
+	for i := range list {
+		list[i] = rv.Index(i).Interface()
+	}
+	return list, true
+}
@@ -0,0 +1,160 @@
+package nodes
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/necorox/FlowCore/backend/internal/flow"
+	"github.com/necorox/FlowCore/backend/internal/flow/script"
+)
+
+// reservedScriptVars はピンのラベルで上書きしないスクリプト変数
+var reservedScriptVars = map[string]bool{
+	"data":    true,
+	"input":   true,
+	"inputs":  true,
+	"request": true,
+}
+
+// Process はJavaScriptを実行し、結果を出力する
+// processType が condition の場合は結果を真偽値に、random の場合はスクリプト省略時に乱数を出力する
+func Process(ec *flow.ExecutionContext, in *flow.NodeInput) (map[string]interface{}, error) {
+	processType := in.ConfigString("processType")
+	if processType == "" {
+		processType = "script"
+	}
+	source := in.ConfigString("script")
+
+	switch processType {
+	case "script", "condition":
+		if source == "" {
+			return nil, fmt.Errorf("script is required")
+		}
+	case "random":
+		if source == "" {
+			value, err := randomInt(in.Config)
+			if err != nil {
+				return nil, err
+			}
+			return in.OutputAll(value), nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported processType %q", processType)
+	}
+
+	result, err := script.Run(ec.Context(), source, scriptVars(ec, in), script.Options{
+		Limits: scriptLimits(ec, in),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if processType == "condition" {
+		return in.OutputAll(truthy(result)), nil
+	}
+	return in.OutputAll(result), nil
+}
+
+// Filter はdata配列に対してmap/filter/reduce/sortの変換を行う
+// スクリプトが関数に評価される場合はtransformTypeに従って適用し、それ以外は評価結果をそのまま出力する
+func Filter(ec *flow.ExecutionContext, in *flow.NodeInput) (map[string]interface{}, error) {
+	transformType := in.ConfigString("transformType")
+	if transformType == "" {
+		transformType = "map"
+	}
+	switch transformType {
+	case "map", "filter", "reduce", "sort":
+	default:
+		return nil, fmt.Errorf("unsupported transformType %q", transformType)
+	}
+
+	source := in.ConfigString("script")
+	if source == "" {
+		return nil, fmt.Errorf("script is required")
+	}
+
+	result, err := script.Run(ec.Context(), source, scriptVars(ec, in), script.Options{
+		Limits:    scriptLimits(ec, in),
+		Transform: transformType,
+		Initial:   in.Config["initial"],
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return in.OutputAll(result), nil
+}
+
+// scriptVars はスクリプトに公開する変数を組み立てる
+// 入力はピンIDをキーにした inputs、ラベル名の変数、最初の入力値の data/input として公開する
+func scriptVars(ec *flow.ExecutionContext, in *flow.NodeInput) map[string]interface{} {
+	inputs := make(map[string]interface{}, len(in.Inputs))
+	vars := map[string]interface{}{
+		"inputs":  inputs,
+		"request": ec.Request.Map(),
+	}
+
+	var first interface{}
+	found := false
+	for _, pin := range in.InputPins() {
+		value, ok := in.Inputs[pin.ID]
+		if !ok {
+			continue
+		}
+		inputs[pin.ID] = value
+		if pin.Label != "" && !reservedScriptVars[pin.Label] {
+			vars[pin.Label] = value
+		}
+		if !found && pin.DataType != "trigger" {
+			first, found = value, true
+		}
+	}
+
+	vars["data"] = first
+	vars["input"] = first
+	return vars
+}
+
+// scriptLimits はノード設定の timeoutMs をエンジンの上限内で反映する
+func scriptLimits(ec *flow.ExecutionContext, in *flow.NodeInput) script.Limits {
+	limits := ec.ScriptLimits()
+	if ms, ok := in.Config["timeoutMs"].(float64); ok && ms > 0 {
+		timeout := time.Duration(ms) * time.Millisecond
+		if limits.Timeout == 0 || timeout < limits.Timeout {
+			limits.Timeout = timeout
+		}
+	}
+	return limits
+}
+
+// randomInt は min〜max（既定は1〜100）の整数を返す
+func randomInt(config map[string]interface{}) (int, error) {
+	lo, hi := 1, 100
+	if v, ok := config["min"].(float64); ok {
+		lo = int(v)
+	}
+	if v, ok := config["max"].(float64); ok {
+		hi = int(v)
+	}
+	if hi < lo {
+		return 0, fmt.Errorf("max must be greater than or equal to min")
+	}
+	return lo + rand.Intn(hi-lo+1), nil
+}
+
+// truthy はJavaScriptと同様の規則で値を真偽値に変換する
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	default:
+		return true
+	}
+}
@@ -0,0 +1,451 @@
+package nodes
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/necorox/FlowCore/backend/internal/flow"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// responseFormats はレスポンスの形式 → 既定のContent-Type
+var responseFormats = map[string]string{
+	flow.FormatJSON: "application/json",
+	flow.FormatText: "text/plain; charset=utf-8",
+	flow.FormatHTML: "text/html; charset=utf-8",
+	flow.FormatCSV:  "text/csv; charset=utf-8",
+}
+
+var sameSiteModes = map[string]http.SameSite{
+	"lax":    http.SameSiteLaxMode,
+	"strict": http.SameSiteStrictMode,
+	"none":   http.SameSiteNoneMode,
+}
+
+// Response は入力データからHTTPレスポンスを生成する
+// statusPinを指定した場合はそのピンの値をステータスコードとし、ボディからは除外する
+// selectedFieldsを指定した場合はボディを選択したピン・フィールドに絞り込む
+func Response(ec *flow.ExecutionContext, in *flow.NodeInput) (map[string]interface{}, error) {
+	status, err := statusCode(in.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	statusPin := in.ConfigString("statusPin")
+	if statusPin != "" {
+		value, ok := in.Named(statusPin)
+		if !ok {
+			return nil, fmt.Errorf("status pin %q has no value", statusPin)
+		}
+		if status, err = parseStatusCode(value); err != nil {
+			return nil, err
+		}
+	}
+
+	// 入力ピンが1つならその値、複数ならラベルをキーにしたオブジェクトを返す
+	var body interface{}
+	pins := in.InputPins()
+	fields := make(map[string]interface{}, len(pins))
+	for _, pin := range pins {
+		if pin.ID == statusPin || pin.Label == statusPin {
+			continue
+		}
+		body = in.Inputs[pin.ID]
+		fields[pin.Label] = in.Inputs[pin.ID]
+	}
+	if len(fields) != 1 {
+		body = fields
+	}
+
+	var columns []string
+	if selected, _ := stringList(in.Config["selectedFields"]); len(selected) > 0 {
+		body, columns = selectFields(ec, in, body, selected)
+	}
+
+	headers := http.Header{}
+	if raw, ok := in.Config["headers"].(map[string]interface{}); ok {
+		for name, value := range raw {
+			headers.Set(name, fmt.Sprint(value))
+		}
+	}
+	for _, cookie := range responseCookies(in.Config) {
+		headers.Add("Set-Cookie", cookie.String())
+	}
+
+	format := in.ConfigString("format")
+	if _, ok := responseFormats[format]; !ok {
+		format = flow.FormatJSON
+	}
+	if headers.Get("Content-Type") == "" {
+		headers.Set("Content-Type", responseFormats[format])
+	}
+	if body, err = formatBody(body, format, columns); err != nil {
+		return nil, err
+	}
+
+	if err := ec.SetResponse(&flow.Response{
+		StatusCode: status,
+		Headers:    headers,
+		Body:       body,
+		Format:     format,
+	}); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// validateResponse はResponseノードの設定を検証する（formatはConfigSchemaのEnumで検証する）
+func validateResponse(node *models.Node) []string {
+	config := node.Config
+	var errs []string
+
+	// 旧形式のstatusキーはマイグレーション前に保存されたバージョンをデプロイ・ロールバックできるよう、
+	// statusCodeの非推奨の別名として受け付ける（両方ある場合はstatusCodeを優先する）
+	for _, key := range []string{"statusCode", "status"} {
+		value, ok := config[key]
+		if !ok {
+			continue
+		}
+		if !flow.IsTemplate(value) {
+			if _, err := parseStatusCode(value); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		break
+	}
+	if value, ok := config["statusPin"]; ok {
+		if _, ok := value.(string); !ok {
+			errs = append(errs, "statusPin must be a string")
+		}
+	}
+
+	if value, ok := config["headers"]; ok {
+		headers, ok := value.(map[string]interface{})
+		if !ok {
+			errs = append(errs, "headers must be an object")
+		}
+		for name, v := range headers {
+			switch v.(type) {
+			case string, float64, bool:
+			default:
+				errs = append(errs, fmt.Sprintf("header %q must be a string", name))
+			}
+			if strings.EqualFold(name, "Set-Cookie") {
+				errs = append(errs, "use cookies instead of the Set-Cookie header")
+			}
+		}
+	}
+
+	if value, ok := config["cookies"]; ok {
+		list, ok := value.([]interface{})
+		if !ok {
+			errs = append(errs, "cookies must be an array")
+		}
+		for i, item := range list {
+			cookie, ok := item.(map[string]interface{})
+			if !ok {
+				errs = append(errs, fmt.Sprintf("cookies[%d] must be an object", i))
+				continue
+			}
+			if name, _ := cookie["name"].(string); name == "" {
+				errs = append(errs, fmt.Sprintf("cookies[%d].name is required", i))
+			}
+			if sameSite, ok := cookie["sameSite"]; ok && !flow.IsTemplate(sameSite) {
+				if s, _ := sameSite.(string); sameSiteModes[strings.ToLower(s)] == 0 {
+					errs = append(errs, fmt.Sprintf("cookies[%d].sameSite must be one of lax, strict, none", i))
+				}
+			}
+		}
+	}
+
+	if value, ok := config["selectedFields"]; ok {
+		list, ok := value.([]interface{})
+		if !ok {
+			errs = append(errs, "selectedFields must be an array of strings")
+		}
+		for _, item := range list {
+			if s, ok := item.(string); !ok || s == "" {
+				errs = append(errs, "selectedFields must be an array of strings")
+				break
+			}
+		}
+	}
+
+	return errs
+}
+
+// statusCode は設定からステータスコードを取得する
+// 旧形式のstatusキーはstatusCodeの非推奨の別名として受け付ける（statusCodeを優先する）
+func statusCode(config map[string]interface{}) (int, error) {
+	value, ok := config["statusCode"]
+	if !ok {
+		value, ok = config["status"]
+	}
+	if !ok {
+		return http.StatusOK, nil
+	}
+	return parseStatusCode(value)
+}
+
+func parseStatusCode(value interface{}) (int, error) {
+	var code int
+	switch v := value.(type) {
+	case float64:
+		code = int(v)
+	case int:
+		code = v
+	case int64:
+		code = int(v)
+	case string:
+		parsed, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return 0, fmt.Errorf("invalid status code %q", v)
+		}
+		code = parsed
+	default:
+		return 0, fmt.Errorf("invalid status code %v", value)
+	}
+
+	if code < 100 || code > 599 {
+		return 0, fmt.Errorf("invalid status code %d", code)
+	}
+	return code, nil
+}
+
+// selectFields はボディを選択したフィールドに絞り込み、フィールドの列名とともに返す
+// 各フィールドは、このノードの入力ピン（IDまたはラベル）、実行済みノードの出力ピンID、
+// ボディ内のフィールド（ドット区切りのパス）の順に解決する
+// ボディ内のフィールドは、ボディが配列の場合は各要素に適用する
+func selectFields(ec *flow.ExecutionContext, in *flow.NodeInput, body interface{}, fields []string) (interface{}, []string) {
+	selected := make(map[string]interface{}, len(fields))
+	columns := make([]string, 0, len(fields))
+	var paths []string
+	for _, field := range fields {
+		if value, ok := in.Named(field); ok {
+			label := inputLabel(in, field)
+			selected[label] = value
+			columns = append(columns, label)
+			continue
+		}
+		if pin, value, ok := ec.OutputPin(field); ok {
+			selected[pin.Label] = value
+			columns = append(columns, pin.Label)
+			continue
+		}
+		paths = append(paths, field)
+		columns = append(columns, field)
+	}
+
+	if len(paths) == 0 {
+		return selected, columns
+	}
+	projected := project(body, paths)
+	if len(selected) == 0 {
+		return projected, columns
+	}
+	if obj, ok := projected.(map[string]interface{}); ok {
+		for key, value := range obj {
+			selected[key] = value
+		}
+	}
+	return selected, columns
+}
+
+func inputLabel(in *flow.NodeInput, name string) string {
+	for _, pin := range in.InputPins() {
+		if pin.ID == name {
+			return pin.Label
+		}
+	}
+	return name
+}
+
+// project はオブジェクト（または配列の各要素）から指定したパスのフィールドだけを取り出す
+// DatabaseノードのSELECTの結果などの型付きのスライスも配列として扱う
+func project(value interface{}, paths []string) interface{} {
+	if v, ok := value.(map[string]interface{}); ok {
+		out := make(map[string]interface{}, len(paths))
+		for _, path := range paths {
+			if field, ok := lookupPath(v, path); ok {
+				setPath(out, path, field)
+			}
+		}
+		return out
+	}
+	if list, ok := toList(value); ok {
+		items := make([]interface{}, len(list))
+		for i, item := range list {
+			items[i] = project(item, paths)
+		}
+		return items
+	}
+	return value
+}
+
+func lookupPath(obj map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = obj
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func setPath(obj map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := obj[key].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			obj[key] = child
+		}
+		obj = child
+	}
+	obj[keys[len(keys)-1]] = value
+}
+
+// responseCookies はcookies設定からSet-Cookieに設定するCookieを生成する
+func responseCookies(config map[string]interface{}) []*http.Cookie {
+	list, _ := config["cookies"].([]interface{})
+	cookies := make([]*http.Cookie, 0, len(list))
+	for _, item := range list {
+		c, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		cookie := &http.Cookie{
+			Path:     "/",
+			HttpOnly: true,
+		}
+		cookie.Name, _ = c["name"].(string)
+		if value, ok := c["value"]; ok && value != nil {
+			cookie.Value = fmt.Sprint(value)
+		}
+		if path, ok := c["path"].(string); ok {
+			cookie.Path = path
+		}
+		cookie.Domain, _ = c["domain"].(string)
+		if maxAge, ok := c["maxAge"].(float64); ok {
+			cookie.MaxAge = int(maxAge)
+		}
+		if secure, ok := c["secure"].(bool); ok {
+			cookie.Secure = secure
+		}
+		if httpOnly, ok := c["httpOnly"].(bool); ok {
+			cookie.HttpOnly = httpOnly
+		}
+		if sameSite, ok := c["sameSite"].(string); ok {
+			cookie.SameSite = sameSiteModes[strings.ToLower(sameSite)]
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies
+}
+
+// formatBody はボディを指定した形式に変換する（json以外は文字列を返す）
+func formatBody(body interface{}, format string, columns []string) (interface{}, error) {
+	switch format {
+	case flow.FormatText, flow.FormatHTML:
+		if s, ok := body.(string); ok {
+			return s, nil
+		}
+		if body == nil {
+			return "", nil
+		}
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode body: %w", err)
+		}
+		return string(data), nil
+	case flow.FormatCSV:
+		return encodeCSV(body, columns)
+	default:
+		return body, nil
+	}
+}
+
+// encodeCSV はオブジェクトの配列（または単一のオブジェクト）をCSVに変換する
+// 列はselectedFieldsの順、未指定の場合はすべての行のキーを名前順に並べる
+func encodeCSV(body interface{}, columns []string) (string, error) {
+	var rows []map[string]interface{}
+	switch v := body.(type) {
+	case map[string]interface{}:
+		rows = append(rows, v)
+	case nil:
+	default:
+		list, ok := toList(body)
+		if !ok {
+			return "", fmt.Errorf("csv body must be an array of objects")
+		}
+		for i, item := range list {
+			row, ok := item.(map[string]interface{})
+			if !ok {
+				return "", fmt.Errorf("csv row %d must be an object", i)
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	if len(columns) == 0 {
+		seen := map[string]bool{}
+		for _, row := range rows {
+			for key := range row {
+				if !seen[key] {
+					seen[key] = true
+					columns = append(columns, key)
+				}
+			}
+		}
+		sort.Strings(columns)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(columns); err != nil {
+		return "", err
+	}
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			value, ok := lookupPath(row, column)
+			if !ok {
+				value = row[column]
+			}
+			record[i] = csvValue(value)
+		}
+		if err := w.Write(record); err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	return buf.String(), w.Error()
+}
+
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
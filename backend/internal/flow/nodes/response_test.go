@@ -0,0 +1,73 @@
+package nodes
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/necorox/FlowCore/backend/internal/flow"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// TestResponseDatabaseRows はDatabaseノードのSELECTの結果（[]map[string]interface{}）をフィールドの選択・CSVで返せることを確認する
+func TestResponseDatabaseRows(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		wantBody interface{}
+	}{
+		{
+			name:   "selected fields",
+			config: map[string]interface{}{"selectedFields": []interface{}{"id", "name"}},
+			wantBody: []interface{}{
+				map[string]interface{}{"id": int64(1), "name": "alice"},
+				map[string]interface{}{"id": int64(2), "name": "bob"},
+			},
+		},
+		{
+			name:     "csv",
+			config:   map[string]interface{}{"format": "csv"},
+			wantBody: "id,name,password_hash\n1,alice,secret-1\n2,bob,secret-2\n",
+		},
+		{
+			name:     "csv with selected fields",
+			config:   map[string]interface{}{"format": "csv", "selectedFields": []interface{}{"name", "id"}},
+			wantBody: "name,id\nalice,1\nbob,2\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB(t, map[string]*fakeTable{
+				"u_users": {
+					columns: []models.Column{{Name: "id", Type: "number"}, {Name: "name", Type: "string"}, {Name: "password_hash", Type: "string"}},
+					rows:    [][]driver.Value{{int64(1), "alice", "secret-1"}, {int64(2), "bob", "secret-2"}},
+				},
+			})
+			registry := flow.NewRegistry()
+			if err := Register(registry); err != nil {
+				t.Fatal(err)
+			}
+			engine := flow.NewEngine(db, registry, flow.Config{Limits: flow.Limits{Timeout: 10 * time.Second}})
+
+			f := &models.Flow{
+				Nodes: []models.Node{
+					testNode("db", "database", map[string]interface{}{"table": "u_users"}, testPin("db-out", "output", "rows")),
+					testNode("response", "response", tt.config, testPin("response-in", "input", "rows")),
+				},
+				Connections: []models.Connection{
+					testConnection("db", "db-out", "response", "response-in"),
+				},
+			}
+
+			result, err := engine.Run(context.Background(), f, &flow.Request{Method: "GET"}, flow.Options{})
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if result.Response == nil || !reflect.DeepEqual(result.Response.Body, tt.wantBody) {
+				t.Errorf("Run() response = %#v, want body %#v", result.Response, tt.wantBody)
+			}
+		})
+	}
+}
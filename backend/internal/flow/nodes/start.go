@@ -0,0 +1,338 @@
+package nodes
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/necorox/FlowCore/backend/internal/flow"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// paramLocations はパラメータの取得元（省略時はパス・クエリ・JSONボディ・フォームの順に探す）
+// authは認証された呼び出し元（sub, tenant_id, roles, scopes, email, type）
+var paramLocations = map[string]bool{
+	"path": true, "query": true, "header": true, "cookie": true, "body": true, "form": true, "file": true, "auth": true,
+}
+
+// paramTypes はパラメータの型（値はこの型に変換される）
+var paramTypes = map[string]bool{
+	"any": true, "string": true, "int": true, "number": true, "bool": true,
+	"uuid": true, "timestamp": true, "object": true, "array": true, "file": true,
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Param は開始ノードで宣言されたリクエストパラメータ
+type Param struct {
+	Name     string
+	In       string
+	Type     string
+	Required bool
+	Default  interface{}
+}
+
+// Start はリクエストパラメータを出力ピンに展開する
+// trigger型のピンにはリクエスト全体、それ以外のピンにはラベル名のパラメータを出力する
+// 宣言されたパラメータは型を変換し、必須パラメータが欠けている場合は*flow.RequestErrorを返す
+func Start(ec *flow.ExecutionContext, in *flow.NodeInput) (map[string]interface{}, error) {
+	req := ec.Request
+
+	params, errs := ParseParams(in.Config)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid params: %s", strings.Join(errs, "; "))
+	}
+
+	values := make(map[string]interface{}, len(params))
+	declared := make(map[string]bool, len(params))
+	details := make(map[string]string)
+	for _, p := range params {
+		declared[p.Name] = true
+
+		value, ok := p.lookup(req)
+		if !ok {
+			if p.Required {
+				details[p.Name] = "is required"
+				continue
+			}
+			if p.Default == nil {
+				continue
+			}
+			value = p.Default
+		}
+
+		converted, err := convertValue(value, p.Type)
+		if err != nil {
+			details[p.Name] = err.Error()
+			continue
+		}
+		values[p.Name] = converted
+	}
+	if len(details) > 0 {
+		return nil, &flow.RequestError{Details: details}
+	}
+
+	outputs := make(map[string]interface{})
+	for _, pin := range in.OutputPins() {
+		switch {
+		case pin.DataType == "trigger":
+			outputs[pin.ID] = req.Map()
+		case declared[pin.Label]:
+			outputs[pin.ID] = values[pin.Label]
+		default:
+			outputs[pin.ID] = lookupParam(req, pin.Label)
+		}
+	}
+
+	return outputs, nil
+}
+
+// validateStart は開始ノードのparams設定を検証する
+func validateStart(node *models.Node) []string {
+	_, errs := ParseParams(node.Config)
+	return errs
+}
+
+// parseParams はparams設定を解析する
+// 各要素はパラメータ名の文字列、または {name, in, type, required, default} のオブジェクト
+func ParseParams(config map[string]interface{}) ([]Param, []string) {
+	raw, ok := config["params"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, []string{"params must be an array"}
+	}
+
+	var params []Param
+	var errs []string
+	seen := make(map[string]bool, len(list))
+	for i, item := range list {
+		p := Param{Type: "any"}
+		switch v := item.(type) {
+		case string:
+			p.Name = v
+		case map[string]interface{}:
+			p.Name, _ = v["name"].(string)
+			p.In, _ = v["in"].(string)
+			if t, ok := v["type"].(string); ok && t != "" {
+				p.Type = t
+			} else if p.In == "file" {
+				p.Type = "file"
+			}
+			p.Required, _ = v["required"].(bool)
+			p.Default = v["default"]
+		default:
+			errs = append(errs, fmt.Sprintf("params[%d] must be a string or an object", i))
+			continue
+		}
+
+		if p.Name == "" {
+			errs = append(errs, fmt.Sprintf("params[%d] has no name", i))
+			continue
+		}
+		if seen[p.Name] {
+			errs = append(errs, fmt.Sprintf("param %q is declared more than once", p.Name))
+			continue
+		}
+		seen[p.Name] = true
+
+		if p.In != "" && !paramLocations[p.In] {
+			errs = append(errs, fmt.Sprintf("param %q has unknown location %q", p.Name, p.In))
+		}
+		if !paramTypes[p.Type] {
+			errs = append(errs, fmt.Sprintf("param %q has unknown type %q", p.Name, p.Type))
+		}
+		if p.Type == "file" && p.In != "file" {
+			errs = append(errs, fmt.Sprintf("param %q: type \"file\" requires location \"file\"", p.Name))
+		}
+		if p.In == "file" && p.Type != "file" && p.Type != "array" {
+			errs = append(errs, fmt.Sprintf("param %q: location \"file\" requires type \"file\" or \"array\"", p.Name))
+		}
+		params = append(params, p)
+	}
+
+	return params, errs
+}
+
+// lookup はパラメータの取得元から値を探す
+// 文字列のパラメータが空の場合は指定されていないものとして扱う
+func (p *Param) lookup(req *flow.Request) (interface{}, bool) {
+	array := p.Type == "array"
+
+	switch p.In {
+	case "path":
+		value, ok := req.Params[p.Name]
+		return value, ok
+	case "query":
+		return stringValues(req.Query[p.Name], array)
+	case "header":
+		value, ok := req.Headers[http.CanonicalHeaderKey(p.Name)]
+		return value, ok && value != ""
+	case "cookie":
+		value, ok := req.Cookies[p.Name]
+		return value, ok && value != ""
+	case "body":
+		body, ok := req.Body.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		value, ok := body[p.Name]
+		return value, ok && value != nil
+	case "form":
+		return stringValues(req.Form[p.Name], array)
+	case "auth":
+		value, ok := req.AuthMap()[p.Name]
+		return value, ok && value != nil
+	case "file":
+		files := req.Files[p.Name]
+		if len(files) == 0 {
+			return nil, false
+		}
+		if array {
+			items := make([]interface{}, 0, len(files))
+			for _, file := range files {
+				items = append(items, file.Map())
+			}
+			return items, true
+		}
+		return files[0].Map(), true
+	default:
+		value := lookupParam(req, p.Name)
+		if s, ok := value.(string); ok && s == "" {
+			return nil, false
+		}
+		return value, value != nil
+	}
+}
+
+// stringValues はクエリ・フォームの値を返す（配列型の場合はすべての値）
+func stringValues(values []string, array bool) (interface{}, bool) {
+	if len(values) == 0 || (len(values) == 1 && values[0] == "") {
+		return nil, false
+	}
+	if !array {
+		return values[0], true
+	}
+	items := make([]interface{}, len(values))
+	for i, value := range values {
+		items[i] = value
+	}
+	return items, true
+}
+
+// lookupParam はパスパラメータ、クエリ、JSONボディ、フォームの順にパラメータを探す
+func lookupParam(req *flow.Request, name string) interface{} {
+	if value, ok := req.Params[name]; ok {
+		return value
+	}
+	if value, ok := req.QueryValue(name); ok {
+		return value
+	}
+	if body, ok := req.Body.(map[string]interface{}); ok {
+		if value, ok := body[name]; ok {
+			return value
+		}
+	}
+	if value, ok := req.FormValue(name); ok {
+		return value
+	}
+	return nil
+}
+
+// convertValue は値を宣言された型に変換する
+func convertValue(value interface{}, paramType string) (interface{}, error) {
+	switch paramType {
+	case "string":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+		return nil, fmt.Errorf("must be a string")
+
+	case "int":
+		switch v := value.(type) {
+		case string:
+			if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return n, nil
+			}
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+				return int64(v), nil
+			}
+		case int64:
+			return v, nil
+		case int:
+			return int64(v), nil
+		}
+		return nil, fmt.Errorf("must be an integer")
+
+	case "number":
+		switch v := value.(type) {
+		case string:
+			if n, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && !math.IsInf(n, 0) && !math.IsNaN(n) {
+				return n, nil
+			}
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case int:
+			return float64(v), nil
+		}
+		return nil, fmt.Errorf("must be a number")
+
+	case "bool":
+		switch v := value.(type) {
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, nil
+			}
+		case bool:
+			return v, nil
+		}
+		return nil, fmt.Errorf("must be a boolean")
+
+	case "uuid":
+		if s, ok := value.(string); ok && uuidPattern.MatchString(s) {
+			return strings.ToLower(s), nil
+		}
+		return nil, fmt.Errorf("must be a UUID")
+
+	case "timestamp":
+		if s, ok := value.(string); ok {
+			for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+				if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+					return t.UTC().Format(time.RFC3339Nano), nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("must be an RFC 3339 timestamp")
+
+	case "object":
+		if m, ok := value.(map[string]interface{}); ok {
+			return m, nil
+		}
+		return nil, fmt.Errorf("must be an object")
+
+	case "array":
+		if items, ok := value.([]interface{}); ok {
+			return items, nil
+		}
+		return nil, fmt.Errorf("must be an array")
+
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,68 @@
+package nodes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/necorox/FlowCore/backend/internal/flow"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// Subflow は設定で指定したバージョンのサブフローを実行する
+// 入力ピンの値をラベル名の入力としてサブフローに渡し、ラベルが宣言された出力と一致する出力ピンにはその出力、
+// それ以外の出力ピンには出力全体（出力名 → 値）を出力する
+func Subflow(ec *flow.ExecutionContext, in *flow.NodeInput) (map[string]interface{}, error) {
+	ctx := ec.Context()
+	name := in.ConfigString("subflow")
+	version, _ := in.Config["version"].(float64)
+
+	sf, err := ec.DB().SubflowVersion(ctx, name, int(version))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("subflow %q version %d not found", name, int(version))
+		}
+		return nil, fmt.Errorf("failed to load subflow %q: %w", name, err)
+	}
+
+	inputs := make(map[string]interface{}, len(in.Inputs))
+	for _, pin := range in.InputPins() {
+		if value, ok := in.Inputs[pin.ID]; ok {
+			inputs[pin.Label] = value
+		}
+	}
+	for _, p := range sf.Inputs {
+		if _, ok := inputs[p.Name]; p.Required && !ok {
+			return nil, fmt.Errorf("required input %q of subflow %q is missing", p.Name, name)
+		}
+	}
+
+	result, err := ec.RunSubflow(ctx, sf, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make(map[string]interface{})
+	for _, pin := range in.OutputPins() {
+		if value, ok := result[pin.Label]; ok {
+			outputs[pin.ID] = value
+		} else {
+			outputs[pin.ID] = result
+		}
+	}
+	return outputs, nil
+}
+
+// validateSubflow はサブフローの参照（名前とバージョン）の形式を検証する
+// 参照先の存在と入力・出力ピンの照合は保存時にMetaDBを参照して行う（flow.ValidateSubflowCalls）
+func validateSubflow(node *models.Node) []string {
+	var errs []string
+	if name, ok := node.Config["subflow"].(string); ok && name != "" && !flow.ValidSubflowName(name) {
+		errs = append(errs, fmt.Sprintf("invalid subflow name %q", name))
+	}
+	if version, ok := node.Config["version"].(float64); ok && (version < 1 || version != math.Trunc(version)) {
+		errs = append(errs, "version must be a positive integer")
+	}
+	return errs
+}
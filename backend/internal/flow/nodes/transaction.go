@@ -0,0 +1,49 @@
+package nodes
+
+import (
+	"fmt"
+
+	"github.com/necorox/FlowCore/backend/internal/flow"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// defaultTxRetries はシリアライゼーション失敗時に本体を再実行する既定の回数
+const defaultTxRetries = 3
+
+// Transaction は本体（body）のノードを1つのトランザクション内で実行し、Collectノードが受け取った値を出力する
+// 本体のDatabaseノードはすべて同じトランザクションを使用し、本体が成功した場合にコミット、失敗した場合にロールバックする
+// 本体のItemノードはTransactionノードの入力をラベル名で出力する
+func Transaction(ec *flow.ExecutionContext, in *flow.NodeInput) (map[string]interface{}, error) {
+	opts := flow.TxOptions{MaxRetries: defaultTxRetries}
+	if name := in.ConfigString("isolation"); name != "" {
+		level, ok := flow.IsolationLevels[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported isolation level %q", name)
+		}
+		opts.Isolation = level
+	}
+	if n, ok := in.Config["maxRetries"].(float64); ok {
+		opts.MaxRetries = int(n)
+	}
+
+	vars := make(map[string]interface{}, len(in.Inputs))
+	for _, pin := range in.InputPins() {
+		if value, ok := in.Inputs[pin.ID]; ok {
+			vars[pin.Label] = value
+		}
+	}
+
+	result, err := ec.RunBodyInTx(in.Node.Body, vars, opts)
+	if err != nil {
+		return nil, err
+	}
+	return in.OutputAll(result), nil
+}
+
+// validateTransaction はTransactionノードの再実行回数を検証する
+func validateTransaction(node *models.Node) []string {
+	if n, ok := node.Config["maxRetries"].(float64); ok && (n < 0 || n != float64(int(n))) {
+		return []string{"maxRetries must be a non-negative integer"}
+	}
+	return nil
+}
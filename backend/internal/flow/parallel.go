@@ -0,0 +1,114 @@
+package flow
+
+import (
+	"context"
+	"sort"
+)
+
+// runParallel は互いに依存しないノードを並列に実行する
+//
+// 実行可能になったノードは実行順序（g.order）の順に開始する。Startノードはリクエストの検証を先に行うため、
+// 他のノードと並列に実行しない。最初のワーカーはrunGraphを呼び出したgoroutineの枠として常に使用でき、
+// 追加のワーカーはリクエスト全体で共有する枠（ec.workers）を取得できた場合にのみ開始する。
+// ノードの本体（ForEachの要素など）も、本体を実行するノードの枠を引き継ぐか共有の枠を取得して実行するため、
+// 入れ子になっても同時に実行されるノードの数は上限（MaxParallelism）を超えない。
+// いずれかのノードが失敗した場合は実行中の他のノードをキャンセルし、未実行のノードは実行しない
+func (r *graphRun) runParallel() error {
+	ctx, cancel := context.WithCancel(r.ec.ctx)
+	defer cancel()
+
+	index := make(map[string]int, len(r.g.order))
+	for i, node := range r.g.order {
+		index[node.ID] = i
+	}
+	// pendingは完了を待っている接続元の接続の数
+	pending := make([]int, len(r.g.order))
+	for _, conn := range r.g.flow.Connections {
+		pending[index[conn.To.NodeID]]++
+	}
+	var ready []int
+	for i := range pending {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	// release は完了したノードの接続先の待ち数を減らし、実行可能になったノードを追加する
+	release := func(i int) {
+		for _, conn := range r.g.outgoing[r.g.order[i].ID] {
+			j := index[conn.To.NodeID]
+			if pending[j]--; pending[j] == 0 {
+				ready = append(ready, j)
+			}
+		}
+		sort.Ints(ready)
+	}
+
+	type result struct {
+		index int
+		err   error
+	}
+	done := make(chan result)
+	running := 0
+	exclusive := false
+	var firstErr error
+
+	for {
+		for firstErr == nil && !exclusive && len(ready) > 0 {
+			i := ready[0]
+			node := r.g.order[i]
+			if err := contextError(r.ec.ctx); err != nil {
+				firstErr = err
+				break
+			}
+			if r.skipInactive(node) {
+				ready = ready[1:]
+				release(i)
+				continue
+			}
+			if node.Type == "start" && running > 0 {
+				break
+			}
+
+			token := false
+			if running > 0 {
+				select {
+				case r.ec.workers <- struct{}{}:
+					token = true
+				default:
+				}
+				if !token {
+					break
+				}
+			}
+
+			ready = ready[1:]
+			running++
+			exclusive = node.Type == "start"
+			go func() {
+				err := r.runNode(ctx, i)
+				if token {
+					<-r.ec.workers
+				}
+				done <- result{index: i, err: err}
+			}()
+		}
+		if running == 0 {
+			break
+		}
+
+		res := <-done
+		running--
+		exclusive = false
+		if res.err != nil {
+			// 最初のエラーを返し、実行中の他のノードをキャンセルする（キャンセルされたノードのエラーはトレースにのみ記録する）
+			if firstErr == nil {
+				firstErr = res.err
+				cancel()
+			}
+			continue
+		}
+		release(res.index)
+	}
+
+	return firstErr
+}
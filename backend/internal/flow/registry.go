@@ -0,0 +1,71 @@
+package flow
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// NodeType はレジストリに登録されるノードタイプ
+type NodeType struct {
+	models.NodeTypeInfo
+	Executor NodeExecutor
+	// Validate はスキーマで表現できない設定・ピンの検証を行い、エラーメッセージを返す（省略可）
+	Validate func(node *models.Node) []string
+}
+
+// Registry はノードタイプと実行処理の対応を管理する
+type Registry struct {
+	mu    sync.RWMutex
+	types map[string]NodeType
+}
+
+// NewRegistry は空のRegistryを作成する
+func NewRegistry() *Registry {
+	return &Registry{types: make(map[string]NodeType)}
+}
+
+// Register はノードタイプを登録する
+func (r *Registry) Register(t NodeType) error {
+	if t.Type == "" {
+		return fmt.Errorf("node type name is required")
+	}
+	if t.Executor == nil {
+		return fmt.Errorf("node type %q has no executor", t.Type)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.types[t.Type]; exists {
+		return fmt.Errorf("node type %q is already registered", t.Type)
+	}
+	r.types[t.Type] = t
+	return nil
+}
+
+// Lookup はノードタイプを取得する
+func (r *Registry) Lookup(name string) (NodeType, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.types[name]
+	return t, ok
+}
+
+// List は登録済みノードタイプの定義を名前順で返す
+func (r *Registry) List() []models.NodeTypeInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	infos := make([]models.NodeTypeInfo, 0, len(r.types))
+	for _, t := range r.types {
+		infos = append(infos, t.NodeTypeInfo)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Type < infos[j].Type
+	})
+	return infos
+}
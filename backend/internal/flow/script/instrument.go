@@ -0,0 +1,343 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/parser"
+	"github.com/dop251/goja/token"
+)
+
+// reservedPrefix は挿入する関数の名前の接頭辞（スクリプトからはこの接頭辞の名前を宣言・参照できない）
+const reservedPrefix = "__flowcore_"
+
+// stepFunc はステップ数を数える関数のグローバル名
+const stepFunc = reservedPrefix + "step"
+
+// stepCall はループの本体・関数の本体の先頭に挿入する呼び出し
+const stepCall = stepFunc + "()"
+
+// lengthFunc は文字列の連結の結果の長さを確認する関数のグローバル名
+const lengthFunc = reservedPrefix + "length"
+
+// errReservedName はスクリプトが挿入する関数の名前（reservedPrefixで始まる名前）を使用した場合のエラー
+var errReservedName = fmt.Errorf("identifiers starting with %q are reserved", reservedPrefix)
+
+// astPkgPath はgojaのASTのパッケージ（このパッケージの型のみをたどる）
+var astPkgPath = reflect.TypeOf(ast.Program{}).PkgPath()
+
+// insertion はソースのoffsetの位置に挿入する文字列
+type insertion struct {
+	offset int
+	text   string
+}
+
+// instrument はスクリプトを解析し、ループの本体と関数の本体の先頭にステップ数を数える呼び出しを挿入したソースを返す
+// ループの反復・関数の呼び出しのたびに1ステップを消費するため、組み込み関数のコールバックや再帰も数えられる
+// あわせて + ・ += ・テンプレートリテラルの結果を長さを確認する関数に渡し、連結で長い文字列を生成できないようにする
+func instrument(source string) (string, error) {
+	program, err := parser.ParseFile(nil, "script", source, 0)
+	if err != nil {
+		return "", err
+	}
+
+	var inserts []insertion
+	add := func(idx int, text string) {
+		// file.Idxは1始まりの位置
+		inserts = append(inserts, insertion{offset: idx - 1, text: text})
+	}
+	// loopBody はループの本体の先頭で1ステップを消費させる
+	// 式文は「(step(), 式)」、その他の文は終了位置を使わずに済むよう「if (step()) ; else 文」の形にする
+	loopBody := func(body ast.Statement) {
+		switch stmt := body.(type) {
+		case *ast.BlockStatement:
+			add(int(stmt.LeftBrace)+1, stepCall+";")
+		case *ast.ExpressionStatement:
+			add(int(stmt.Expression.Idx0()), "("+stepCall+", ")
+			add(int(stmt.Expression.Idx1()), ")")
+		case *ast.IfStatement:
+			add(ifStart(source, stmt), "if ("+stepCall+") ; else ")
+		default:
+			add(int(body.Idx0()), "if ("+stepCall+") ; else ")
+		}
+	}
+
+	// concatenations は連結の式（左辺・右辺・演算子）。括弧の位置を求めるため、リテラルの位置を集めてから処理する
+	type concatenation struct {
+		left, right ast.Expression
+		op          string
+	}
+	var concatenations []concatenation
+	var literals []span
+
+	reserved := false
+	walk(reflect.ValueOf(program), map[ast.Node]bool{}, func(node ast.Node) {
+		switch n := node.(type) {
+		case *ast.Identifier:
+			if strings.HasPrefix(string(n.Name), reservedPrefix) {
+				reserved = true
+			}
+		case *ast.StringLiteral:
+			literals = append(literals, span{int(n.Idx0()) - 1, int(n.Idx1()) - 1})
+		case *ast.RegExpLiteral:
+			literals = append(literals, span{int(n.Idx0()) - 1, int(n.Idx1()) - 1})
+		case *ast.BinaryExpression:
+			if n.Operator == token.PLUS {
+				concatenations = append(concatenations, concatenation{n.Left, n.Right, "+"})
+			}
+		case *ast.AssignExpression:
+			// += の場合もOperatorはPLUSになる
+			if n.Operator == token.PLUS {
+				concatenations = append(concatenations, concatenation{n.Left, n.Right, "+="})
+			}
+		case *ast.TemplateLiteral:
+			literals = append(literals, templateSpans(source, n)...)
+			// タグ付きテンプレートは関数の呼び出し（String.rawはサンドボックスで確認する）
+			if n.Tag == nil {
+				add(int(n.OpenQuote), lengthFunc+"(")
+				add(int(n.Idx1()), ")")
+			}
+		case *ast.ForStatement:
+			loopBody(n.Body)
+		case *ast.ForInStatement:
+			loopBody(n.Body)
+		case *ast.ForOfStatement:
+			loopBody(n.Body)
+		case *ast.WhileStatement:
+			loopBody(n.Body)
+		case *ast.DoWhileStatement:
+			loopBody(n.Body)
+		case *ast.FunctionLiteral:
+			add(int(n.Body.LeftBrace)+1, stepCall+";")
+		case *ast.ArrowFunctionLiteral:
+			switch body := n.Body.(type) {
+			case *ast.BlockStatement:
+				add(int(body.LeftBrace)+1, stepCall+";")
+			case *ast.ExpressionBody:
+				add(int(body.Idx0()), "("+stepCall+", ")
+				add(int(body.Idx1()), ")")
+			}
+		}
+	})
+	if reserved {
+		return "", errReservedName
+	}
+
+	// 連結の式全体を長さを確認する関数の呼び出しで囲む
+	sort.Slice(literals, func(i, j int) bool { return literals[i].start < literals[j].start })
+	for _, c := range concatenations {
+		start, ok := concatStart(source, literals, c.left, c.op)
+		if !ok {
+			return "", errors.New("failed to instrument script")
+		}
+		inserts = append(inserts, insertion{offset: start, text: lengthFunc + "("})
+		add(int(c.right.Idx1()), ")")
+	}
+
+	// 元のソースの位置の順に挿入する
+	sort.SliceStable(inserts, func(i, j int) bool { return inserts[i].offset < inserts[j].offset })
+	var b strings.Builder
+	b.Grow(len(source) + len(inserts)*len(stepCall))
+	prev := 0
+	for _, ins := range inserts {
+		if ins.offset < prev || ins.offset > len(source) {
+			return "", errors.New("failed to instrument script")
+		}
+		b.WriteString(source[prev:ins.offset])
+		b.WriteString(ins.text)
+		prev = ins.offset
+	}
+	b.WriteString(source[prev:])
+	return b.String(), nil
+}
+
+// span はソースの範囲（0始まり、endは含まない）
+type span struct {
+	start, end int
+}
+
+// concatStart は連結の式（左辺 演算子 右辺）を囲む位置（0始まり）を返す
+// 括弧で囲まれた式のIdx0は括弧の内側になるため、左辺の先頭から演算子までの間で対応する開き括弧のない
+// 閉じ括弧の数だけ、開始位置を括弧の外側に戻す（文字列・正規表現・テンプレートのリテラルとコメント内の括弧は数えない）
+// 左辺の後が空白・コメント・閉じ括弧と演算子以外の場合や、開始位置を戻せない場合はfalseを返す
+func concatStart(source string, literals []span, left ast.Expression, op string) (int, bool) {
+	start := int(left.Idx0()) - 1
+	end := skipSpace(source, int(left.Idx1())-1)
+	for end < len(source) && source[end] == ')' {
+		end = skipSpace(source, end+1)
+	}
+	if !strings.HasPrefix(source[end:], op) {
+		return 0, false
+	}
+
+	unmatched, depth := 0, 0
+	for i := start; i < end; {
+		if j := literalEnd(literals, i); j > i {
+			i = j
+			continue
+		}
+		if j := skipSpace(source, i); j > i {
+			i = j
+			continue
+		}
+		switch source[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			} else {
+				unmatched++
+			}
+		}
+		i++
+	}
+
+	for ; unmatched > 0; unmatched-- {
+		start = skipSpaceBack(source, start)
+		if start == 0 || source[start-1] != '(' {
+			return 0, false
+		}
+		start--
+	}
+	return start, true
+}
+
+// templateSpans はテンプレートリテラルの文字列の部分（${ } 内の式を除く）の範囲を返す
+// TemplateElementのIdxは文字列の先頭を指さないため、ソースから求める
+func templateSpans(source string, n *ast.TemplateLiteral) []span {
+	var spans []span
+	i := int(n.OpenQuote) - 1
+	for k := 0; i < len(source); k++ {
+		// 文字列の部分は ` または式の後の } から、${ または閉じる ` まで
+		start := i
+		for i++; i < len(source) && source[i] != '`' && !strings.HasPrefix(source[i:], "${"); i++ {
+			if source[i] == '\\' {
+				i++
+			}
+		}
+		spans = append(spans, span{start, min(i+1, len(source))})
+		if k >= len(n.Expressions) || i >= len(source) || source[i] == '`' {
+			break
+		}
+		i = skipSpace(source, int(n.Expressions[k].Idx1())-1)
+		for i < len(source) && source[i] == ')' {
+			i = skipSpace(source, i+1)
+		}
+		if i >= len(source) || source[i] != '}' {
+			break
+		}
+	}
+	return spans
+}
+
+// literalEnd はiがリテラルの先頭の場合にその終了位置を返す（それ以外はi）
+func literalEnd(literals []span, i int) int {
+	n := sort.Search(len(literals), func(k int) bool { return literals[k].start >= i })
+	if n < len(literals) && literals[n].start == i {
+		return literals[n].end
+	}
+	return i
+}
+
+// skipSpace はsource[i]以降の空白とコメントを読み飛ばした位置を返す
+func skipSpace(source string, i int) int {
+	for i < len(source) {
+		switch {
+		case isSpace(source[i]):
+			i++
+		case strings.HasPrefix(source[i:], "//"):
+			n := strings.IndexByte(source[i:], '\n')
+			if n < 0 {
+				return len(source)
+			}
+			i += n + 1
+		case strings.HasPrefix(source[i:], "/*"):
+			n := strings.Index(source[i+2:], "*/")
+			if n < 0 {
+				return len(source)
+			}
+			i += n + 4
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// skipSpaceBack はsource[i]より前の空白とブロックコメントを読み飛ばした位置を返す
+func skipSpaceBack(source string, i int) int {
+	for i > 0 {
+		switch {
+		case isSpace(source[i-1]):
+			i--
+		case strings.HasSuffix(source[:i], "*/"):
+			n := strings.LastIndex(source[:i-2], "/*")
+			if n < 0 {
+				return i
+			}
+			i = n
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+func isSpace(c byte) bool {
+	return strings.IndexByte(" \t\r\n\v\f", c) >= 0
+}
+
+// ifStart はif文の開始位置を返す（見つからない場合は0）
+// gojaのパーサーはIfStatement.Ifを設定しないため、条件式の前にあるifキーワードを探す
+func ifStart(source string, stmt *ast.IfStatement) int {
+	i := int(stmt.Test.Idx0()) - 1
+	for i > 0 && strings.IndexByte(" \t\r\n(", source[i-1]) >= 0 {
+		i--
+	}
+	if i >= 2 && source[i-2:i] == "if" {
+		return i - 1
+	}
+	return 0
+}
+
+// walk はASTをたどり、すべてのノードについてvisitを呼び出す（同じノードは1回のみ）
+// 関数の宣言は本体とDeclarationListの両方から参照されるため、訪問済みのノードは除外する
+func walk(v reflect.Value, seen map[ast.Node]bool, visit func(ast.Node)) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() {
+			walk(v.Elem(), seen, visit)
+		}
+	case reflect.Ptr:
+		if !v.IsNil() {
+			walk(v.Elem(), seen, visit)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			walk(v.Index(i), seen, visit)
+		}
+	case reflect.Struct:
+		if v.Type().PkgPath() != astPkgPath {
+			return
+		}
+		if v.CanAddr() {
+			if node, ok := v.Addr().Interface().(ast.Node); ok {
+				if seen[node] {
+					return
+				}
+				seen[node] = true
+				visit(node)
+			}
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				walk(v.Field(i), seen, visit)
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package script
+
+import (
+	"fmt"
+
+	"github.com/dop251/goja"
+)
+
+// sandboxSource はスクリプトの実行前に組み込み関数を制限するプログラム
+// - 文字列・配列を生成する組み込み関数は、実行前（長さが引数・配列の長さから決まる場合）と実行後に長さを確認する
+// - Array.prototype.join は結果の長さを確認しながら連結する実装に置き換える
+// - 文字列の連結（+・+=・テンプレートリテラル）の結果の長さを確認する関数を定義する（instrumentが呼び出しを挿入する）
+// - ArrayBuffer・型付き配列（長さだけで大きなメモリを確保できる）を削除する
+// - eval・Functionコンストラクタ（ステップ数を数えられないコードの実行）を禁止する
+// 元の関数はクロージャ内にのみ保持するため、スクリプトからは取得できない
+const sandboxSource = `(function (maxLength, lengthFunc) {
+	"use strict";
+	const apply = Reflect.apply, construct = Reflect.construct, ownKeys = Reflect.ownKeys;
+	const defineProperty = Object.defineProperty, getOwnPropertyDescriptor = Object.getOwnPropertyDescriptor;
+	const getPrototypeOf = Object.getPrototypeOf;
+	const isArray = Array.isArray, floor = Math.floor, toString = String;
+
+	const check = (length) => {
+		if (length > maxLength) {
+			throw new RangeError("value exceeds the maximum length of " + maxLength);
+		}
+	};
+	const lengthOf = (value) =>
+		(typeof value === "string" || (value !== null && typeof value === "object")) && typeof value.length === "number"
+			? value.length
+			: 0;
+	const count = (value) => {
+		const n = +value;
+		return n > 0 ? floor(n) : 0;
+	};
+	const define = (object, key, value) =>
+		defineProperty(object, key, { value: value, writable: true, enumerable: false, configurable: true });
+	const guard = (object, key, before) => {
+		const original = object[key];
+		if (typeof original !== "function") {
+			return;
+		}
+		define(object, key, function (...args) {
+			if (before) {
+				check(before(this, args));
+			}
+			const result = apply(original, this, args);
+			check(lengthOf(result));
+			return result;
+		});
+	};
+
+	const arrayLength = (self) => lengthOf(Object(self));
+	const arrayBefore = {
+		concat: (self, args) => args.reduce((n, arg) => n + (isArray(arg) ? arg.length : 1), arrayLength(self)),
+	};
+	for (const key of ownKeys(Array.prototype)) {
+		if (key !== "constructor" && key !== "join") {
+			guard(Array.prototype, key, arrayBefore[key] || arrayLength);
+		}
+	}
+	define(Array.prototype, "join", function (separator) {
+		const self = Object(this);
+		const length = arrayLength(self);
+		check(length);
+		const sep = separator === undefined ? "," : toString(separator);
+		let result = "";
+		for (let i = 0; i < length; i++) {
+			const value = self[i];
+			const s = (i > 0 ? sep : "") + (value === undefined || value === null ? "" : toString(value));
+			check(result.length + s.length);
+			result += s;
+		}
+		return result;
+	});
+	guard(Array, "from", (self, args) => lengthOf(args[0]));
+	guard(Array, "of", (self, args) => args.length);
+	const OriginalArray = Array;
+	const GuardedArray = function Array(...args) {
+		check(args.length === 1 && typeof args[0] === "number" ? args[0] : args.length);
+		return construct(OriginalArray, args, new.target || GuardedArray);
+	};
+	for (const key of ownKeys(OriginalArray)) {
+		if (key !== "length" && key !== "name") {
+			defineProperty(GuardedArray, key, getOwnPropertyDescriptor(OriginalArray, key));
+		}
+	}
+	define(globalThis, "Array", GuardedArray);
+	define(OriginalArray.prototype, "constructor", GuardedArray);
+
+	const stringBefore = {
+		repeat: (self, args) => toString(self).length * count(args[0]),
+		padStart: (self, args) => count(args[0]),
+		padEnd: (self, args) => count(args[0]),
+		concat: (self, args) => args.reduce((n, arg) => n + toString(arg).length, toString(self).length),
+	};
+	// その他の文字列のメソッドの結果は元の文字列の長さに比例するため、結果が長くなりうるメソッドのみを対象にする
+	for (const key of ["repeat", "padStart", "padEnd", "concat", "replace", "replaceAll"]) {
+		guard(String.prototype, key, stringBefore[key]);
+	}
+	for (const key of ["fromCharCode", "fromCodePoint", "raw"]) {
+		guard(String, key);
+	}
+	guard(JSON, "stringify");
+	// 連結の結果を確認するため、最大長の2倍までの文字列は一時的に生成されうる
+	defineProperty(globalThis, lengthFunc, {
+		value: (value) => {
+			if (typeof value === "string") {
+				check(value.length);
+			}
+			return value;
+		},
+		writable: false, enumerable: false, configurable: false,
+	});
+
+	for (const key of [
+		"ArrayBuffer", "SharedArrayBuffer", "DataView",
+		"Int8Array", "Uint8Array", "Uint8ClampedArray", "Int16Array", "Uint16Array",
+		"Int32Array", "Uint32Array", "Float32Array", "Float64Array", "BigInt64Array", "BigUint64Array",
+	]) {
+		delete globalThis[key];
+	}
+
+	const deny = function () {
+		throw new EvalError("dynamic code evaluation is not allowed");
+	};
+	deny.prototype = Function.prototype;
+	for (const fn of [function () {}, function* () {}, async function () {}]) {
+		define(getPrototypeOf(fn), "constructor", deny);
+	}
+	define(globalThis, "eval", deny);
+	define(globalThis, "Function", deny);
+})`
+
+// sandboxProgram はコンパイル済みのsandboxSource
+var sandboxProgram = goja.MustCompile("sandbox", sandboxSource, true)
+
+// setupSandbox はランタイムの組み込み関数を制限し、ステップ数を数える関数を設定する
+// 数えたステップ数がmaxStepsを超えるとErrStepLimitでスクリプトを中断する（0は無制限）
+func setupSandbox(vm *goja.Runtime, limits Limits) error {
+	setup, err := vm.RunProgram(sandboxProgram)
+	if err != nil {
+		return err
+	}
+	fn, ok := goja.AssertFunction(setup)
+	if !ok {
+		return fmt.Errorf("sandbox setup is not a function")
+	}
+	maxLength := limits.MaxValueLength
+	if maxLength <= 0 {
+		maxLength = int(^uint32(0) >> 1)
+	}
+	if _, err := fn(goja.Undefined(), vm.ToValue(maxLength), vm.ToValue(lengthFunc)); err != nil {
+		return err
+	}
+
+	steps := 0
+	step := func() {
+		steps++
+		if limits.MaxSteps > 0 && steps > limits.MaxSteps {
+			vm.Interrupt(ErrStepLimit)
+		}
+	}
+	// 書き換え・再定義できないよう、書き込み・設定不可のプロパティとして定義する
+	return vm.GlobalObject().DefineDataProperty(stepFunc, vm.ToValue(step), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+}
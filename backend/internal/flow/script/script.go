@@ -0,0 +1,276 @@
+// Package script はProcess/Filterノード向けのサンドボックス化されたJavaScript実行環境を提供する
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+var (
+	// ErrTimeout はスクリプトが制限時間内に終了しなかった場合のエラー
+	ErrTimeout = errors.New("script execution timed out")
+	// ErrStepLimit はスクリプトがステップ数（ループの反復・関数の呼び出し）の上限を超えた場合のエラー
+	ErrStepLimit = errors.New("script exceeded step limit")
+	// ErrScriptTooLarge はスクリプトが最大サイズを超えた場合のエラー
+	ErrScriptTooLarge = errors.New("script is too large")
+	// ErrResultTooLarge はスクリプトの結果が最大サイズを超えた場合のエラー
+	ErrResultTooLarge = errors.New("script result is too large")
+	// ErrInputTooLarge はスクリプトに渡す変数が最大サイズを超えた場合のエラー
+	ErrInputTooLarge = errors.New("script input is too large")
+)
+
+// Limits はスクリプト実行の制限（ランタイムごとに適用され、並行して実行される他のスクリプトの影響を受けない）
+// メモリの使用量は入力・組み込み関数と文字列の連結で生成する値の長さ・ステップ数・実行時間で制限する
+type Limits struct {
+	Timeout        time.Duration // 最大実行時間
+	MaxSteps       int           // ループの反復と関数の呼び出しの最大回数
+	MaxValueLength int           // 組み込み関数・文字列の連結で生成する文字列・配列の最大長
+	MaxCallStack   int           // 最大コールスタック深度
+	MaxScriptBytes int           // スクリプトの最大サイズ
+	MaxInputBytes  int           // 変数をJSONにした場合の合計の最大サイズ
+	MaxResultBytes int           // 結果をJSONにした場合の最大サイズ
+}
+
+// DefaultLimits はデフォルトの実行制限
+var DefaultLimits = Limits{
+	Timeout:        time.Second,
+	MaxSteps:       10_000_000,
+	MaxValueLength: 4 << 20,
+	MaxCallStack:   256,
+	MaxScriptBytes: 64 << 10,
+	MaxInputBytes:  4 << 20,
+	MaxResultBytes: 4 << 20,
+}
+
+// Options はスクリプト実行のオプション
+type Options struct {
+	Limits Limits
+	// Transform が指定され、スクリプトの評価結果が関数の場合は
+	// data 配列に対して map/filter/reduce/sort として適用する
+	Transform string
+	// Initial はreduceの初期値
+	Initial interface{}
+}
+
+// Run はスクリプトを実行し、結果をJSON互換のGoの値に変換して返す
+// スクリプトは式（例: data.map(x => x.id)）または return を含む関数本体として記述できる
+// varsの値はJSONとしてコピーされるため、スクリプトからGo側の値は変更できない
+func Run(ctx context.Context, source string, vars map[string]interface{}, opts Options) (interface{}, error) {
+	limits := opts.Limits
+	if limits.MaxScriptBytes > 0 && len(source) > limits.MaxScriptBytes {
+		return nil, ErrScriptTooLarge
+	}
+
+	program, err := compile(source)
+	if err != nil {
+		return nil, err
+	}
+
+	// ファイルシステム・ネットワーク等のモジュールを持たない素のランタイムを使用する
+	vm := goja.New()
+	if limits.MaxCallStack > 0 {
+		vm.SetMaxCallStackSize(limits.MaxCallStack)
+	}
+	if err := setupSandbox(vm, limits); err != nil {
+		return nil, fmt.Errorf("failed to set up script sandbox: %w", err)
+	}
+
+	inputBytes := 0
+	for name, value := range vars {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set variable %q: %w", name, err)
+		}
+		inputBytes += len(data)
+		if limits.MaxInputBytes > 0 && inputBytes > limits.MaxInputBytes {
+			return nil, ErrInputTooLarge
+		}
+		jsValue, err := parseJSON(vm, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set variable %q: %w", name, err)
+		}
+		if err := vm.Set(name, jsValue); err != nil {
+			return nil, fmt.Errorf("failed to set variable %q: %w", name, err)
+		}
+	}
+
+	stop := watch(ctx, vm, limits)
+	defer stop()
+
+	result, err := vm.RunProgram(program)
+	if err == nil && opts.Transform != "" {
+		result, err = transform(vm, result, opts.Transform, opts.Initial)
+	}
+	if err != nil {
+		var interrupted *goja.InterruptedError
+		if errors.As(err, &interrupted) {
+			if cause, ok := interrupted.Value().(error); ok {
+				return nil, cause
+			}
+		}
+		var stackOverflow *goja.StackOverflowError
+		if errors.As(err, &stackOverflow) {
+			return nil, fmt.Errorf("script exceeded maximum call stack size")
+		}
+		return nil, fmt.Errorf("script error: %w", err)
+	}
+
+	return fromJSValue(vm, result, limits.MaxResultBytes)
+}
+
+// compile はスクリプトを式またはプログラムとしてコンパイルし、
+// トップレベルの return を含む場合は関数本体としてコンパイルする
+// どちらの場合もステップ数を数える呼び出しを挿入してからコンパイルする
+func compile(source string) (*goja.Program, error) {
+	instrumented, err := instrument(source)
+	if errors.Is(err, errReservedName) {
+		return nil, err
+	}
+	if err != nil {
+		wrapped, wrapErr := instrument("(function() {\n" + source + "\n})()")
+		if wrapErr != nil {
+			if errors.Is(wrapErr, errReservedName) {
+				return nil, wrapErr
+			}
+			return nil, fmt.Errorf("script syntax error: %w", err)
+		}
+		instrumented = wrapped
+	}
+
+	program, err := goja.Compile("script", instrumented, true)
+	if err != nil {
+		return nil, fmt.Errorf("script syntax error: %w", err)
+	}
+	return program, nil
+}
+
+// watch はタイムアウト・コンテキストのキャンセルを監視し、スクリプトを中断する
+func watch(ctx context.Context, vm *goja.Runtime, limits Limits) (stop func()) {
+	done := make(chan struct{})
+
+	var timer *time.Timer
+	var timeout <-chan time.Time
+	if limits.Timeout > 0 {
+		timer = time.NewTimer(limits.Timeout)
+		timeout = timer.C
+	}
+
+	go func() {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			vm.Interrupt(ctx.Err())
+		case <-timeout:
+			vm.Interrupt(ErrTimeout)
+		}
+	}()
+
+	return func() {
+		close(done)
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// transform は関数として評価されたスクリプトをdata配列に適用する
+func transform(vm *goja.Runtime, fn goja.Value, kind string, initial interface{}) (goja.Value, error) {
+	if _, ok := goja.AssertFunction(fn); !ok {
+		return fn, nil
+	}
+
+	data := vm.Get("data")
+	if data == nil || goja.IsUndefined(data) || goja.IsNull(data) {
+		return nil, fmt.Errorf("data is not set")
+	}
+	array := data.ToObject(vm)
+
+	call := func(method string, args ...goja.Value) (goja.Value, error) {
+		f, ok := goja.AssertFunction(array.Get(method))
+		if !ok {
+			return nil, fmt.Errorf("data is not an array")
+		}
+		return f(array, args...)
+	}
+
+	switch kind {
+	case "map", "filter":
+		return call(kind, fn)
+	case "reduce":
+		if initial == nil {
+			return call("reduce", fn)
+		}
+		init, err := toJSValue(vm, initial)
+		if err != nil {
+			return nil, err
+		}
+		return call("reduce", fn, init)
+	case "sort":
+		// 元の配列を変更しないようコピーしてからソートする
+		copied, err := call("slice")
+		if err != nil {
+			return nil, err
+		}
+		sortFn, ok := goja.AssertFunction(copied.ToObject(vm).Get("sort"))
+		if !ok {
+			return nil, fmt.Errorf("data is not an array")
+		}
+		return sortFn(copied, fn)
+	default:
+		return nil, fmt.Errorf("unsupported transform %q", kind)
+	}
+}
+
+// toJSValue はGoの値をJSON経由でJavaScriptのネイティブな値に変換する
+func toJSValue(vm *goja.Runtime, value interface{}) (goja.Value, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return parseJSON(vm, data)
+}
+
+// parseJSON はJSONをJavaScriptのネイティブな値に変換する
+func parseJSON(vm *goja.Runtime, data []byte) (goja.Value, error) {
+	parse, ok := goja.AssertFunction(vm.Get("JSON").ToObject(vm).Get("parse"))
+	if !ok {
+		return nil, fmt.Errorf("JSON.parse is not available")
+	}
+	return parse(goja.Undefined(), vm.ToValue(string(data)))
+}
+
+// fromJSValue はJavaScriptの値をJSON経由でGoの値に変換する
+func fromJSValue(vm *goja.Runtime, value goja.Value, maxBytes int) (interface{}, error) {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil, nil
+	}
+
+	stringify, ok := goja.AssertFunction(vm.Get("JSON").ToObject(vm).Get("stringify"))
+	if !ok {
+		return nil, fmt.Errorf("JSON.stringify is not available")
+	}
+	encoded, err := stringify(goja.Undefined(), value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert script result: %w", err)
+	}
+	if goja.IsUndefined(encoded) {
+		// 関数などJSONに変換できない値
+		return nil, nil
+	}
+
+	s := encoded.String()
+	if maxBytes > 0 && len(s) > maxBytes {
+		return nil, ErrResultTooLarge
+	}
+
+	var result interface{}
+	if err := json.Unmarshal([]byte(s), &result); err != nil {
+		return nil, fmt.Errorf("failed to convert script result: %w", err)
+	}
+	return result, nil
+}
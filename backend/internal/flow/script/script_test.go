@@ -0,0 +1,185 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		vars   map[string]interface{}
+		opts   Options
+		want   interface{}
+	}{
+		{name: "expression", source: "1 + 2", want: float64(3)},
+		{name: "return body", source: "const x = 2;\nreturn x * 3", want: float64(6)},
+		{name: "arrow expression body", source: "data.map(x => x * 2)", vars: map[string]interface{}{"data": []interface{}{1, 2}}, want: []interface{}{float64(2), float64(4)}},
+		{name: "arrow object body", source: "data.map(x => ({ v: x }))", vars: map[string]interface{}{"data": []interface{}{1}}, want: []interface{}{map[string]interface{}{"v": float64(1)}}},
+		{name: "arrow assignment body", source: "let t = 0; data.forEach(x => t += x); t", vars: map[string]interface{}{"data": []interface{}{1, 2, 3}}, want: float64(6)},
+		{name: "arrow sequence body", source: "let t = 0; [1, 2].forEach(x => (t += x, t)); t", want: float64(3)},
+		{name: "nested arrows", source: "(a => b => a + b)(1)(2)", want: float64(3)},
+		{name: "arrow conditional body", source: "[1, 2, 3].filter(x => x > 1 ? true : false).length", want: float64(2)},
+		{name: "arrow template body", source: "[1].map(x => `n${x}`)[0]", want: "n1"},
+		{name: "for loop without block", source: "let n = 0; for (let i = 0; i < 5; i++) n += i; n", want: float64(10)},
+		{name: "for loop with parenthesized body", source: "let n = 0; for (let i = 0; i < 3; i++) (n++); n", want: float64(3)},
+		{name: "for loop with if body", source: "let n = 0; for (let i = 0; i < 4; i++) if ((i % 2)) n++; n", want: float64(2)},
+		{name: "do while without block", source: "let n = 0; do n++; while (n < 3); n", want: float64(3)},
+		{name: "loop body with if else", source: "let n = 0; if (true) for (const x of [1, 2]) if (x > 1) n += x; else n -= 1; n", want: float64(1)},
+		{name: "for in", source: "let k = ''; for (const key in { a: 1, b: 2 }) { k += key } k", want: "ab"},
+		{name: "while", source: "let n = 0; while (n < 4) { n++ } n", want: float64(4)},
+		{name: "function declaration", source: "function f(n) { return n < 2 ? n : f(n - 1) + f(n - 2) }\nreturn f(10)", want: float64(55)},
+		{name: "class and getter", source: "class A { get v() { return 7 } m() { return this.v } }\nreturn new A().m()", want: float64(7)},
+		{name: "generator", source: "function* g() { yield 1; yield 2 }\nreturn [...g()]", want: []interface{}{float64(1), float64(2)}},
+		{name: "array join", source: "[1, null, 'a'].join('-')", want: "1--a"},
+		{name: "array toString", source: "String([1, 2])", want: "1,2"},
+		{name: "array instanceof", source: "[] instanceof Array && new Array(2) instanceof Array && Array.isArray(Array.of(1))", want: true},
+		{name: "array subclass", source: "class L extends Array {}\nreturn new L(3).length", want: float64(3)},
+		{name: "string concatenation", source: "let s = 'a' + 1 + `b${2}`; s += 'c'; s", want: "a1b2c"},
+		{name: "parenthesized operands", source: "const a = 'a', b = 'b'; (a) + (b) + ((a + b)) + (/* c */ a) + b", want: "ababab"},
+		{name: "parenthesized left operands", source: "const a = 'a', b = 'b'; ((a + b)) + (a) + b + (((a) + b) + (a))", want: "abababa"},
+		{name: "parentheses in literals", source: "const a = 'a'; (')' + a + `)${(a) + ')'}`) + (/\\)/.source + a) + '(' /* ) */ + a", want: ")a)a)\\)a(a"},
+		{name: "numeric addition", source: "let n = 1; n += 2; (n) + (1 + 1)", want: float64(5)},
+		{name: "concatenation with comments", source: "'a' /* x */ + // y\n 'b'", want: "ab"},
+		{name: "tagged template", source: "String.raw`a${1}b`", want: "a1b"},
+		{name: "transform map", source: "x => x + 1", vars: map[string]interface{}{"data": []interface{}{1}}, opts: Options{Transform: "map"}, want: []interface{}{float64(2)}},
+		{name: "transform sort", source: "(a, b) => b - a", vars: map[string]interface{}{"data": []interface{}{1, 3, 2}}, opts: Options{Transform: "sort"}, want: []interface{}{float64(3), float64(2), float64(1)}},
+		{name: "transform reduce", source: "(a, x) => a + x", vars: map[string]interface{}{"data": []interface{}{1, 2}}, opts: Options{Transform: "reduce", Initial: 10}, want: float64(13)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.Limits = DefaultLimits
+			got, err := Run(context.Background(), tt.source, tt.vars, opts)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !equalJSON(got, tt.want) {
+				t.Errorf("Run() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunLimits(t *testing.T) {
+	limits := Limits{
+		Timeout:        5 * time.Second,
+		MaxSteps:       10_000,
+		MaxValueLength: 1 << 16,
+		MaxCallStack:   256,
+		MaxInputBytes:  1 << 10,
+		MaxResultBytes: 1 << 10,
+	}
+	tests := []struct {
+		name    string
+		source  string
+		vars    map[string]interface{}
+		wantErr error
+		wantMsg string
+	}{
+		{name: "infinite loop", source: "for (;;) {}", wantErr: ErrStepLimit},
+		{name: "infinite loop without block", source: "let i = 0; while (true) i++", wantErr: ErrStepLimit},
+		{name: "infinite do while", source: "do {} while (true)", wantErr: ErrStepLimit},
+		{name: "exponential recursion", source: "const f = n => n <= 0 ? 0 : f(n - 1) + f(n - 1); f(30)", wantErr: ErrStepLimit},
+		{name: "builtin callbacks", source: "const a = [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]; a.forEach(() => a.forEach(() => a.forEach(() => a.forEach(() => 0))))", wantErr: ErrStepLimit},
+		{name: "string repeat", source: "'a'.repeat(1 << 29)", wantMsg: "maximum length"},
+		{name: "string padStart", source: "'a'.padStart(1e9)", wantMsg: "maximum length"},
+		{name: "array constructor", source: "new Array(1e9).fill(0)", wantMsg: "maximum length"},
+		{name: "array constructor call", source: "Array(1e9)", wantMsg: "maximum length"},
+		{name: "array prototype constructor", source: "[].constructor(1e9)", wantMsg: "maximum length"},
+		{name: "sparse array fill", source: "const a = []; a.length = 1e9; a.fill(0)", wantMsg: "maximum length"},
+		{name: "sparse array spread", source: "const a = []; a.length = 1e9; [...a.keys()]", wantMsg: "maximum length"},
+		{name: "array from length", source: "Array.from({ length: 1e9 })", wantMsg: "maximum length"},
+		{name: "join of repeated strings", source: "const s = 'a'.repeat(1 << 15); new Array(1000).fill(s).join('')", wantMsg: "maximum length"},
+		{name: "typed array removed", source: "new Uint8Array(1e9)", wantMsg: "Uint8Array"},
+		{name: "eval disabled", source: "eval('1')", wantMsg: "dynamic code evaluation"},
+		{name: "function constructor disabled", source: "(function () {}).constructor('for(;;){}')()", wantMsg: "dynamic code evaluation"},
+		{name: "reserved identifier", source: "let __flowcore_step = () => {}; for (;;) {}", wantErr: errReservedName},
+		{name: "reserved identifier escaped", source: "let \\u005f_flowcore_step = 1", wantErr: errReservedName},
+		{name: "step function is read only", source: "globalThis.__flowcore_step = () => {}", wantErr: errReservedName},
+		{name: "length function is reserved", source: "globalThis['__flowcore_' + 'length'] = s => s", wantMsg: "__flowcore_length"},
+		{name: "string concatenation", source: "let s = 'x'; for (let i = 0; i < 26; i++) s += s; s.length", wantMsg: "maximum length"},
+		{name: "string concatenation with plus", source: "let s = 'x'; for (let i = 0; i < 26; i++) s = s + s; s.length", wantMsg: "maximum length"},
+		{name: "parenthesized concatenation", source: "let s = 'x'; for (let i = 0; i < 26; i++) s = (s) + (s); s.length", wantMsg: "maximum length"},
+		{name: "concatenation in expression", source: "let s = 'x'; for (let i = 0; i < 26; i++) s = [s + s][0]; s.length", wantMsg: "maximum length"},
+		{name: "template literal", source: "let s = 'x'; for (let i = 0; i < 26; i++) s = `${s}${s}`; s.length", wantMsg: "maximum length"},
+		{name: "concatenation in arrow body", source: "let s = 'x'; const d = v => v + v; for (let i = 0; i < 26; i++) s = d(s); s.length", wantMsg: "maximum length"},
+		{name: "input too large", source: "data", vars: map[string]interface{}{"data": strings.Repeat("a", 2<<10)}, wantErr: ErrInputTooLarge},
+		{name: "result too large", source: "'a'.repeat(2 << 10)", wantErr: ErrResultTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Run(context.Background(), tt.source, tt.vars, Options{Limits: limits})
+			if err == nil {
+				t.Fatal("Run() error = nil")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantMsg != "" && !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("Run() error = %v, want message containing %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	limits := DefaultLimits
+	limits.Timeout = 50 * time.Millisecond
+	limits.MaxSteps = 0
+	_, err := Run(context.Background(), "for (;;) {}", nil, Options{Limits: limits})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Run() error = %v, want %v", err, ErrTimeout)
+	}
+}
+
+// TestRunStepsArePerRuntime は並行して実行される他のスクリプトが制限に影響しないことを確認する
+func TestRunStepsArePerRuntime(t *testing.T) {
+	limits := DefaultLimits
+	limits.MaxSteps = 1000
+	done := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		go func() {
+			_, err := Run(context.Background(), "let n = 0; for (let i = 0; i < 900; i++) { n += i } n", nil, Options{Limits: limits})
+			done <- err
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		if err := <-done; err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	}
+}
+
+func equalJSON(a, b interface{}) bool {
+	switch av := a.(type) {
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equalJSON(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k := range av {
+			if !equalJSON(av[k], bv[k]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
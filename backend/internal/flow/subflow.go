@@ -0,0 +1,266 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// MaxSubflowDepth はサブフローの呼び出しの最大の深さ
+const MaxSubflowDepth = 8
+
+// ErrSubflowDepth はサブフローの呼び出しが最大の深さを超えた場合のエラー（再帰呼び出しの防止）
+var ErrSubflowDepth = errors.New("subflow call depth exceeded")
+
+var subflowNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]{0,99}$`)
+
+// ValidSubflowName はサブフロー名として使用できるかどうかを返す
+func ValidSubflowName(name string) bool {
+	return subflowNamePattern.MatchString(name)
+}
+
+// ValidateSubflow はサブフローの定義を検証し、問題があれば*ValidationErrorsを返す
+// サブフローはノードの本体と同様にStart・Responseノードを持たず、
+// Itemノードが宣言された入力（出力ピンのラベル）を出力し、Collectノードの入力（ピンのラベル）が宣言された出力になる
+func ValidateSubflow(f *models.Flow, inputs, outputs []models.SubflowParam, registry *Registry) error {
+	v := &ValidationErrors{}
+	validateFlow(v, f, registry, "", true)
+
+	declared := func(kind string, params []models.SubflowParam) map[string]models.SubflowParam {
+		byName := make(map[string]models.SubflowParam, len(params))
+		for i, p := range params {
+			switch {
+			case p.Name == "":
+				v.flow("%s[%d]: name is required", kind, i)
+			case byName[p.Name].Name != "":
+				v.flow("%s[%d]: duplicate name %q", kind, i, p.Name)
+			}
+			if p.DataType == "" {
+				p.DataType = "any"
+			}
+			byName[p.Name] = p
+		}
+		return byName
+	}
+	inputParams := declared("inputs", inputs)
+	outputParams := declared("outputs", outputs)
+
+	var collect *models.Node
+	for i := range f.Nodes {
+		node := &f.Nodes[i]
+		switch node.Type {
+		case "item":
+			for _, pin := range node.Pins {
+				if pin.Type != "output" {
+					continue
+				}
+				param, ok := inputParams[pin.Label]
+				if !ok {
+					v.pin(pin.ID, "%q is not a declared input", pin.Label)
+				} else if !CompatibleDataTypes(param.DataType, pin.DataType) {
+					v.pin(pin.ID, "incompatible data types: %s → %s", param.DataType, pin.DataType)
+				}
+			}
+		case "collect":
+			collect = node
+		}
+	}
+
+	if collect == nil {
+		if len(outputs) > 0 {
+			v.flow("a collect node is required to return the declared outputs")
+		}
+	} else {
+		labels := map[string]bool{}
+		for _, pin := range collect.Pins {
+			if pin.Type != "input" {
+				continue
+			}
+			labels[pin.Label] = true
+			param, ok := outputParams[pin.Label]
+			if !ok {
+				v.pin(pin.ID, "%q is not a declared output", pin.Label)
+			} else if !CompatibleDataTypes(pin.DataType, param.DataType) {
+				v.pin(pin.ID, "incompatible data types: %s → %s", pin.DataType, param.DataType)
+			}
+		}
+		for _, p := range outputs {
+			if p.Name != "" && !labels[p.Name] {
+				v.node(collect.ID, "declared output %q has no input pin", p.Name)
+			}
+		}
+	}
+
+	if !v.empty() {
+		return v
+	}
+	return nil
+}
+
+// SubflowCall はフロー内のサブフローの呼び出し
+type SubflowCall struct {
+	NodeID  string
+	Name    string
+	Version int
+}
+
+// SubflowCalls はフロー（ノードの本体を含む）のサブフローの呼び出しを返す
+func SubflowCalls(f *models.Flow) []SubflowCall {
+	var calls []SubflowCall
+	for i := range f.Nodes {
+		node := &f.Nodes[i]
+		if node.Type == "subflow" {
+			name, _ := node.Config["subflow"].(string)
+			version, _ := node.Config["version"].(float64)
+			calls = append(calls, SubflowCall{NodeID: node.ID, Name: name, Version: int(version)})
+		}
+		if node.Body != nil {
+			calls = append(calls, SubflowCalls(node.Body)...)
+		}
+	}
+	return calls
+}
+
+// SubflowLookup はサブフローの指定バージョンを取得する（存在しない場合はnil, nil）
+type SubflowLookup func(name string, version int) (*models.SubflowVersion, error)
+
+// ValidateSubflowCalls はフロー（ノードの本体を含む）のサブフローの呼び出しを、呼び出すバージョンの宣言と照合する
+// 参照先が存在しない場合や、入力・出力ピンが宣言と一致しない場合は*ValidationErrorsを返す
+// lookupが失敗した場合はそのエラーを返す
+func ValidateSubflowCalls(f *models.Flow, lookup SubflowLookup) error {
+	v := &ValidationErrors{}
+	if err := validateSubflowCalls(v, f, lookup); err != nil {
+		return err
+	}
+	if !v.empty() {
+		return v
+	}
+	return nil
+}
+
+func validateSubflowCalls(v *ValidationErrors, f *models.Flow, lookup SubflowLookup) error {
+	connected := map[string]bool{}
+	for _, conn := range f.Connections {
+		connected[conn.To.PinID] = true
+	}
+
+	for i := range f.Nodes {
+		node := &f.Nodes[i]
+		if node.Body != nil {
+			if err := validateSubflowCalls(v, node.Body, lookup); err != nil {
+				return err
+			}
+		}
+		if node.Type != "subflow" {
+			continue
+		}
+
+		name, _ := node.Config["subflow"].(string)
+		version, _ := node.Config["version"].(float64)
+		if name == "" || version < 1 {
+			continue // 設定の形式はノードタイプの検証で報告される
+		}
+		sf, err := lookup(name, int(version))
+		if err != nil {
+			return err
+		}
+		if sf == nil {
+			v.node(node.ID, "subflow %q version %d does not exist", name, int(version))
+			continue
+		}
+
+		params := func(list []models.SubflowParam) map[string]models.SubflowParam {
+			byName := make(map[string]models.SubflowParam, len(list))
+			for _, p := range list {
+				if p.DataType == "" {
+					p.DataType = "any"
+				}
+				byName[p.Name] = p
+			}
+			return byName
+		}
+		inputs, outputs := params(sf.Inputs), params(sf.Outputs)
+
+		provided := map[string]bool{}
+		for _, pin := range node.Pins {
+			switch pin.Type {
+			case "input":
+				param, ok := inputs[pin.Label]
+				if !ok {
+					v.pin(pin.ID, "%q is not an input of subflow %q", pin.Label, name)
+					continue
+				}
+				if !CompatibleDataTypes(pin.DataType, param.DataType) {
+					v.pin(pin.ID, "incompatible data types: %s → %s", pin.DataType, param.DataType)
+				}
+				if connected[pin.ID] {
+					provided[pin.Label] = true
+				}
+			case "output":
+				// ラベルが宣言された出力と一致しないピン（エラー出力ピンを除く）には出力全体を出力する
+				if param, ok := outputs[pin.Label]; ok && !CompatibleDataTypes(param.DataType, pin.DataType) {
+					v.pin(pin.ID, "incompatible data types: %s → %s", param.DataType, pin.DataType)
+				}
+			}
+		}
+		for _, p := range sf.Inputs {
+			if p.Required && !provided[p.Name] {
+				v.node(node.ID, "required input %q of subflow %q is not connected", p.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// RunSubflow はサブフローを実行し、宣言された出力（名前 → 値）を返す
+// inputsはサブフローのItemノードが出力する値（入力名 → 値）
+// サブフローのノードもフロー全体のステップ数・実行時間の上限に含まれ、トレースはノードのiterationsに記録される
+func (ec *ExecutionContext) RunSubflow(ctx context.Context, sf *models.SubflowVersion, inputs map[string]interface{}) (map[string]interface{}, error) {
+	if ec.depth >= MaxSubflowDepth {
+		return nil, ErrSubflowDepth
+	}
+	if sf.Flow == nil {
+		return nil, fmt.Errorf("subflow %q version %d has no flow", sf.Name, sf.Version)
+	}
+	g, err := newGraph(sf.Flow)
+	if err != nil {
+		return nil, err
+	}
+	if inputs == nil {
+		inputs = map[string]interface{}{}
+	}
+
+	child := ec.newChild(ctx, ec.querier, ec.inTx, ec.queryMu, inputs)
+	child.depth = ec.depth + 1
+
+	trace, err := ec.engine.runGraph(child, g)
+	if ec.trace {
+		ec.recordIteration(0, trace)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Collectノードの入力ピンが1つの場合、結果はそのピンの値になる
+	outputs := map[string]interface{}{}
+	for _, node := range g.order {
+		if node.Type != "collect" {
+			continue
+		}
+		var labels []string
+		for _, pin := range node.Pins {
+			if pin.Type == "input" {
+				labels = append(labels, pin.Label)
+			}
+		}
+		if len(labels) == 1 {
+			outputs[labels[0]] = child.state.collected
+		} else if fields, ok := child.state.collected.(map[string]interface{}); ok {
+			outputs = fields
+		}
+	}
+	return outputs, nil
+}
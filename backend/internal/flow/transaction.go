@@ -0,0 +1,128 @@
+package flow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// IsolationLevels はトランザクションノードで指定できる分離レベル
+var IsolationLevels = map[string]sql.IsolationLevel{
+	"read_committed":  sql.LevelReadCommitted,
+	"repeatable_read": sql.LevelRepeatableRead,
+	"serializable":    sql.LevelSerializable,
+}
+
+// TxOptions はトランザクション内で本体を実行する際のオプション
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+	// MaxRetries はシリアライゼーション失敗・デッドロックの場合に本体を再実行する最大回数
+	MaxRetries int
+}
+
+// retryBackoff は再実行までの待ち時間（試行回数に比例して延ばす）
+const retryBackoff = 10 * time.Millisecond
+
+// RunBodyInTx はノードの本体を1つのトランザクション内で実行し、Collectノードが受け取った値を返す
+// 本体が成功した場合はコミットし、いずれかのノードが失敗した場合はロールバックする
+// 既にトランザクション内（テスト実行・入れ子のトランザクション）の場合はセーブポイントを使用し、
+// 分離レベルと再実行は外側のトランザクションに従う
+// トレースには試行ごとの本体の実行が記録される
+func (ec *ExecutionContext) RunBodyInTx(body *models.Flow, vars map[string]interface{}, opts TxOptions) (interface{}, error) {
+	if ec.inTx {
+		return ec.runBodyInSavepoint(body, vars)
+	}
+
+	for attempt := 0; ; attempt++ {
+		result, err := ec.runBodyInTx(body, attempt, vars, opts)
+		if err == nil || attempt >= opts.MaxRetries || !database.IsSerializationFailure(err) {
+			return result, err
+		}
+
+		select {
+		case <-ec.ctx.Done():
+			return nil, contextError(ec.ctx)
+		case <-time.After(time.Duration(attempt+1) * retryBackoff):
+		}
+	}
+}
+
+func (ec *ExecutionContext) runBodyInTx(body *models.Flow, attempt int, vars map[string]interface{}, opts TxOptions) (interface{}, error) {
+	tx, err := ec.db.BeginTx(ec.ctx, &sql.TxOptions{Isolation: opts.Isolation})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := ec.runBody(ec.ctx, body, attempt, vars, tx, true, &sync.Mutex{})
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// runBodyInSavepoint は外側のトランザクションのセーブポイント内で本体を実行する
+func (ec *ExecutionContext) runBodyInSavepoint(body *models.Flow, vars map[string]interface{}) (interface{}, error) {
+	sp, err := ec.beginSavepoint()
+	if err != nil {
+		return nil, err
+	}
+	result, err := ec.runBody(ec.ctx, body, 0, vars, ec.querier, true, ec.queryMu)
+	if spErr := sp.end(err); spErr != nil {
+		return nil, spErr
+	}
+	return result, err
+}
+
+// savepoint はトランザクション内で一部の処理を取り消せるようにするセーブポイント
+type savepoint struct {
+	ec      *ExecutionContext
+	name    string
+	queryMu *sync.Mutex
+	unlock  func()
+}
+
+// beginSavepoint は外側のトランザクションにセーブポイントを作成する
+// 並列に実行される他のノードのクエリがセーブポイントに含まれないよう、endまでクエリのロックを保持し、
+// その間はecのクエリに新しいロックを使用させる
+func (ec *ExecutionContext) beginSavepoint() (*savepoint, error) {
+	unlock := ec.LockQuerier()
+	name := fmt.Sprintf("flowcore_sp_%d", ec.savepoints.Add(1))
+	if _, err := ec.querier.ExecContext(ec.ctx, "SAVEPOINT "+name); err != nil {
+		unlock()
+		return nil, fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	sp := &savepoint{ec: ec, name: name, queryMu: ec.queryMu, unlock: unlock}
+	ec.queryMu = &sync.Mutex{}
+	return sp, nil
+}
+
+// end は処理が成功した場合（errがnil）はセーブポイントを解放し、失敗した場合はセーブポイントまでロールバックする
+// 解放・ロールバックに失敗した場合はトランザクションを続行できないため、そのエラーを返す
+func (sp *savepoint) end(err error) error {
+	ec := sp.ec
+	defer func() {
+		ec.queryMu = sp.queryMu
+		sp.unlock()
+	}()
+
+	if err != nil {
+		// 実行時間の上限でキャンセルされた場合は外側のトランザクションごとロールバックされる
+		if _, rbErr := ec.querier.ExecContext(context.WithoutCancel(ec.ctx), "ROLLBACK TO SAVEPOINT "+sp.name); rbErr != nil {
+			return fmt.Errorf("%w (failed to roll back to savepoint: %v)", err, rbErr)
+		}
+		return nil
+	}
+	if _, err := ec.querier.ExecContext(ec.ctx, "RELEASE SAVEPOINT "+sp.name); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,489 @@
+package flow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// ValidationErrors はフロー検証エラーを表す
+// エディタで該当箇所をハイライトできるよう、ノード・ピン・接続のIDごとにエラーをまとめる
+type ValidationErrors struct {
+	Flow        []string            `json:"flow,omitempty"`
+	Nodes       map[string][]string `json:"nodes,omitempty"`
+	Pins        map[string][]string `json:"pins,omitempty"`
+	Connections map[string][]string `json:"connections,omitempty"`
+}
+
+func (v *ValidationErrors) Error() string {
+	var parts []string
+	parts = append(parts, v.Flow...)
+	for _, group := range []map[string][]string{v.Nodes, v.Pins, v.Connections} {
+		for _, id := range sortedIDs(group) {
+			for _, msg := range group[id] {
+				parts = append(parts, id+": "+msg)
+			}
+		}
+	}
+	return "invalid flow: " + strings.Join(parts, "; ")
+}
+
+func (v *ValidationErrors) empty() bool {
+	return len(v.Flow) == 0 && len(v.Nodes) == 0 && len(v.Pins) == 0 && len(v.Connections) == 0
+}
+
+func (v *ValidationErrors) flow(format string, args ...interface{}) {
+	v.Flow = append(v.Flow, fmt.Sprintf(format, args...))
+}
+
+func (v *ValidationErrors) node(id, format string, args ...interface{}) {
+	if v.Nodes == nil {
+		v.Nodes = map[string][]string{}
+	}
+	v.Nodes[id] = append(v.Nodes[id], fmt.Sprintf(format, args...))
+}
+
+func (v *ValidationErrors) pin(id, format string, args ...interface{}) {
+	if v.Pins == nil {
+		v.Pins = map[string][]string{}
+	}
+	v.Pins[id] = append(v.Pins[id], fmt.Sprintf(format, args...))
+}
+
+func (v *ValidationErrors) connection(id, format string, args ...interface{}) {
+	if v.Connections == nil {
+		v.Connections = map[string][]string{}
+	}
+	v.Connections[id] = append(v.Connections[id], fmt.Sprintf(format, args...))
+}
+
+// acceptedDataTypes は入力ピンのデータ型ごとに、同じ型とany以外で接続可能な出力ピンのデータ型
+var acceptedDataTypes = map[string][]string{
+	"object": {"array", "trigger", ErrorDataType},
+	"number": {"integer"},
+	"string": {"uuid", "timestamp"},
+}
+
+// CompatibleDataTypes は出力ピンから入力ピンへ接続可能なデータ型かどうかを返す
+func CompatibleDataTypes(from, to string) bool {
+	if from == to || from == "any" || to == "any" {
+		return true
+	}
+	// trigger型の入力は実行順序のみを表すため、任意の出力を受け付ける
+	if to == "trigger" {
+		return true
+	}
+	for _, accepted := range acceptedDataTypes[to] {
+		if from == accepted {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate はフロー定義の構造を検証し、問題があれば*ValidationErrorsを返す
+func Validate(f *models.Flow, registry *Registry) error {
+	v := &ValidationErrors{}
+	validateFlow(v, f, registry, "", false)
+	if !v.empty() {
+		return v
+	}
+	return nil
+}
+
+// validateFlow はフロー、またはノードの本体・サブフロー（body）のサブグラフを検証する
+// 本体ではStart・Responseノードの代わりにItem・Collectノードを使用する
+// ownerはノードの本体の場合に本体を持つノードのID（サブフローの場合は空）
+func validateFlow(v *ValidationErrors, f *models.Flow, registry *Registry, owner string, body bool) {
+	// フロー全体のエラーは、本体の場合は本体を持つノードのエラーとして記録する
+	flowError := func(format string, args ...interface{}) {
+		if owner == "" {
+			v.flow(format, args...)
+		} else {
+			v.node(owner, "body: "+format, args...)
+		}
+	}
+
+	if len(f.Nodes) == 0 {
+		flowError("at least one node is required")
+		return
+	}
+
+	nodes := make(map[string]*models.Node, len(f.Nodes))
+	pins := make(map[string]*models.Pin)
+	pinOwner := make(map[string]string)
+	var starts, responses, items, collects []string
+
+	for i := range f.Nodes {
+		node := &f.Nodes[i]
+		if node.ID == "" {
+			v.flow("node at index %d has no id", i)
+			continue
+		}
+		if _, exists := nodes[node.ID]; exists {
+			v.node(node.ID, "duplicate node id")
+			continue
+		}
+		nodes[node.ID] = node
+
+		switch node.Type {
+		case "start":
+			starts = append(starts, node.ID)
+		case "response":
+			responses = append(responses, node.ID)
+		case "item":
+			items = append(items, node.ID)
+		case "collect":
+			collects = append(collects, node.ID)
+		}
+
+		nodeType, ok := registry.Lookup(node.Type)
+		if !ok {
+			v.node(node.ID, "unknown node type %q", node.Type)
+		} else {
+			validateConfig(v, node, nodeType.ConfigSchema)
+			validateExpressions(v, node, nodeType.ConfigSchema)
+			if nodeType.Validate != nil {
+				for _, msg := range nodeType.Validate(node) {
+					v.node(node.ID, "%s", msg)
+				}
+			}
+
+			switch {
+			case nodeType.HasBody && (node.Body == nil || len(node.Body.Nodes) == 0):
+				v.node(node.ID, "body is required")
+			case nodeType.HasBody:
+				validateFlow(v, node.Body, registry, node.ID, true)
+			case node.Body != nil:
+				v.node(node.ID, "node type %q does not have a body", node.Type)
+			}
+		}
+
+		for j := range node.Pins {
+			pin := &node.Pins[j]
+			if pin.ID == "" {
+				v.node(node.ID, "pin at index %d has no id", j)
+				continue
+			}
+			if _, exists := pins[pin.ID]; exists {
+				v.pin(pin.ID, "duplicate pin id")
+				continue
+			}
+			pins[pin.ID] = pin
+			pinOwner[pin.ID] = node.ID
+
+			if pin.NodeID != "" && pin.NodeID != node.ID {
+				v.pin(pin.ID, "pin belongs to node %q but node_id is %q", node.ID, pin.NodeID)
+			}
+			if pin.Type != "input" && pin.Type != "output" {
+				v.pin(pin.ID, "pin type must be input or output")
+			}
+			if pin.DataType == "" {
+				v.pin(pin.ID, "data_type is required")
+			}
+			if pin.DataType == ErrorDataType {
+				if pin.Type != "output" {
+					v.pin(pin.ID, "data_type error can only be used on output pins")
+				} else if errPin := errorPin(node); errPin != pin {
+					v.pin(pin.ID, "node can have at most one error output pin")
+				}
+			}
+		}
+	}
+
+	if !body {
+		classes := make([]string, 0, len(f.ErrorResponses))
+		for class := range f.ErrorResponses {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		for _, class := range classes {
+			mapping := f.ErrorResponses[class]
+			if !IsErrorClass(class) {
+				v.flow("error_responses: unknown error class %q", class)
+			}
+			if mapping.StatusCode != 0 && (mapping.StatusCode < 400 || mapping.StatusCode > 599) {
+				v.flow("error_responses: status_code for %q must be between 400 and 599", class)
+			}
+		}
+	} else if len(f.ErrorResponses) > 0 {
+		flowError("error_responses can only be set on the flow")
+	}
+
+	if !body {
+		switch len(starts) {
+		case 0:
+			v.flow("flow must have exactly one start node")
+		case 1:
+		default:
+			for _, id := range starts {
+				v.node(id, "flow must have exactly one start node")
+			}
+		}
+		if len(responses) == 0 {
+			v.flow("flow must have at least one response node")
+		}
+		for _, id := range append(items, collects...) {
+			v.node(id, "node can only be used inside a body")
+		}
+	} else {
+		for _, id := range append(starts, responses...) {
+			v.node(id, "node cannot be used inside a body")
+		}
+		for _, list := range [][]string{items, collects} {
+			if len(list) > 1 {
+				for _, id := range list {
+					v.node(id, "body can have at most one %s node", nodes[id].Type)
+				}
+			}
+		}
+	}
+
+	// 接続を検証
+	// 1つの入力ピンへの複数の接続は分岐の合流としてのみ許可する（グラフ構築後に検証）
+	incoming := map[string][]string{}
+	sources := map[string]bool{}
+	var valid []models.Connection
+	for i, conn := range f.Connections {
+		id := conn.ID
+		if id == "" {
+			id = fmt.Sprintf("#%d", i)
+		}
+		ok := true
+
+		fromPin := checkPinRef(v, id, "from", conn.From, nodes, pins, pinOwner)
+		toPin := checkPinRef(v, id, "to", conn.To, nodes, pins, pinOwner)
+		if fromPin == nil || toPin == nil {
+			continue
+		}
+
+		if fromPin.Type != "output" {
+			v.connection(id, "source pin %q must be an output pin", fromPin.ID)
+			ok = false
+		}
+		if toPin.Type != "input" {
+			v.connection(id, "target pin %q must be an input pin", toPin.ID)
+			ok = false
+		}
+		if conn.From.NodeID == conn.To.NodeID {
+			v.connection(id, "a node cannot be connected to itself")
+			ok = false
+		}
+		if !CompatibleDataTypes(fromPin.DataType, toPin.DataType) {
+			v.connection(id, "incompatible data types: %s → %s", fromPin.DataType, toPin.DataType)
+			ok = false
+		}
+		if sources[fromPin.ID+"→"+toPin.ID] {
+			v.connection(id, "duplicate connection from %q to %q", fromPin.ID, toPin.ID)
+			ok = false
+		} else {
+			sources[fromPin.ID+"→"+toPin.ID] = true
+		}
+
+		if ok {
+			valid = append(valid, conn)
+			incoming[toPin.ID] = append(incoming[toPin.ID], conn.From.NodeID)
+		}
+	}
+
+	if !v.empty() {
+		return
+	}
+
+	// 循環と到達可能性を検証
+	g, err := newGraph(&models.Flow{Nodes: f.Nodes, Connections: valid})
+	if err != nil {
+		flowError("%v", err)
+		return
+	}
+	validateNodeReferences(v, f, g, registry)
+	if body {
+		validateMerges(v, f, g, registry, incoming)
+		return
+	}
+
+	reachable := g.reachableFrom(starts[0])
+	reached := false
+	for _, id := range responses {
+		if reachable[id] {
+			reached = true
+			break
+		}
+	}
+	if !reached {
+		v.flow("no response node is reachable from the start node")
+	}
+
+	validateMerges(v, f, g, registry, incoming)
+
+	// Responseノードが選択した出力ピンは、そのノードより前に実行されるノードのものであること
+	for _, id := range responses {
+		fields, _ := nodes[id].Config["selectedFields"].([]interface{})
+		if len(fields) == 0 {
+			continue
+		}
+		ancestors := g.ancestorsOf(id)
+		for _, field := range fields {
+			pinID, _ := field.(string)
+			pin, ok := pins[pinID]
+			if !ok || pin.Type != "output" {
+				continue
+			}
+			if !ancestors[pinOwner[pinID]] {
+				v.node(id, "selected pin %q is not connected upstream of this node", pinID)
+			}
+		}
+	}
+
+}
+
+// validateMerges は合流する接続の接続元が、いずれも分岐ノードの下流（または分岐ノード自身）であることを検証する
+func validateMerges(v *ValidationErrors, f *models.Flow, g *graph, registry *Registry, incoming map[string][]string) {
+	for _, node := range f.Nodes {
+		for _, pin := range node.Pins {
+			if len(incoming[pin.ID]) < 2 {
+				continue
+			}
+			for _, from := range incoming[pin.ID] {
+				if !g.afterBranch(from, registry) {
+					v.pin(pin.ID, "input pin has multiple connections, but %q is not on a conditional branch", from)
+					break
+				}
+			}
+		}
+	}
+}
+
+// checkPinRef は接続が参照するノードとピンが存在するか検証する
+func checkPinRef(v *ValidationErrors, connID, side string, ref models.PinRef, nodes map[string]*models.Node, pins map[string]*models.Pin, pinOwner map[string]string) *models.Pin {
+	if _, ok := nodes[ref.NodeID]; !ok {
+		v.connection(connID, "%s references unknown node %q", side, ref.NodeID)
+		return nil
+	}
+	pin, ok := pins[ref.PinID]
+	if !ok {
+		v.connection(connID, "%s references unknown pin %q", side, ref.PinID)
+		return nil
+	}
+	if pinOwner[ref.PinID] != ref.NodeID {
+		v.connection(connID, "%s pin %q does not belong to node %q", side, ref.PinID, ref.NodeID)
+		return nil
+	}
+	return pin
+}
+
+// validateConfig はノード設定を設定スキーマと照合する
+func validateConfig(v *ValidationErrors, node *models.Node, schema []models.ConfigField) {
+	for _, field := range schema {
+		value, ok := node.Config[field.Key]
+		if !ok || value == nil || value == "" {
+			if field.Required {
+				v.node(node.ID, "config %q is required", field.Key)
+			}
+			continue
+		}
+
+		// 式は実行時に評価されるため、型と選択肢は検証しない
+		if !field.Raw && IsTemplate(value) {
+			continue
+		}
+		if !matchesConfigType(value, field.Type) {
+			v.node(node.ID, "config %q must be of type %s", field.Key, field.Type)
+			continue
+		}
+		if s, isString := value.(string); isString && len(field.Enum) > 0 && !contains(field.Enum, s) {
+			v.node(node.ID, "config %q must be one of %s", field.Key, strings.Join(field.Enum, ", "))
+		}
+	}
+}
+
+func matchesConfigType(value interface{}, configType string) bool {
+	switch configType {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	default:
+		return true
+	}
+}
+
+// reachableFrom は指定ノードから到達可能なノードIDの集合を返す
+func (g *graph) reachableFrom(nodeID string) map[string]bool {
+	visited := map[string]bool{nodeID: true}
+	queue := []string{nodeID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, conn := range g.outgoing[id] {
+			if !visited[conn.To.NodeID] {
+				visited[conn.To.NodeID] = true
+				queue = append(queue, conn.To.NodeID)
+			}
+		}
+	}
+	return visited
+}
+
+// ancestorsOf は指定ノードより前に実行されるノード（接続を遡って到達できるノード）のIDの集合を返す
+func (g *graph) ancestorsOf(nodeID string) map[string]bool {
+	visited := map[string]bool{}
+	queue := []string{nodeID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, conn := range g.incoming[id] {
+			if !visited[conn.From.NodeID] {
+				visited[conn.From.NodeID] = true
+				queue = append(queue, conn.From.NodeID)
+			}
+		}
+	}
+	return visited
+}
+
+// afterBranch はノードが分岐ノード（エラー出力ピンを持つノードを含む）自身、またはその下流にあるかどうかを返す
+func (g *graph) afterBranch(nodeID string, registry *Registry) bool {
+	ancestors := g.ancestorsOf(nodeID)
+	ancestors[nodeID] = true
+	for id := range ancestors {
+		if errorPin(g.nodes[id]) != nil {
+			return true
+		}
+		if nodeType, ok := registry.Lookup(g.nodes[id].Type); ok && nodeType.Branching {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedIDs(m map[string][]string) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
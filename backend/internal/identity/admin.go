@@ -0,0 +1,343 @@
+package identity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/necorox/FlowCore/backend/internal/authz"
+	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// adminSessionTokenBytes は管理者のセッショントークンの乱数のバイト数
+const adminSessionTokenBytes = 32
+
+// adminPasswordPolicy は管理者のパスワードのポリシー（エンドユーザーの認証設定には従わない）
+var adminPasswordPolicy = models.AuthConfig{MinPasswordLength: 12}
+
+var (
+	// ErrNoAdmins は管理者が1人も存在せず、最初のownerの作成に必要な設定もない場合のエラー
+	ErrNoAdmins = errors.New("no admin users exist: set ADMIN_EMAIL and ADMIN_PASSWORD to create the first owner")
+	// ErrLastOwner は最後のownerを削除・降格しようとした場合のエラー
+	ErrLastOwner = errors.New("at least one owner is required")
+)
+
+// AdminConfig は管理者の認証の設定
+type AdminConfig struct {
+	SessionTTL time.Duration // セッショントークンの有効期間
+}
+
+// AdminService はAdmin APIの管理者アカウントとセッションを管理する
+// セッショントークンは不透明なトークンとしてハッシュのみを保存し、ロールはリクエストごとにMetaDBから読み込む
+type AdminService struct {
+	db     *database.DB
+	config AdminConfig
+}
+
+// NewAdminService は新しいAdminServiceを作成する（MASTER_KEYは不要）
+func NewAdminService(db *database.DB, config AdminConfig) *AdminService {
+	return &AdminService{db: db, config: config}
+}
+
+// Bootstrap は管理者が1人も存在しない場合に、指定したメールアドレス・パスワードのownerを作成する
+// 管理者が存在する場合は何もせずfalseを返す
+func (s *AdminService) Bootstrap(ctx context.Context, email, password string) (bool, error) {
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM admin_users)`).Scan(&exists); err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+	if email == "" || password == "" {
+		return false, ErrNoAdmins
+	}
+	if _, err := s.Create(ctx, email, password, models.AdminRoleOwner); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Login はメールアドレスとパスワードを検証し、セッショントークンを発行する
+func (s *AdminService) Login(ctx context.Context, email, password string) (*models.AdminLoginResponse, error) {
+	var hash string
+	admin, err := scanAdmin(s.db.QueryRowContext(ctx, `
+		SELECT `+adminColumns+`, a.password_hash
+		FROM admin_users a
+		WHERE a.email = $1
+	`, normalizeEmail(email)), &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		// 管理者の有無が応答時間から分からないよう、存在しない場合もハッシュを比較する
+		checkPassword(dummyPasswordHash(), password)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !checkPassword(hash, password) {
+		return nil, ErrInvalidCredentials
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// 期限切れのセッションはログイン時に削除する
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM admin_sessions WHERE admin_user_id = $1 AND expires_at <= NOW()
+	`, admin.ID); err != nil {
+		return nil, err
+	}
+	token := randomToken(adminSessionTokenBytes)
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO admin_sessions (admin_user_id, token_hash, expires_at)
+		VALUES ($1, $2, NOW() + make_interval(secs => $3))
+	`, admin.ID, hashToken(token), s.config.SessionTTL.Seconds()); err != nil {
+		return nil, err
+	}
+	admin, err = scanAdmin(tx.QueryRowContext(ctx, `
+		UPDATE admin_users a SET last_login_at = NOW()
+		WHERE a.id = $1
+		RETURNING `+adminColumns+`
+	`, admin.ID))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &models.AdminLoginResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(s.config.SessionTTL.Seconds()),
+		Admin:       admin,
+	}, nil
+}
+
+// Logout はセッショントークンを失効させる（存在しないトークンの場合もエラーにしない）
+func (s *AdminService) Logout(ctx context.Context, token string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM admin_sessions WHERE token_hash = $1`, hashToken(token))
+	return err
+}
+
+// Authenticate はセッショントークンを検証し、呼び出し元を返す（Admin APIとRuntime APIのadmin認証のauthz.Verifier）
+// 呼び出し元のRolesには、管理者のロールとその下位のロールを設定する
+func (s *AdminService) Authenticate(ctx context.Context, token string) (*models.Principal, error) {
+	admin, err := scanAdmin(s.db.QueryRowContext(ctx, `
+		SELECT `+adminColumns+`
+		FROM admin_sessions s
+		JOIN admin_users a ON a.id = s.admin_user_id
+		WHERE s.token_hash = $1 AND s.expires_at > NOW()
+	`, hashToken(token)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %v", authz.ErrUnauthenticated, ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &models.Principal{
+		Type:    models.AuthAdmin,
+		Subject: admin.ID,
+		Roles:   models.AdminRoleGrants(admin.Role),
+		Email:   admin.Email,
+	}, nil
+}
+
+// List は管理者の一覧を返す
+func (s *AdminService) List(ctx context.Context) ([]models.AdminUser, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+adminColumns+`
+		FROM admin_users a
+		ORDER BY a.created_at ASC, a.id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	admins := []models.AdminUser{}
+	for rows.Next() {
+		admin, err := scanAdmin(rows)
+		if err != nil {
+			return nil, err
+		}
+		admins = append(admins, *admin)
+	}
+	return admins, rows.Err()
+}
+
+// Get は管理者を返す（存在しない場合はsql.ErrNoRows）
+func (s *AdminService) Get(ctx context.Context, id string) (*models.AdminUser, error) {
+	return scanAdmin(s.db.QueryRowContext(ctx, `
+		SELECT `+adminColumns+`
+		FROM admin_users a
+		WHERE a.id = $1
+	`, id))
+}
+
+// Create は管理者を作成する
+func (s *AdminService) Create(ctx context.Context, email, password, role string) (*models.AdminUser, error) {
+	email = normalizeEmail(email)
+	errs := ValidationError{}
+	if msg := validateEmail(email); msg != "" {
+		errs["email"] = msg
+	}
+	if msg := validatePassword(password, &adminPasswordPolicy); msg != "" {
+		errs["password"] = msg
+	}
+	if msg := validateAdminRole(role); msg != "" {
+		errs["role"] = msg
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	admin, err := scanAdmin(s.db.QueryRowContext(ctx, `
+		INSERT INTO admin_users AS a (email, password_hash, role)
+		VALUES ($1, $2, $3)
+		RETURNING `+adminColumns+`
+	`, email, hash, role))
+	if database.IsUniqueViolation(err) {
+		return nil, ErrEmailTaken
+	}
+	return admin, err
+}
+
+// Update は管理者のロール・パスワードを更新する（空の項目は変更しない）
+// パスワードを変更した場合は、その管理者のすべてのセッションを失効させる
+func (s *AdminService) Update(ctx context.Context, id string, req *models.UpdateAdminUserRequest) (*models.AdminUser, error) {
+	errs := ValidationError{}
+	if req.Role != "" {
+		if msg := validateAdminRole(req.Role); msg != "" {
+			errs["role"] = msg
+		}
+	}
+	if req.Password != "" {
+		if msg := validatePassword(req.Password, &adminPasswordPolicy); msg != "" {
+			errs["password"] = msg
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	var hash sql.NullString
+	if req.Password != "" {
+		h, err := hashPassword(req.Password)
+		if err != nil {
+			return nil, err
+		}
+		hash = sql.NullString{String: h, Valid: true}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if req.Role != "" && req.Role != models.AdminRoleOwner {
+		if err := checkLastOwner(ctx, tx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	admin, err := scanAdmin(tx.QueryRowContext(ctx, `
+		UPDATE admin_users a SET
+			role = COALESCE(NULLIF($2, ''), a.role),
+			password_hash = COALESCE($3, a.password_hash),
+			updated_at = NOW()
+		WHERE a.id = $1
+		RETURNING `+adminColumns+`
+	`, id, req.Role, hash))
+	if err != nil {
+		return nil, err
+	}
+	if hash.Valid {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM admin_sessions WHERE admin_user_id = $1`, id); err != nil {
+			return nil, err
+		}
+	}
+	return admin, tx.Commit()
+}
+
+// Delete は管理者を削除する（セッションもあわせて削除される）
+func (s *AdminService) Delete(ctx context.Context, id string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkLastOwner(ctx, tx, id); err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM admin_users WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+// checkLastOwner は管理者が最後のownerの場合にErrLastOwnerを返す
+// 同時に複数のownerが削除・降格されないよう、ownerの行をロックする
+func checkLastOwner(ctx context.Context, tx *sql.Tx, id string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM admin_users WHERE role = $1 FOR UPDATE`, models.AdminRoleOwner)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var owners []string
+	for rows.Next() {
+		var ownerID string
+		if err := rows.Scan(&ownerID); err != nil {
+			return err
+		}
+		owners = append(owners, ownerID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(owners) == 1 && owners[0] == id {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+func validateAdminRole(role string) string {
+	if role == "" {
+		return "Role is required"
+	}
+	if !slices.Contains(models.AdminRoles, role) {
+		return "Role must be one of owner, editor, viewer"
+	}
+	return ""
+}
+
+// adminColumns は管理者取得時のカラム（a: admin_users）
+const adminColumns = `a.id, a.email, a.role, a.last_login_at, a.created_at, a.updated_at`
+
+// scanAdmin はadminColumnsで取得した行を管理者に変換する（extraはadminColumnsに続くカラム）
+func scanAdmin(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*models.AdminUser, error) {
+	var admin models.AdminUser
+	dest := append([]interface{}{&admin.ID, &admin.Email, &admin.Role, &admin.LastLoginAt, &admin.CreatedAt, &admin.UpdatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return &admin, nil
+}
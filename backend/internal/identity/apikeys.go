@@ -0,0 +1,283 @@
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/necorox/FlowCore/backend/internal/authz"
+	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// APIキーの形式: fck_<prefixの乱数（16進数）>_<乱数>
+// 先頭の fck_<prefixの乱数> をprefixとして保存し、キーの識別に使用する
+const (
+	apiKeyScheme       = "fck_"
+	apiKeyPrefixBytes  = 6
+	apiKeySecretBytes  = 32
+	apiKeyNameMaxChars = 255
+)
+
+// apiKeyTouchInterval はlast_used_atを更新する間隔（呼び出しのたびに書き込まないようにする）
+const apiKeyTouchInterval = time.Minute
+
+// APIKeyService はサーバー間の呼び出しに使用するAPIキーを管理する
+// キーはSHA-256のハッシュのみを保存し、作成時に1回のみ返す
+type APIKeyService struct {
+	db  *database.DB
+	now func() time.Time
+}
+
+// NewAPIKeyService は新しいAPIKeyServiceを作成する（MASTER_KEYは不要）
+func NewAPIKeyService(db *database.DB) *APIKeyService {
+	return &APIKeyService{db: db, now: time.Now}
+}
+
+// Create はAPIキーを作成し、キーを含むレスポンスを返す
+// createdByは作成した管理者のID（空の場合は記録しない）
+func (s *APIKeyService) Create(ctx context.Context, req *models.CreateAPIKeyRequest, createdBy string) (*models.CreateAPIKeyResponse, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	errs := ValidationError{}
+	switch {
+	case req.Name == "":
+		errs["name"] = "Name is required"
+	case len([]rune(req.Name)) > apiKeyNameMaxChars:
+		errs["name"] = fmt.Sprintf("Name must be at most %d characters", apiKeyNameMaxChars)
+	}
+	if slices.Contains(req.Scopes, "") {
+		errs["scopes"] = "Must not contain empty values"
+	}
+	for i, id := range req.Endpoints {
+		req.Endpoints[i] = strings.ToLower(strings.TrimSpace(id))
+	}
+	if len(req.Endpoints) == 0 && len(req.Tags) == 0 {
+		errs["endpoints"] = "Specify endpoints or tags the key can call"
+	}
+	if msg := models.ValidateTags(req.Tags); msg != "" {
+		errs["tags"] = msg
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.now()) {
+		errs["expires_at"] = "Must be in the future"
+	}
+	if len(errs) == 0 && len(req.Endpoints) > 0 {
+		missing, err := s.missingEndpoints(ctx, req.Endpoints)
+		if err != nil {
+			return nil, err
+		}
+		if len(missing) > 0 {
+			errs["endpoints"] = fmt.Sprintf("Unknown endpoints: %s", strings.Join(missing, ", "))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	scopesJSON, err := json.Marshal(nonNil(req.Scopes))
+	if err != nil {
+		return nil, err
+	}
+	endpointsJSON, err := json.Marshal(nonNil(req.Endpoints))
+	if err != nil {
+		return nil, err
+	}
+	tagsJSON, err := json.Marshal(nonNil(req.Tags))
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, key := newAPIKey()
+	created, err := scanAPIKey(s.db.QueryRowContext(ctx, `
+		INSERT INTO api_keys AS k (name, prefix, key_hash, scopes, endpoint_ids, tags, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, '')::uuid)
+		RETURNING `+apiKeyColumns+`
+	`, req.Name, prefix, hashToken(key), scopesJSON, endpointsJSON, tagsJSON, req.ExpiresAt, createdBy))
+	if err != nil {
+		return nil, err
+	}
+	return &models.CreateAPIKeyResponse{APIKey: *created, Key: key}, nil
+}
+
+// List はAPIキーの一覧を返す（失効・期限切れのキーを含む）
+func (s *APIKeyService) List(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys k
+		ORDER BY k.created_at DESC, k.id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// Get はAPIキーを返す（存在しない場合はsql.ErrNoRows）
+func (s *APIKeyService) Get(ctx context.Context, id string) (*models.APIKey, error) {
+	return scanAPIKey(s.db.QueryRowContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys k
+		WHERE k.id = $1
+	`, id))
+}
+
+// Revoke はAPIキーを失効させる（失効済みの場合は失効日時を変更しない）
+func (s *APIKeyService) Revoke(ctx context.Context, id string) (*models.APIKey, error) {
+	return scanAPIKey(s.db.QueryRowContext(ctx, `
+		UPDATE api_keys k SET revoked_at = COALESCE(k.revoked_at, NOW())
+		WHERE k.id = $1
+		RETURNING `+apiKeyColumns+`
+	`, id))
+}
+
+// Delete はAPIキーを削除する
+func (s *APIKeyService) Delete(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM api_keys WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Authenticate はAPIキーを検証し、呼び出し元を返す（Runtime APIのapi_key認証のauthz.Verifier）
+// 失効・期限切れのキーはErrUnauthenticated、リクエストの対象のエンドポイントを呼び出せないキーはErrForbiddenとする
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*models.Principal, error) {
+	if !strings.HasPrefix(key, apiKeyScheme) {
+		return nil, fmt.Errorf("%w: malformed API key", authz.ErrUnauthenticated)
+	}
+
+	apiKey, err := scanAPIKey(s.db.QueryRowContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys k
+		WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > NOW())
+	`, hashToken(key)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: invalid, expired or revoked API key", authz.ErrUnauthenticated)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resource := authz.ResourceFrom(ctx)
+	if resource == nil || !apiKeyAllows(apiKey, resource) {
+		return nil, fmt.Errorf("%w: API key %s cannot call this endpoint", authz.ErrForbidden, apiKey.Prefix)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - make_interval(secs => $2))
+	`, apiKey.ID, apiKeyTouchInterval.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Principal{
+		Type:    models.AuthAPIKey,
+		Subject: apiKey.ID,
+		Roles:   []string{},
+		Scopes:  apiKey.Scopes,
+	}, nil
+}
+
+// apiKeyAllows はAPIキーが対象のエンドポイント（IDまたはタグのいずれか）を呼び出せるかどうかを返す
+func apiKeyAllows(key *models.APIKey, resource *authz.Resource) bool {
+	if slices.Contains(key.Endpoints, resource.EndpointID) {
+		return true
+	}
+	return slices.ContainsFunc(resource.Tags, func(tag string) bool {
+		return slices.Contains(key.Tags, tag)
+	})
+}
+
+// missingEndpoints は存在しないエンドポイントのIDを返す
+func (s *APIKeyService) missingEndpoints(ctx context.Context, ids []string) ([]string, error) {
+	idsJSON, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id::text FROM meta_endpoints
+		WHERE id::text IN (SELECT jsonb_array_elements_text($1::jsonb))
+	`, idsJSON)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		found[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+// newAPIKey は新しいAPIキーとそのprefixを返す
+func newAPIKey() (prefix, key string) {
+	b := make([]byte, apiKeyPrefixBytes)
+	rand.Read(b)
+	prefix = apiKeyScheme + hex.EncodeToString(b)
+	return prefix, prefix + "_" + randomToken(apiKeySecretBytes)
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// apiKeyColumns はAPIキー取得時のカラム（k: api_keys）
+const apiKeyColumns = `k.id, k.name, k.prefix, k.scopes, k.endpoint_ids, k.tags, k.expires_at, k.revoked_at, k.last_used_at, k.created_by, k.created_at`
+
+// scanAPIKey はapiKeyColumnsで取得した行をAPIキーに変換する
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (*models.APIKey, error) {
+	var key models.APIKey
+	var scopesJSON, endpointsJSON, tagsJSON []byte
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &scopesJSON, &endpointsJSON, &tagsJSON,
+		&key.ExpiresAt, &key.RevokedAt, &key.LastUsedAt, &key.CreatedBy, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range []struct {
+		data []byte
+		dest *[]string
+	}{{scopesJSON, &key.Scopes}, {endpointsJSON, &key.Endpoints}, {tagsJSON, &key.Tags}} {
+		if err := json.Unmarshal(field.data, field.dest); err != nil {
+			return nil, err
+		}
+	}
+	return &key, nil
+}
@@ -0,0 +1,346 @@
+// Package identity はエンドユーザー向けの内部IdP（メール＋パスワード）を提供する
+// パスワードはbcryptでハッシュ化し、アクセストークンはRS256で署名したJWT、
+// リフレッシュトークンは使用のたびにローテーションする不透明なトークンとして発行する
+// あわせてAdmin APIの管理者アカウントとセッション（AdminService）を提供する
+package identity
+
+import (
+	"context"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/necorox/FlowCore/backend/internal/authz"
+	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/mailer"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// providerPassword はメール＋パスワードの認証情報のプロバイダー名
+const providerPassword = "password"
+
+// MinMasterKeyLength はMASTER_KEYの最小の長さ
+const MinMasterKeyLength = 32
+
+var (
+	// ErrMasterKeyRequired はMASTER_KEYが設定されていない（短すぎる）場合のエラー
+	ErrMasterKeyRequired = fmt.Errorf("MASTER_KEY must be at least %d characters", MinMasterKeyLength)
+	// ErrInvalidCredentials はメールアドレスまたはパスワードが一致しない場合のエラー
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrEmailTaken はメールアドレスが登録済みの場合のエラー
+	ErrEmailTaken = errors.New("email is already registered")
+	// ErrEmailNotVerified はメールアドレスの確認が必要な設定で、未確認のユーザーがログインした場合のエラー
+	ErrEmailNotVerified = errors.New("email address is not verified")
+	// ErrInvalidToken はトークンが不正・期限切れ・失効済みの場合のエラー
+	ErrInvalidToken = errors.New("invalid or expired token")
+	// ErrTokenReused は使用済みのリフレッシュトークンが再度使用された場合のエラー（トークンの漏洩の可能性があるため、同じログインのトークンをすべて失効させる）
+	ErrTokenReused = errors.New("refresh token has already been used")
+)
+
+// ValidationError は入力値のエラー（項目名 → メッセージ）
+type ValidationError map[string]string
+
+func (e ValidationError) Error() string {
+	parts := make([]string, 0, len(e))
+	for field, msg := range e {
+		parts = append(parts, field+": "+msg)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Config は内部IdPの設定
+type Config struct {
+	MasterKey       string        // 署名鍵の暗号化に使用する
+	Issuer          string        // JWTのiss
+	AccessTokenTTL  time.Duration // アクセストークンの有効期間
+	RefreshTokenTTL time.Duration // リフレッシュトークンの有効期間
+	VerificationURL string        // 確認メールのリンク（クエリパラメータtokenに確認トークンを付与する）
+	VerificationTTL time.Duration // 確認トークンの有効期間
+	Mailer          mailer.Mailer
+	MailFrom        string
+}
+
+// Service は内部IdPの処理を提供する
+type Service struct {
+	db              *database.DB
+	config          Config
+	keys            *KeyStore
+	verificationKey []byte // 確認トークンの署名鍵
+	now             func() time.Time
+}
+
+// New は新しいServiceを作成する
+// 署名鍵はMetaDBから初回の使用時に読み込み、存在しない場合は生成する
+func New(db *database.DB, config Config) (*Service, error) {
+	if len(config.MasterKey) < MinMasterKeyLength {
+		return nil, ErrMasterKeyRequired
+	}
+	if config.Mailer == nil {
+		config.Mailer = mailer.LogMailer{}
+	}
+	keys, err := NewKeyStore(db, config.MasterKey)
+	if err != nil {
+		return nil, err
+	}
+	verificationKey, err := hkdf.Key(sha256.New, []byte(config.MasterKey), nil, verificationKeyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{db: db, config: config, keys: keys, verificationKey: verificationKey, now: time.Now}, nil
+}
+
+// Signup はユーザーを登録し、トークンを発行する
+// 認証設定でメールアドレスの確認が必要な場合は、トークンを発行せず確認メールを送信する
+func (s *Service) Signup(ctx context.Context, email, password string) (*models.TokenResponse, error) {
+	policy, err := s.authConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	email = normalizeEmail(email)
+	errs := ValidationError{}
+	if msg := validateEmail(email); msg != "" {
+		errs["email"] = msg
+	}
+	if msg := validatePassword(password, policy); msg != "" {
+		errs["password"] = msg
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	user := &models.User{Email: email, Roles: []string{}}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO users (email) VALUES ($1)
+		RETURNING id, created_at
+	`, email).Scan(&user.ID, &user.CreatedAt)
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return nil, ErrEmailTaken
+		}
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO user_identities (user_id, provider, provider_subject, password_hash)
+		VALUES ($1, $2, $3, $4)
+	`, user.ID, providerPassword, email, hash)
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return nil, ErrEmailTaken
+		}
+		return nil, err
+	}
+
+	if policy.EmailVerification {
+		token, err := s.createVerification(ctx, tx, user)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		s.sendVerification(ctx, email, token)
+		return &models.TokenResponse{User: user, VerificationRequired: true}, nil
+	}
+
+	resp, _, err := s.issueTokens(ctx, tx, user, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp, tx.Commit()
+}
+
+// Login はメールアドレスとパスワードを検証し、トークンを発行する
+func (s *Service) Login(ctx context.Context, email, password string) (*models.TokenResponse, error) {
+	email = normalizeEmail(email)
+
+	var hash sql.NullString
+	user, err := scanUser(s.db.QueryRowContext(ctx, `
+		SELECT `+userColumns+`, i.password_hash
+		FROM user_identities i
+		JOIN users u ON u.id = i.user_id
+		WHERE i.provider = $1 AND i.provider_subject = $2
+	`, providerPassword, email), &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		// ユーザーの有無が応答時間から分からないよう、存在しない場合もハッシュを比較する
+		checkPassword(dummyPasswordHash(), password)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !hash.Valid || !checkPassword(hash.String, password) {
+		return nil, ErrInvalidCredentials
+	}
+
+	// パスワードが一致した場合のみ確認の状態を返す
+	if !user.EmailVerified {
+		policy, err := s.authConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if policy.EmailVerification {
+			return nil, ErrEmailNotVerified
+		}
+	}
+
+	resp, _, err := s.issueTokens(ctx, s.db, user, "")
+	return resp, err
+}
+
+// ChangePassword はユーザーのパスワードを変更する
+// 変更後は、すべてのログインのリフレッシュトークンを失効させる
+func (s *Service) ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) error {
+	policy, err := s.authConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var identityID string
+	var hash sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, password_hash
+		FROM user_identities
+		WHERE user_id = $1 AND provider = $2
+		FOR UPDATE
+	`, userID, providerPassword).Scan(&identityID, &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+	if !hash.Valid || !checkPassword(hash.String, currentPassword) {
+		return ValidationError{"current_password": "Current password is incorrect"}
+	}
+	if msg := validatePassword(newPassword, policy); msg != "" {
+		return ValidationError{"new_password": msg}
+	}
+
+	newHash, err := hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE user_identities SET password_hash = $2, updated_at = NOW() WHERE id = $1
+	`, identityID, newHash)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// VerifyAccessToken はアクセストークンの署名・有効期限・発行者を検証し、クレームを返す
+func (s *Service) VerifyAccessToken(ctx context.Context, token string) (*Claims, error) {
+	return verifyJWT(ctx, s.keys, token, s.config.Issuer, s.now())
+}
+
+// Authenticate はアクセストークンを検証し、呼び出し元を返す（Runtime APIのjwt認証のauthz.Verifier）
+func (s *Service) Authenticate(ctx context.Context, token string) (*models.Principal, error) {
+	claims, err := s.VerifyAccessToken(ctx, token)
+	if errors.Is(err, ErrInvalidToken) {
+		return nil, fmt.Errorf("%w: %v", authz.ErrUnauthenticated, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	roles := claims.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	return &models.Principal{
+		Type:     models.AuthJWT,
+		Subject:  claims.Subject,
+		TenantID: claims.TenantID,
+		Roles:    roles,
+		Email:    claims.Email,
+	}, nil
+}
+
+// JWKS はアクセストークンの検証に使用する公開鍵の一覧を返す
+func (s *Service) JWKS(ctx context.Context) (*models.JWKS, error) {
+	keys, err := s.keys.JWKs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &models.JWKS{Keys: keys}, nil
+}
+
+// authConfig はMetaDBの認証設定を返す（設定が存在しない場合は既定値）
+func (s *Service) authConfig(ctx context.Context) (*models.AuthConfig, error) {
+	settings, err := s.db.AuthSettings(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		config := models.DefaultAuthConfig
+		return &config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings.Config, nil
+}
+
+// userColumns はユーザー取得時のカラム（u: users）
+const userColumns = `u.id, u.email, u.email_verified_at IS NOT NULL, COALESCE(u.tenant_id, ''), u.roles, u.created_at`
+
+// scanUser はuserColumnsで取得した行をユーザーに変換する（extraはuserColumnsに続くカラム）
+func scanUser(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*models.User, error) {
+	var user models.User
+	var rolesJSON []byte
+	dest := append([]interface{}{&user.ID, &user.Email, &user.EmailVerified, &user.TenantID, &rolesJSON, &user.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(rolesJSON, &user.Roles); err != nil {
+		return nil, err
+	}
+	if user.Roles == nil {
+		user.Roles = []string{}
+	}
+	return &user, nil
+}
+
+// normalizeEmail はメールアドレスの前後の空白を除き、小文字に正規化する
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func validateEmail(email string) string {
+	if email == "" {
+		return "Email is required"
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > 254 {
+		return "Email is invalid"
+	}
+	return ""
+}
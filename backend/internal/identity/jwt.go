@@ -0,0 +1,105 @@
+package identity
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// clockSkew は有効期限・発行日時の検証で許容する時刻のずれ
+const clockSkew = 30 * time.Second
+
+// Claims はアクセストークン（JWT）のクレーム
+type Claims struct {
+	Subject   string   `json:"sub"` // ユーザーID
+	Issuer    string   `json:"iss"`
+	TenantID  string   `json:"tenant_id,omitempty"`
+	Roles     []string `json:"roles"`
+	Email     string   `json:"email,omitempty"`
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+	ID        string   `json:"jti"`
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid"`
+}
+
+// signJWT はクレームをRS256で署名したJWTを返す
+func signJWT(kid string, key *rsa.PrivateKey, claims *Claims) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: signingAlgorithm, Typ: "JWT", Kid: kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// verifyJWT はJWTの署名・発行者・有効期限を検証し、クレームを返す
+// 検証に失敗した場合は詳細を含めずErrInvalidTokenを返す
+func verifyJWT(ctx context.Context, keys *KeyStore, token, issuer string, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+	// algはトークンの指定に従わず、RS256のみを受け付ける
+	if header.Alg != signingAlgorithm || header.Kid == "" {
+		return nil, ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	key, err := keys.PublicKey(ctx, header.Kid)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, ErrInvalidToken
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if claims.Subject == "" || claims.Issuer != issuer {
+		return nil, ErrInvalidToken
+	}
+	if now.Add(-clockSkew).Unix() >= claims.ExpiresAt || now.Add(clockSkew).Unix() < claims.IssuedAt {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
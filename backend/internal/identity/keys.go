@@ -0,0 +1,292 @@
+package identity
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// 署名鍵の設定
+const (
+	signingAlgorithm = "RS256"
+	signingKeyBits   = 2048
+	// keyReloadInterval は未知のkidのトークンを受け取った際に署名鍵を再読み込みする最短の間隔
+	keyReloadInterval = 30 * time.Second
+)
+
+// signingKeyInfo は署名鍵の暗号化に使用する鍵を導出する際のinfo
+const signingKeyInfo = "flowcore/signing-keys"
+
+// KeyStore はJWTの署名鍵を管理する
+// 秘密鍵はMASTER_KEYから導出した鍵（HKDF-SHA256）でAES-256-GCMにより暗号化してauth_signing_keysに保存する
+// 他のインスタンスが追加した鍵は、未知のkidのトークンを受け取った際に再読み込みする
+type KeyStore struct {
+	db   *database.DB
+	aead cipher.AEAD
+
+	mu       sync.RWMutex
+	loaded   time.Time
+	public   map[string]*rsa.PublicKey // kid → 公開鍵（失効済みの鍵を含む）
+	order    []string                  // 作成日時の昇順のkid
+	signKid  string
+	signKey  *rsa.PrivateKey
+	generate sync.Mutex
+}
+
+// NewKeyStore は新しいKeyStoreを作成する
+func NewKeyStore(db *database.DB, masterKey string) (*KeyStore, error) {
+	key, err := hkdf.Key(sha256.New, []byte(masterKey), nil, signingKeyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyStore{db: db, aead: aead}, nil
+}
+
+// SigningKey は署名に使用する鍵（失効していない最新の鍵）を返す
+// 鍵が存在しない場合は生成して保存する
+func (s *KeyStore) SigningKey(ctx context.Context) (string, *rsa.PrivateKey, error) {
+	s.mu.RLock()
+	kid, key := s.signKid, s.signKey
+	s.mu.RUnlock()
+	if key != nil {
+		return kid, key, nil
+	}
+
+	// 複数のリクエストが同時に鍵を生成しないよう直列化する
+	s.generate.Lock()
+	defer s.generate.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return "", nil, err
+	}
+	s.mu.RLock()
+	kid, key = s.signKid, s.signKey
+	s.mu.RUnlock()
+	if key != nil {
+		return kid, key, nil
+	}
+
+	if err := s.create(ctx); err != nil {
+		return "", nil, err
+	}
+	if err := s.load(ctx); err != nil {
+		return "", nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.signKey == nil {
+		return "", nil, errors.New("no signing key available")
+	}
+	return s.signKid, s.signKey, nil
+}
+
+// PublicKey はkidの公開鍵を返す
+// 未知のkidの場合は、前回の読み込みからkeyReloadInterval以上経過していれば再読み込みする
+func (s *KeyStore) PublicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	s.mu.RLock()
+	key, ok := s.public[kid]
+	stale := time.Since(s.loaded) >= keyReloadInterval
+	s.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if !stale {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if key, ok := s.public[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// JWKs は公開鍵の一覧を返す（署名鍵が存在しない場合は生成する）
+func (s *KeyStore) JWKs(ctx context.Context) ([]models.JWK, error) {
+	if _, _, err := s.SigningKey(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]models.JWK, 0, len(s.order))
+	for _, kid := range s.order {
+		keys = append(keys, publicJWK(kid, s.public[kid]))
+	}
+	return keys, nil
+}
+
+// load はauth_signing_keysから鍵を読み込む
+// 秘密鍵は署名に使用する鍵のみ復号する
+func (s *KeyStore) load(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT kid, public_key, private_key, retired_at
+		FROM auth_signing_keys
+		WHERE algorithm = $1
+		ORDER BY created_at, kid
+	`, signingAlgorithm)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	public := map[string]*rsa.PublicKey{}
+	var order []string
+	var signKid string
+	var signSealed []byte
+	for rows.Next() {
+		var kid, publicPEM string
+		var sealed []byte
+		var retiredAt sql.NullTime
+		if err := rows.Scan(&kid, &publicPEM, &sealed, &retiredAt); err != nil {
+			return err
+		}
+		key, err := parsePublicKey(publicPEM)
+		if err != nil {
+			return fmt.Errorf("signing key %q: %w", kid, err)
+		}
+		public[kid] = key
+		order = append(order, kid)
+		if !retiredAt.Valid {
+			signKid, signSealed = kid, sealed
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var signKey *rsa.PrivateKey
+	if signKid != "" {
+		signKey, err = s.open(signKid, signSealed)
+		if err != nil {
+			return fmt.Errorf("signing key %q: %w", signKid, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.public = public
+	s.order = order
+	s.signKid = signKid
+	s.signKey = signKey
+	s.loaded = time.Now()
+	return nil
+}
+
+// create は新しい署名鍵を生成して保存する
+func (s *KeyStore) create(ctx context.Context) error {
+	key, err := rsa.GenerateKey(rand.Reader, signingKeyBits)
+	if err != nil {
+		return err
+	}
+	kid := thumbprint(&key.PublicKey)
+
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return err
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO auth_signing_keys (kid, algorithm, public_key, private_key)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (kid) DO NOTHING
+	`, kid, signingAlgorithm, string(publicPEM), s.seal(kid, privateDER))
+	return err
+}
+
+// seal は秘密鍵を暗号化する（nonce + ciphertext。kidを追加データとして認証する）
+func (s *KeyStore) seal(kid string, plaintext []byte) []byte {
+	nonce := make([]byte, s.aead.NonceSize())
+	rand.Read(nonce)
+	return s.aead.Seal(nonce, nonce, plaintext, []byte(kid))
+}
+
+// open は暗号化された秘密鍵を復号する
+func (s *KeyStore) open(kid string, sealed []byte) (*rsa.PrivateKey, error) {
+	n := s.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, errors.New("encrypted private key is truncated")
+	}
+	der, err := s.aead.Open(nil, sealed[:n], sealed[n:], []byte(kid))
+	if err != nil {
+		return nil, errors.New("failed to decrypt private key (MASTER_KEY may have changed)")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return key, nil
+}
+
+func parsePublicKey(publicPEM string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicPEM))
+	if block == nil {
+		return nil, errors.New("invalid public key PEM")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an RSA key")
+	}
+	return key, nil
+}
+
+// publicJWK は公開鍵をJWKに変換する
+func publicJWK(kid string, key *rsa.PublicKey) models.JWK {
+	return models.JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: signingAlgorithm,
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// thumbprint は公開鍵のJWK Thumbprint（RFC 7638。kidとして使用する）を返す
+func thumbprint(key *rsa.PublicKey) string {
+	jwk := publicJWK("", key)
+	// 必須メンバーを辞書順に並べ、空白を含まないJSON
+	canonical := `{"e":"` + jwk.E + `","kty":"RSA","n":"` + jwk.N + `"}`
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,73 @@
+package identity
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/necorox/FlowCore/backend/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordCost はbcryptのコスト
+const passwordCost = 12
+
+// パスワードの長さの制限（bcryptは72バイトを超える部分を扱えない）
+// 認証設定のmin_password_lengthはこの範囲で指定する
+const (
+	minPasswordLength = 8
+	maxPasswordBytes  = 72
+)
+
+// dummyPasswordHash は存在しないユーザーのログイン時に比較するハッシュ（初回の使用時に生成する）
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := bcrypt.GenerateFromPassword([]byte("flowcore-dummy-password"), passwordCost)
+	if err != nil {
+		panic(err)
+	}
+	return string(hash)
+})
+
+// ValidateAuthConfig は認証設定を検証し、エラー（項目名 → メッセージ）を返す
+func ValidateAuthConfig(config *models.AuthConfig) map[string]string {
+	errs := map[string]string{}
+	if config.MinPasswordLength < minPasswordLength || config.MinPasswordLength > maxPasswordBytes {
+		errs["config.min_password_length"] = fmt.Sprintf("Min password length must be between %d and %d", minPasswordLength, maxPasswordBytes)
+	}
+	return errs
+}
+
+// validatePassword はパスワードが認証設定のポリシーを満たすかを検証し、問題があればメッセージを返す
+func validatePassword(password string, policy *models.AuthConfig) string {
+	minLength := max(policy.MinPasswordLength, minPasswordLength)
+	switch {
+	case utf8.RuneCountInString(password) < minLength:
+		return fmt.Sprintf("Password must be at least %d characters", minLength)
+	case len(password) > maxPasswordBytes:
+		return fmt.Sprintf("Password must be at most %d bytes", maxPasswordBytes)
+	case policy.RequireNumber && !strings.ContainsFunc(password, unicode.IsDigit):
+		return "Password must contain a number"
+	case policy.RequireSpecialChar && !strings.ContainsFunc(password, isSpecialChar):
+		return "Password must contain a special character"
+	}
+	return ""
+}
+
+// isSpecialChar は記号（句読点・記号）かどうかを返す
+func isSpecialChar(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func checkPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
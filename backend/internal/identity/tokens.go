@@ -0,0 +1,154 @@
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+
+	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// refreshTokenBytes はリフレッシュトークンの乱数のバイト数
+const refreshTokenBytes = 32
+
+// Refresh はリフレッシュトークンを検証し、新しいトークンを発行する（ローテーション）
+// 使用済みのトークンが再度使用された場合は、同じログインで発行したトークンをすべて失効させてErrTokenReusedを返す
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (*models.TokenResponse, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id, userID, familyID string
+	var revoked, expired bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, user_id, family_id, revoked_at IS NOT NULL, expires_at <= NOW()
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE
+	`, hashToken(refreshToken)).Scan(&id, &userID, &familyID, &revoked, &expired)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		if err := revokeFamily(ctx, tx, familyID); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, ErrTokenReused
+	}
+	if expired {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := scanUser(tx.QueryRowContext(ctx, `
+		SELECT `+userColumns+` FROM users u WHERE u.id = $1
+	`, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp, newID, err := s.issueTokens(ctx, tx, user, familyID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW(), replaced_by = $2 WHERE id = $1
+	`, id, newID)
+	if err != nil {
+		return nil, err
+	}
+	return resp, tx.Commit()
+}
+
+// Logout はリフレッシュトークンと、同じログインで発行したトークンをすべて失効させる
+// 存在しない・失効済みのトークンの場合も成功とする
+func (s *Service) Logout(ctx context.Context, refreshToken string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1)
+		  AND revoked_at IS NULL
+	`, hashToken(refreshToken))
+	return err
+}
+
+// issueTokens はアクセストークンとリフレッシュトークンを発行する
+// familyIDが空の場合は新しいログインとして新しいfamilyを割り当てる。発行したリフレッシュトークンのIDも返す
+func (s *Service) issueTokens(ctx context.Context, q database.Querier, user *models.User, familyID string) (*models.TokenResponse, string, error) {
+	kid, key, err := s.keys.SigningKey(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := s.now()
+	claims := &Claims{
+		Subject:   user.ID,
+		Issuer:    s.config.Issuer,
+		TenantID:  user.TenantID,
+		Roles:     user.Roles,
+		Email:     user.Email,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(s.config.AccessTokenTTL).Unix(),
+		ID:        randomToken(16),
+	}
+	accessToken, err := signJWT(kid, key, claims)
+	if err != nil {
+		return nil, "", err
+	}
+
+	refreshToken := randomToken(refreshTokenBytes)
+	var refreshID string
+	err = q.QueryRowContext(ctx, `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, COALESCE(NULLIF($2, '')::uuid, gen_random_uuid()), $3, NOW() + make_interval(secs => $4))
+		RETURNING id
+	`, user.ID, familyID, hashToken(refreshToken), s.config.RefreshTokenTTL.Seconds()).Scan(&refreshID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &models.TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.config.AccessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+		User:         user,
+	}, refreshID, nil
+}
+
+// revokeFamily は同じログインで発行したリフレッシュトークンをすべて失効させる
+func revokeFamily(ctx context.Context, q database.Querier, familyID string) error {
+	_, err := q.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`, familyID)
+	return err
+}
+
+// randomToken はURLセーフな乱数のトークンを返す
+func randomToken(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// hashToken はトークンの保存用のハッシュ（SHA-256の16進数）を返す
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
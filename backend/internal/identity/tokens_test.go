@@ -0,0 +1,328 @@
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// fakeRefreshToken はrefresh_tokensの1行
+type fakeRefreshToken struct {
+	id, userID, familyID, hash string
+	revoked                    bool
+	replacedBy                 string
+}
+
+// fakeStore はリフレッシュトークンの処理が発行するクエリだけを扱うインメモリのMetaDB
+type fakeStore struct {
+	mu     sync.Mutex
+	tokens []*fakeRefreshToken
+	nextID int
+}
+
+func (s *fakeStore) find(match func(*fakeRefreshToken) bool) []*fakeRefreshToken {
+	var found []*fakeRefreshToken
+	for _, t := range s.tokens {
+		if match(t) {
+			found = append(found, t)
+		}
+	}
+	return found
+}
+
+func (s *fakeStore) query(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case strings.Contains(query, "FROM refresh_tokens") && strings.Contains(query, "FOR UPDATE"):
+		found := s.find(func(t *fakeRefreshToken) bool { return t.hash == args[0].Value })
+		if len(found) == 0 {
+			return nil, nil, nil
+		}
+		t := found[0]
+		return []string{"id", "user_id", "family_id", "revoked", "expired"}, [][]driver.Value{{t.id, t.userID, t.familyID, t.revoked, false}}, nil
+	case strings.Contains(query, "FROM users u"):
+		return []string{"id", "email", "verified", "tenant_id", "roles", "created_at"},
+			[][]driver.Value{{args[0].Value, "user@example.com", true, "", []byte(`["user"]`), time.Now()}}, nil
+	case strings.Contains(query, "INSERT INTO refresh_tokens"):
+		s.nextID++
+		familyID := args[1].Value.(string)
+		if familyID == "" {
+			familyID = fmt.Sprintf("family-%d", s.nextID)
+		}
+		t := &fakeRefreshToken{id: fmt.Sprintf("token-%d", s.nextID), userID: args[0].Value.(string), familyID: familyID, hash: args[2].Value.(string)}
+		s.tokens = append(s.tokens, t)
+		return []string{"id"}, [][]driver.Value{{t.id}}, nil
+	}
+	return nil, nil, fmt.Errorf("unexpected query: %s", query)
+}
+
+func (s *fakeStore) exec(query string, args []driver.NamedValue) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case strings.Contains(query, "replaced_by = $2"):
+		for _, t := range s.find(func(t *fakeRefreshToken) bool { return t.id == args[0].Value }) {
+			t.revoked, t.replacedBy = true, args[1].Value.(string)
+		}
+	case strings.Contains(query, "WHERE family_id = $1"):
+		for _, t := range s.find(func(t *fakeRefreshToken) bool { return t.familyID == args[0].Value }) {
+			t.revoked = true
+		}
+	default:
+		return fmt.Errorf("unexpected query: %s", query)
+	}
+	return nil
+}
+
+type fakeConnector struct{ store *fakeStore }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn(c), nil }
+func (c fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ store *fakeStore }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	columns, rows, err := c.store.query(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: columns, rows: rows}, nil
+}
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.store.exec(query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// newTestService はfakeStoreと生成済みの署名鍵を使用するServiceを作成する
+func newTestService(t *testing.T) (*Service, *fakeStore) {
+	t.Helper()
+	store := &fakeStore{}
+	conn := sql.OpenDB(fakeConnector{store: store})
+	t.Cleanup(func() { conn.Close() })
+
+	s, err := New(database.NewFromConn(conn), Config{
+		MasterKey:       strings.Repeat("k", MinMasterKeyLength),
+		Issuer:          "flowcore-test",
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.keys.setTestKey(t, "test-key")
+	return s, store
+}
+
+// setTestKey はDBを使用せずに署名鍵を設定する
+func (s *KeyStore) setTestKey(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, signingKeyBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.signKid, s.signKey = kid, key
+	s.public = map[string]*rsa.PublicKey{kid: &key.PublicKey}
+	s.order = []string{kid}
+	s.loaded = time.Now()
+	return key
+}
+
+// TestRefreshDetectsReuse はローテーション済みのリフレッシュトークンの再使用で、同じログインのトークンがすべて失効することを確認する
+func TestRefreshDetectsReuse(t *testing.T) {
+	// refreshは使用するトークンのラベル、saveは発行されたトークンを保存するラベル
+	type step struct {
+		refresh string
+		save    string
+		wantErr error
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "rotation",
+			steps: []step{
+				{refresh: "a", save: "a2"},
+				{refresh: "a2", save: "a3"},
+				{refresh: "a3"},
+			},
+		},
+		{
+			name: "reuse of rotated token revokes the family",
+			steps: []step{
+				{refresh: "a", save: "a2"},
+				{refresh: "a", wantErr: ErrTokenReused},
+				{refresh: "a2", wantErr: ErrTokenReused},
+			},
+		},
+		{
+			name: "reuse does not affect other logins",
+			steps: []step{
+				{refresh: "a", save: "a2"},
+				{refresh: "a", wantErr: ErrTokenReused},
+				{refresh: "b", save: "b2"},
+				{refresh: "b2"},
+			},
+		},
+		{
+			name: "unknown token",
+			steps: []step{
+				{refresh: "unknown", wantErr: ErrInvalidToken},
+				{refresh: "a"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, store := newTestService(t)
+			user := &models.User{ID: "user-1", Roles: []string{"user"}}
+
+			// a, bはそれぞれ別のログインで発行したトークン
+			tokens := map[string]string{"unknown": "not-issued"}
+			for _, label := range []string{"a", "b"} {
+				resp, _, err := s.issueTokens(ctx, s.db, user, "")
+				if err != nil {
+					t.Fatalf("issueTokens() error = %v", err)
+				}
+				tokens[label] = resp.RefreshToken
+			}
+
+			for i, st := range tt.steps {
+				resp, err := s.Refresh(ctx, tokens[st.refresh])
+				if !errors.Is(err, st.wantErr) {
+					t.Fatalf("step %d: Refresh(%s) error = %v, want %v", i, st.refresh, err, st.wantErr)
+				}
+				if err != nil {
+					continue
+				}
+				if resp.RefreshToken == tokens[st.refresh] {
+					t.Fatalf("step %d: Refresh(%s) returned the same refresh token", i, st.refresh)
+				}
+				if _, err := s.VerifyAccessToken(ctx, resp.AccessToken); err != nil {
+					t.Fatalf("step %d: VerifyAccessToken() error = %v", i, err)
+				}
+				if st.save != "" {
+					tokens[st.save] = resp.RefreshToken
+				}
+			}
+
+			// ローテーションしたトークンは発行したトークンに置き換えられ、同じログインのトークンとして記録される
+			for _, tok := range store.tokens {
+				if tok.replacedBy == "" {
+					continue
+				}
+				next := store.find(func(n *fakeRefreshToken) bool { return n.id == tok.replacedBy })
+				if len(next) != 1 || next[0].familyID != tok.familyID || !tok.revoked {
+					t.Errorf("token %s replaced by %s, want a revoked token replaced in the same family", tok.id, tok.replacedBy)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyJWT(t *testing.T) {
+	ctx := context.Background()
+	keys := &KeyStore{}
+	key := keys.setTestKey(t, "test-key")
+	other, err := rsa.GenerateKey(rand.Reader, signingKeyBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	valid := func() *Claims {
+		return &Claims{Subject: "user-1", Issuer: "flowcore-test", Roles: []string{"user"}, IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix(), ID: "jti"}
+	}
+	sign := func(kid string, key *rsa.PrivateKey, claims *Claims) string {
+		token, err := signJWT(kid, key, claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	tampered := func(token string) string {
+		parts := strings.Split(token, ".")
+		other := strings.Split(sign("test-key", key, &Claims{Subject: "admin", Issuer: "flowcore-test", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix()}), ".")
+		return parts[0] + "." + other[1] + "." + parts[2]
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid", token: sign("test-key", key, valid())},
+		{name: "expired", token: sign("test-key", key, &Claims{Subject: "user-1", Issuer: "flowcore-test", IssuedAt: now.Add(-time.Hour).Unix(), ExpiresAt: now.Add(-time.Minute).Unix()}), wantErr: true},
+		{name: "issued in the future", token: sign("test-key", key, &Claims{Subject: "user-1", Issuer: "flowcore-test", IssuedAt: now.Add(time.Hour).Unix(), ExpiresAt: now.Add(2 * time.Hour).Unix()}), wantErr: true},
+		{name: "other issuer", token: sign("test-key", key, &Claims{Subject: "user-1", Issuer: "other", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix()}), wantErr: true},
+		{name: "no subject", token: sign("test-key", key, &Claims{Issuer: "flowcore-test", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix()}), wantErr: true},
+		{name: "signed by another key", token: sign("test-key", other, valid()), wantErr: true},
+		{name: "unknown key id", token: sign("other-key", other, valid()), wantErr: true},
+		{name: "tampered payload", token: tampered(sign("test-key", key, valid())), wantErr: true},
+		{name: "alg none", token: "eyJhbGciOiJub25lIiwia2lkIjoidGVzdC1rZXkifQ." + strings.Split(sign("test-key", key, valid()), ".")[1] + ".", wantErr: true},
+		{name: "malformed", token: "not-a-jwt", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifyJWT(ctx, keys, tt.token, "flowcore-test", now)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("verifyJWT() = %+v, %v, want ErrInvalidToken", claims, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifyJWT() error = %v", err)
+			}
+			if claims.Subject != "user-1" || claims.ID != "jti" {
+				t.Errorf("verifyJWT() claims = %+v", claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package identity
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/necorox/FlowCore/backend/internal/database"
+	"github.com/necorox/FlowCore/backend/internal/mailer"
+	"github.com/necorox/FlowCore/backend/internal/models"
+)
+
+// verificationKeyInfo は確認トークンの署名に使用する鍵を導出する際のinfo
+const verificationKeyInfo = "flowcore/email-verification"
+
+// verificationTokenBytes は確認トークンのIDの乱数のバイト数
+const verificationTokenBytes = 16
+
+// createVerification は確認トークンを作成して保存し、署名したトークンを返す
+func (s *Service) createVerification(ctx context.Context, q database.Querier, user *models.User) (string, error) {
+	id := randomToken(verificationTokenBytes)
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO email_verification_tokens (id, user_id, email, expires_at)
+		VALUES ($1, $2, $3, NOW() + make_interval(secs => $4))
+	`, id, user.ID, user.Email, s.config.VerificationTTL.Seconds())
+	if err != nil {
+		return "", err
+	}
+	return id + "." + s.verificationSignature(id), nil
+}
+
+// sendVerification は確認メールを送信する
+// 送信に失敗してもユーザーは確認メールを再送できるため、エラーはログに出力するのみとする
+func (s *Service) sendVerification(ctx context.Context, email, token string) {
+	link, err := url.Parse(s.config.VerificationURL)
+	if err != nil {
+		log.Printf("Warning: invalid email verification URL: %v", err)
+		return
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	err = s.config.Mailer.Send(ctx, &mailer.Message{
+		From:    s.config.MailFrom,
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Open the following link to verify your email address:\n\n%s\n\nThe link expires in %s. If you did not sign up, you can ignore this email.\n",
+			link.String(), s.config.VerificationTTL),
+	})
+	if err != nil {
+		log.Printf("Warning: failed to send verification email: %v", err)
+	}
+}
+
+// VerifyEmail は確認トークンを検証し、ユーザーのメールアドレスを確認済みにする
+// トークンは1回のみ使用でき、同じユーザーの未使用のトークンもあわせて無効にする
+func (s *Service) VerifyEmail(ctx context.Context, token string) (*models.User, error) {
+	id, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.verificationSignature(id))) {
+		return nil, ErrInvalidToken
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userID, email string
+	err = tx.QueryRowContext(ctx, `
+		UPDATE email_verification_tokens SET used_at = NOW()
+		WHERE id = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id, email
+	`, id).Scan(&userID, &email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// トークンの作成後にメールアドレスが変更された場合は無効とする
+	user, err := scanUser(tx.QueryRowContext(ctx, `
+		UPDATE users u SET email_verified_at = COALESCE(u.email_verified_at, NOW()), updated_at = NOW()
+		WHERE u.id = $1 AND u.email = $2
+		RETURNING `+userColumns+`
+	`, userID, email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE email_verification_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return user, tx.Commit()
+}
+
+// ResendVerification は未確認のユーザーに確認メールを再送する
+// メールアドレスの登録の有無を推測されないよう、ユーザーが存在しない・確認済みの場合もエラーにしない
+func (s *Service) ResendVerification(ctx context.Context, email string) error {
+	policy, err := s.authConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if !policy.EmailVerification {
+		return nil
+	}
+
+	user, err := scanUser(s.db.QueryRowContext(ctx, `
+		SELECT `+userColumns+`
+		FROM user_identities i
+		JOIN users u ON u.id = i.user_id
+		WHERE i.provider = $1 AND i.provider_subject = $2
+	`, providerPassword, normalizeEmail(email)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return nil
+	}
+
+	token, err := s.createVerification(ctx, s.db, user)
+	if err != nil {
+		return err
+	}
+	s.sendVerification(ctx, user.Email, token)
+	return nil
+}
+
+// verificationSignature は確認トークンのIDの署名（HMAC-SHA256）を返す
+func (s *Service) verificationSignature(id string) string {
+	mac := hmac.New(sha256.New, s.verificationKey)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,423 @@
+// Package jsonschema はJSON Schema（draft 2020-12のサブセット）のコンパイルと検証を提供する
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// unsupportedKeywords は検証に影響するが未対応のキーワード
+// 黙って無視すると期待した検証が行われないため、コンパイル時にエラーにする
+var unsupportedKeywords = []string{
+	"$dynamicRef", "$dynamicAnchor", "patternProperties", "propertyNames", "dependentRequired",
+	"dependentSchemas", "if", "then", "else", "contains", "minContains", "maxContains",
+	"unevaluatedProperties", "unevaluatedItems",
+}
+
+var validTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true, "number": true, "integer": true, "string": true,
+}
+
+// Schema はコンパイル済みのJSON Schema
+type Schema struct {
+	root *node
+	raw  interface{}
+}
+
+// node はスキーマの1つの階層を表す
+type node struct {
+	// always はtrue/falseのスキーマ（nilの場合はキーワードで検証する）
+	always *bool
+
+	types    []string
+	enum     []interface{}
+	constant *interface{}
+
+	// object
+	properties    map[string]*node
+	required      []string
+	additional    *node
+	minProperties *int
+	maxProperties *int
+
+	// array
+	items       *node
+	prefixItems []*node
+	minItems    *int
+	maxItems    *int
+	uniqueItems bool
+
+	// string
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+	format    string
+
+	// number
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+	multipleOf       *float64
+
+	// 組み合わせ
+	allOf []*node
+	anyOf []*node
+	oneOf []*node
+	not   *node
+	ref   *node
+}
+
+// Compile はJSONのスキーマをコンパイルする
+func Compile(data []byte) (*Schema, error) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return CompileValue(raw)
+}
+
+// CompileValue はデコード済みのスキーマ（オブジェクトまたは真偽値）をコンパイルする
+func CompileValue(raw interface{}) (*Schema, error) {
+	c := &compiler{root: raw, refs: map[string]*node{}}
+	root, err := c.compile(raw, "")
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{root: root, raw: raw}, nil
+}
+
+// Raw はコンパイル前のスキーマを返す（APIドキュメントの生成に使用する）
+func (s *Schema) Raw() interface{} {
+	return s.raw
+}
+
+// compiler は$refの解決のためにルートのスキーマを保持する
+type compiler struct {
+	root interface{}
+	refs map[string]*node
+}
+
+// SchemaError はスキーマ自体が不正な場合のエラー
+type SchemaError struct {
+	Pointer string // スキーマ内のJSONポインタ
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	if e.Pointer == "" {
+		return "invalid schema: " + e.Message
+	}
+	return fmt.Sprintf("invalid schema at %s: %s", e.Pointer, e.Message)
+}
+
+func schemaError(ptr, format string, args ...interface{}) error {
+	return &SchemaError{Pointer: ptr, Message: fmt.Sprintf(format, args...)}
+}
+
+func (c *compiler) compile(raw interface{}, ptr string) (*node, error) {
+	switch v := raw.(type) {
+	case bool:
+		return &node{always: &v}, nil
+	case map[string]interface{}:
+		n := &node{}
+		if err := c.compileObject(n, v, ptr); err != nil {
+			return nil, err
+		}
+		return n, nil
+	default:
+		return nil, schemaError(ptr, "schema must be an object or a boolean")
+	}
+}
+
+func (c *compiler) compileObject(n *node, m map[string]interface{}, ptr string) error {
+	for _, keyword := range unsupportedKeywords {
+		if _, ok := m[keyword]; ok {
+			return schemaError(ptr, "keyword %q is not supported", keyword)
+		}
+	}
+
+	var err error
+	if ref, ok := m["$ref"]; ok {
+		s, ok := ref.(string)
+		if !ok {
+			return schemaError(ptr+"/$ref", "must be a string")
+		}
+		if n.ref, err = c.resolve(s, ptr+"/$ref"); err != nil {
+			return err
+		}
+	}
+
+	if t, ok := m["type"]; ok {
+		switch v := t.(type) {
+		case string:
+			n.types = []string{v}
+		case []interface{}:
+			for _, item := range v {
+				s, ok := item.(string)
+				if !ok {
+					return schemaError(ptr+"/type", "must be a string or an array of strings")
+				}
+				n.types = append(n.types, s)
+			}
+		default:
+			return schemaError(ptr+"/type", "must be a string or an array of strings")
+		}
+		for _, typ := range n.types {
+			if !validTypes[typ] {
+				return schemaError(ptr+"/type", "unknown type %q", typ)
+			}
+		}
+	}
+
+	if e, ok := m["enum"]; ok {
+		list, ok := e.([]interface{})
+		if !ok {
+			return schemaError(ptr+"/enum", "must be an array")
+		}
+		n.enum = list
+	}
+	if value, ok := m["const"]; ok {
+		n.constant = &value
+	}
+
+	// object
+	if props, ok := m["properties"]; ok {
+		pm, ok := props.(map[string]interface{})
+		if !ok {
+			return schemaError(ptr+"/properties", "must be an object")
+		}
+		n.properties = make(map[string]*node, len(pm))
+		for name, sub := range pm {
+			if n.properties[name], err = c.compile(sub, ptr+"/properties/"+escape(name)); err != nil {
+				return err
+			}
+		}
+	}
+	if req, ok := m["required"]; ok {
+		list, ok := req.([]interface{})
+		if !ok {
+			return schemaError(ptr+"/required", "must be an array of strings")
+		}
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				return schemaError(ptr+"/required", "must be an array of strings")
+			}
+			n.required = append(n.required, s)
+		}
+	}
+	if additional, ok := m["additionalProperties"]; ok {
+		if n.additional, err = c.compile(additional, ptr+"/additionalProperties"); err != nil {
+			return err
+		}
+	}
+	if n.minProperties, err = intKeyword(m, "minProperties", ptr); err != nil {
+		return err
+	}
+	if n.maxProperties, err = intKeyword(m, "maxProperties", ptr); err != nil {
+		return err
+	}
+
+	// array
+	if items, ok := m["items"]; ok {
+		if n.items, err = c.compile(items, ptr+"/items"); err != nil {
+			return err
+		}
+	}
+	if prefix, ok := m["prefixItems"]; ok {
+		if n.prefixItems, err = c.compileList(prefix, ptr+"/prefixItems"); err != nil {
+			return err
+		}
+	}
+	if n.minItems, err = intKeyword(m, "minItems", ptr); err != nil {
+		return err
+	}
+	if n.maxItems, err = intKeyword(m, "maxItems", ptr); err != nil {
+		return err
+	}
+	if unique, ok := m["uniqueItems"]; ok {
+		b, ok := unique.(bool)
+		if !ok {
+			return schemaError(ptr+"/uniqueItems", "must be a boolean")
+		}
+		n.uniqueItems = b
+	}
+
+	// string
+	if n.minLength, err = intKeyword(m, "minLength", ptr); err != nil {
+		return err
+	}
+	if n.maxLength, err = intKeyword(m, "maxLength", ptr); err != nil {
+		return err
+	}
+	if pattern, ok := m["pattern"]; ok {
+		s, ok := pattern.(string)
+		if !ok {
+			return schemaError(ptr+"/pattern", "must be a string")
+		}
+		if n.pattern, err = regexp.Compile(s); err != nil {
+			return schemaError(ptr+"/pattern", "invalid regular expression: %v", err)
+		}
+	}
+	if format, ok := m["format"]; ok {
+		s, ok := format.(string)
+		if !ok {
+			return schemaError(ptr+"/format", "must be a string")
+		}
+		n.format = s
+	}
+
+	// number
+	for keyword, target := range map[string]**float64{
+		"minimum":          &n.minimum,
+		"maximum":          &n.maximum,
+		"exclusiveMinimum": &n.exclusiveMinimum,
+		"exclusiveMaximum": &n.exclusiveMaximum,
+		"multipleOf":       &n.multipleOf,
+	} {
+		if *target, err = numberKeyword(m, keyword, ptr); err != nil {
+			return err
+		}
+	}
+	if n.multipleOf != nil && *n.multipleOf <= 0 {
+		return schemaError(ptr+"/multipleOf", "must be greater than 0")
+	}
+
+	// 組み合わせ
+	if list, ok := m["allOf"]; ok {
+		if n.allOf, err = c.compileList(list, ptr+"/allOf"); err != nil {
+			return err
+		}
+	}
+	if list, ok := m["anyOf"]; ok {
+		if n.anyOf, err = c.compileList(list, ptr+"/anyOf"); err != nil {
+			return err
+		}
+	}
+	if list, ok := m["oneOf"]; ok {
+		if n.oneOf, err = c.compileList(list, ptr+"/oneOf"); err != nil {
+			return err
+		}
+	}
+	if not, ok := m["not"]; ok {
+		if n.not, err = c.compile(not, ptr+"/not"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *compiler) compileList(raw interface{}, ptr string) ([]*node, error) {
+	list, ok := raw.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, schemaError(ptr, "must be a non-empty array of schemas")
+	}
+	nodes := make([]*node, len(list))
+	for i, item := range list {
+		var err error
+		if nodes[i], err = c.compile(item, ptr+"/"+strconv.Itoa(i)); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// resolve はドキュメント内の$ref（"#" または "#/$defs/name" 形式のJSONポインタ）を解決する
+// 再帰的な参照に対応するため、コンパイル前にノードを登録する
+func (c *compiler) resolve(ref, ptr string) (*node, error) {
+	if n, ok := c.refs[ref]; ok {
+		return n, nil
+	}
+	if !strings.HasPrefix(ref, "#") {
+		return nil, schemaError(ptr, "only local references (#/...) are supported")
+	}
+
+	target := c.root
+	path := strings.TrimPrefix(ref, "#")
+	if path != "" {
+		if !strings.HasPrefix(path, "/") {
+			return nil, schemaError(ptr, "invalid reference %q", ref)
+		}
+		for _, token := range strings.Split(path[1:], "/") {
+			token = unescape(token)
+			switch v := target.(type) {
+			case map[string]interface{}:
+				var ok bool
+				if target, ok = v[token]; !ok {
+					return nil, schemaError(ptr, "reference %q not found", ref)
+				}
+			case []interface{}:
+				i, err := strconv.Atoi(token)
+				if err != nil || i < 0 || i >= len(v) {
+					return nil, schemaError(ptr, "reference %q not found", ref)
+				}
+				target = v[i]
+			default:
+				return nil, schemaError(ptr, "reference %q not found", ref)
+			}
+		}
+	}
+
+	n := &node{}
+	c.refs[ref] = n
+	switch v := target.(type) {
+	case bool:
+		n.always = &v
+	case map[string]interface{}:
+		if err := c.compileObject(n, v, path); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, schemaError(ptr, "reference %q does not point to a schema", ref)
+	}
+	return n, nil
+}
+
+func intKeyword(m map[string]interface{}, keyword, ptr string) (*int, error) {
+	raw, ok := m[keyword]
+	if !ok {
+		return nil, nil
+	}
+	f, ok := raw.(float64)
+	if !ok || f < 0 || f != float64(int(f)) {
+		return nil, schemaError(ptr+"/"+keyword, "must be a non-negative integer")
+	}
+	n := int(f)
+	return &n, nil
+}
+
+func numberKeyword(m map[string]interface{}, keyword, ptr string) (*float64, error) {
+	raw, ok := m[keyword]
+	if !ok {
+		return nil, nil
+	}
+	f, ok := raw.(float64)
+	if !ok {
+		return nil, schemaError(ptr+"/"+keyword, "must be a number")
+	}
+	return &f, nil
+}
+
+// escape はJSONポインタのトークンをエスケープする（RFC 6901）
+func escape(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func unescape(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,133 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		schema      string
+		wantPointer string
+		wantErr     string
+	}{
+		{name: "invalid JSON", schema: `{`, wantErr: "invalid JSON"},
+		{name: "not an object", schema: `"string"`, wantErr: "must be an object or a boolean"},
+		{name: "unsupported keyword", schema: `{"if": {"type": "string"}}`, wantErr: `keyword "if" is not supported`},
+		{name: "unknown type", schema: `{"type": "integer64"}`, wantPointer: "/type", wantErr: `unknown type "integer64"`},
+		{name: "required not strings", schema: `{"required": [1]}`, wantPointer: "/required", wantErr: "must be an array of strings"},
+		{name: "invalid pattern", schema: `{"type": "string", "pattern": "("}`, wantPointer: "/pattern", wantErr: "invalid regular expression"},
+		{name: "multipleOf zero", schema: `{"multipleOf": 0}`, wantPointer: "/multipleOf", wantErr: "must be greater than 0"},
+		{name: "empty anyOf", schema: `{"anyOf": []}`, wantPointer: "/anyOf", wantErr: "must be a non-empty array of schemas"},
+		{name: "nested property", schema: `{"properties": {"a": {"type": 1}}}`, wantPointer: "/properties/a/type", wantErr: "must be a string or an array of strings"},
+		{name: "remote reference", schema: `{"$ref": "https://example.com/schema.json"}`, wantErr: "only local references"},
+		{name: "missing reference", schema: `{"$ref": "#/$defs/missing"}`, wantErr: `reference "#/$defs/missing" not found`},
+		{name: "reference to non-schema", schema: `{"$defs": {"n": 1}, "$ref": "#/$defs/n"}`, wantErr: "does not point to a schema"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile([]byte(tt.schema))
+			if err == nil {
+				t.Fatal("Compile() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Compile() error = %v, want %q", err, tt.wantErr)
+			}
+			var schemaErr *SchemaError
+			if tt.wantPointer != "" && (!errors.As(err, &schemaErr) || schemaErr.Pointer != tt.wantPointer) {
+				t.Errorf("Compile() error = %#v, want pointer %q", err, tt.wantPointer)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	user := `{
+		"type": "object",
+		"required": ["name", "email"],
+		"properties": {
+			"name": {"type": "string", "minLength": 1, "maxLength": 5},
+			"email": {"type": "string", "format": "email"},
+			"age": {"type": "integer", "minimum": 0},
+			"role": {"enum": ["admin", "member"]},
+			"tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true},
+			"a/b": {"type": "boolean"}
+		},
+		"additionalProperties": false
+	}`
+	tree := `{
+		"$defs": {
+			"node": {
+				"type": "object",
+				"required": ["value"],
+				"properties": {
+					"value": {"type": "number"},
+					"children": {"type": "array", "items": {"$ref": "#/$defs/node"}}
+				}
+			}
+		},
+		"$ref": "#/$defs/node"
+	}`
+
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		want   map[string]string // nilの場合は違反なし
+	}{
+		{name: "valid object", schema: user, value: `{"name": "alice", "email": "a@example.com", "age": 20, "tags": ["x", "y"]}`},
+		{name: "required", schema: user, value: `{}`, want: map[string]string{"/name": "is required", "/email": "is required"}},
+		{name: "root type", schema: user, value: `[]`, want: map[string]string{"": "must be of type object"}},
+		{name: "property type", schema: user, value: `{"name": 1, "email": "a@example.com"}`, want: map[string]string{"/name": "must be of type string"}},
+		{name: "integer", schema: user, value: `{"name": "a", "email": "a@example.com", "age": 1.5}`, want: map[string]string{"/age": "must be of type integer"}},
+		{name: "minimum", schema: user, value: `{"name": "a", "email": "a@example.com", "age": -1}`, want: map[string]string{"/age": "must be >= 0"}},
+		{name: "string length", schema: user, value: `{"name": "", "email": "a@example.com"}`, want: map[string]string{"/name": "must be at least 1 characters"}},
+		{name: "length counts characters", schema: user, value: `{"name": "あいうえお", "email": "a@example.com"}`},
+		{name: "format", schema: user, value: `{"name": "a", "email": "not an email"}`, want: map[string]string{"/email": "must be a valid email"}},
+		{name: "enum", schema: user, value: `{"name": "a", "email": "a@example.com", "role": "owner"}`, want: map[string]string{"/role": `must be one of ["admin","member"]`}},
+		{name: "array items", schema: user, value: `{"name": "a", "email": "a@example.com", "tags": ["x", 1]}`, want: map[string]string{"/tags/1": "must be of type string"}},
+		{name: "unique items", schema: user, value: `{"name": "a", "email": "a@example.com", "tags": ["x", "x"]}`, want: map[string]string{"/tags": "must not contain duplicate items (0 and 1)"}},
+		{name: "additional properties", schema: user, value: `{"name": "a", "email": "a@example.com", "admin": true}`, want: map[string]string{"/admin": "is not allowed"}},
+		{name: "escaped pointer", schema: user, value: `{"name": "a", "email": "a@example.com", "a/b": "yes"}`, want: map[string]string{"/a~1b": "must be of type boolean"}},
+		{name: "multiple violations at one pointer", schema: `{"type": "string", "minLength": 3, "pattern": "^[a-z]+$"}`, value: `"A"`, want: map[string]string{"": "must be at least 3 characters; must match pattern ^[a-z]+$"}},
+		{name: "recursive reference", schema: tree, value: `{"value": 1, "children": [{"value": 2, "children": [{"value": 3}]}]}`},
+		{name: "recursive reference violation", schema: tree, value: `{"value": 1, "children": [{"value": 2, "children": [{}]}]}`, want: map[string]string{"/children/0/children/0/value": "is required"}},
+		{name: "anyOf", schema: `{"anyOf": [{"type": "string"}, {"type": "number"}]}`, value: `true`, want: map[string]string{"": "must match at least one schema in anyOf"}},
+		{name: "oneOf matches both", schema: `{"oneOf": [{"type": "number"}, {"minimum": 0}]}`, value: `1`, want: map[string]string{"": "must match exactly one schema in oneOf (matched 2)"}},
+		{name: "oneOf matches one", schema: `{"oneOf": [{"type": "number"}, {"minimum": 0}]}`, value: `"x"`},
+		{name: "not", schema: `{"not": {"type": "null"}}`, value: `null`, want: map[string]string{"": "must not match the schema in not"}},
+		{name: "false schema", schema: `false`, value: `1`, want: map[string]string{"": "is not allowed"}},
+		{name: "true schema", schema: `true`, value: `{"any": "thing"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := Compile([]byte(tt.schema))
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			var value interface{}
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatal(err)
+			}
+
+			err = schema.Validate(value)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate() error = %v, want *ValidationError", err)
+			}
+			if got := validationErr.Details(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() details = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Violation はスキーマ違反を表す
+type Violation struct {
+	Pointer string // 違反箇所のJSONポインタ（ルートは空文字列）
+	Message string
+}
+
+// ValidationError はスキーマ違反の一覧
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		parts = append(parts, pointerLabel(v.Pointer)+": "+v.Message)
+	}
+	return "schema validation failed: " + strings.Join(parts, "; ")
+}
+
+// Details は違反をJSONポインタ → メッセージのマップで返す
+// 同じ箇所に複数の違反がある場合はメッセージを連結する
+func (e *ValidationError) Details() map[string]string {
+	details := make(map[string]string, len(e.Violations))
+	for _, v := range e.Violations {
+		if existing, ok := details[v.Pointer]; ok {
+			details[v.Pointer] = existing + "; " + v.Message
+			continue
+		}
+		details[v.Pointer] = v.Message
+	}
+	return details
+}
+
+func pointerLabel(ptr string) string {
+	if ptr == "" {
+		return "(root)"
+	}
+	return ptr
+}
+
+var (
+	emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+$`)
+	uuidPattern  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// Validate は値（JSONとしてデコード済み）を検証し、違反があれば*ValidationErrorを返す
+func (s *Schema) Validate(value interface{}) error {
+	v := &validator{}
+	v.validate(s.root, value, "")
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: v.violations}
+}
+
+type validator struct {
+	violations []Violation
+}
+
+func (v *validator) add(ptr, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{Pointer: ptr, Message: fmt.Sprintf(format, args...)})
+}
+
+// matches は違反を記録せずにスキーマに一致するかどうかを返す（anyOf/oneOf/not用）
+func matches(n *node, value interface{}) bool {
+	sub := &validator{}
+	sub.validate(n, value, "")
+	return len(sub.violations) == 0
+}
+
+func (v *validator) validate(n *node, value interface{}, ptr string) {
+	if n.always != nil {
+		if !*n.always {
+			v.add(ptr, "is not allowed")
+		}
+		return
+	}
+
+	if n.ref != nil {
+		v.validate(n.ref, value, ptr)
+	}
+
+	if len(n.types) > 0 && !matchesType(value, n.types) {
+		v.add(ptr, "must be of type %s", strings.Join(n.types, " or "))
+		// 型が異なる場合は型固有のキーワードを検証しない
+		return
+	}
+
+	if n.enum != nil && !containsValue(n.enum, value) {
+		v.add(ptr, "must be one of %s", encode(n.enum))
+	}
+	if n.constant != nil && !equal(*n.constant, value) {
+		v.add(ptr, "must be equal to %s", encode(*n.constant))
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(n, val, ptr)
+	case []interface{}:
+		v.validateArray(n, val, ptr)
+	case string:
+		v.validateString(n, val, ptr)
+	default:
+		if f, ok := toFloat(value); ok {
+			v.validateNumber(n, f, ptr)
+		}
+	}
+
+	for _, sub := range n.allOf {
+		v.validate(sub, value, ptr)
+	}
+	if len(n.anyOf) > 0 {
+		matched := false
+		for _, sub := range n.anyOf {
+			if matches(sub, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.add(ptr, "must match at least one schema in anyOf")
+		}
+	}
+	if len(n.oneOf) > 0 {
+		count := 0
+		for _, sub := range n.oneOf {
+			if matches(sub, value) {
+				count++
+			}
+		}
+		if count != 1 {
+			v.add(ptr, "must match exactly one schema in oneOf (matched %d)", count)
+		}
+	}
+	if n.not != nil && matches(n.not, value) {
+		v.add(ptr, "must not match the schema in not")
+	}
+}
+
+func (v *validator) validateObject(n *node, obj map[string]interface{}, ptr string) {
+	for _, name := range n.required {
+		if _, ok := obj[name]; !ok {
+			v.add(ptr+"/"+escape(name), "is required")
+		}
+	}
+	if n.minProperties != nil && len(obj) < *n.minProperties {
+		v.add(ptr, "must have at least %d properties", *n.minProperties)
+	}
+	if n.maxProperties != nil && len(obj) > *n.maxProperties {
+		v.add(ptr, "must have at most %d properties", *n.maxProperties)
+	}
+
+	for _, name := range sortedKeys(obj) {
+		childPtr := ptr + "/" + escape(name)
+		if sub, ok := n.properties[name]; ok {
+			v.validate(sub, obj[name], childPtr)
+			continue
+		}
+		if n.additional != nil {
+			v.validate(n.additional, obj[name], childPtr)
+		}
+	}
+}
+
+func (v *validator) validateArray(n *node, items []interface{}, ptr string) {
+	if n.minItems != nil && len(items) < *n.minItems {
+		v.add(ptr, "must contain at least %d items", *n.minItems)
+	}
+	if n.maxItems != nil && len(items) > *n.maxItems {
+		v.add(ptr, "must contain at most %d items", *n.maxItems)
+	}
+	if n.uniqueItems {
+	unique:
+		for i := range items {
+			for j := i + 1; j < len(items); j++ {
+				if equal(items[i], items[j]) {
+					v.add(ptr, "must not contain duplicate items (%d and %d)", i, j)
+					break unique
+				}
+			}
+		}
+	}
+
+	for i, item := range items {
+		childPtr := ptr + "/" + strconv.Itoa(i)
+		if i < len(n.prefixItems) {
+			v.validate(n.prefixItems[i], item, childPtr)
+			continue
+		}
+		if n.items != nil {
+			v.validate(n.items, item, childPtr)
+		}
+	}
+}
+
+func (v *validator) validateString(n *node, s string, ptr string) {
+	length := utf8.RuneCountInString(s)
+	if n.minLength != nil && length < *n.minLength {
+		v.add(ptr, "must be at least %d characters", *n.minLength)
+	}
+	if n.maxLength != nil && length > *n.maxLength {
+		v.add(ptr, "must be at most %d characters", *n.maxLength)
+	}
+	if n.pattern != nil && !n.pattern.MatchString(s) {
+		v.add(ptr, "must match pattern %s", n.pattern.String())
+	}
+	if n.format != "" && !validFormat(n.format, s) {
+		v.add(ptr, "must be a valid %s", n.format)
+	}
+}
+
+func (v *validator) validateNumber(n *node, f float64, ptr string) {
+	if n.minimum != nil && f < *n.minimum {
+		v.add(ptr, "must be >= %s", formatNumber(*n.minimum))
+	}
+	if n.maximum != nil && f > *n.maximum {
+		v.add(ptr, "must be <= %s", formatNumber(*n.maximum))
+	}
+	if n.exclusiveMinimum != nil && f <= *n.exclusiveMinimum {
+		v.add(ptr, "must be > %s", formatNumber(*n.exclusiveMinimum))
+	}
+	if n.exclusiveMaximum != nil && f >= *n.exclusiveMaximum {
+		v.add(ptr, "must be < %s", formatNumber(*n.exclusiveMaximum))
+	}
+	if n.multipleOf != nil {
+		q := f / *n.multipleOf
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			v.add(ptr, "must be a multiple of %s", formatNumber(*n.multipleOf))
+		}
+	}
+}
+
+// validFormat はformatを検証する（未知のformatは注釈として扱い、常に有効とする）
+func validFormat(format, s string) bool {
+	switch format {
+	case "email":
+		if !emailPattern.MatchString(s) {
+			return false
+		}
+		_, err := mail.ParseAddress(s)
+		return err == nil
+	case "uuid":
+		return uuidPattern.MatchString(s)
+	case "date-time":
+		_, err := time.Parse(time.RFC3339Nano, s)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	case "time":
+		_, err := time.Parse("15:04:05Z07:00", s)
+		return err == nil
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != ""
+	case "ipv4":
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil && !strings.Contains(s, ":")
+	case "ipv6":
+		ip := net.ParseIP(s)
+		return ip != nil && strings.Contains(s, ":")
+	default:
+		return true
+	}
+}
+
+func matchesType(value interface{}, types []string) bool {
+	for _, typ := range types {
+		switch typ {
+		case "null":
+			if value == nil {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "object":
+			if _, ok := value.(map[string]interface{}); ok {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]interface{}); ok {
+				return true
+			}
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := toFloat(value); ok {
+				return true
+			}
+		case "integer":
+			if f, ok := toFloat(value); ok && f == math.Trunc(f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// equal はJSONの値として等しいかどうかを返す（数値は型によらず値で比較する）
+func equal(a, b interface{}) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func containsValue(list []interface{}, value interface{}) bool {
+	for _, item := range list {
+		if equal(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func encode(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
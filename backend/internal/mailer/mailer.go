@@ -0,0 +1,81 @@
+// Package mailer はメールの送信を提供する
+// 送信方法はMailerインターフェースで差し替えられる。開発用にログ出力・ファイル保存の実装を持つ
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Message は送信するメール（本文はプレーンテキスト）
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer はメールを送信する
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// New は送信方法（log・file）に対応するMailerを作成する
+// fileの場合はdirにメールを保存する
+func New(driver, dir string) (Mailer, error) {
+	switch driver {
+	case "", "log":
+		return LogMailer{}, nil
+	case "file":
+		return NewFileMailer(dir)
+	}
+	return nil, fmt.Errorf("unknown mailer %q (expected log or file)", driver)
+}
+
+// LogMailer はメールを送信せず、サーバーのログに出力する（開発用）
+type LogMailer struct{}
+
+// Send はメールをログに出力する
+func (LogMailer) Send(ctx context.Context, msg *Message) error {
+	log.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileMailer はメールを送信せず、ディレクトリに.emlファイルとして保存する（開発用）
+type FileMailer struct {
+	dir string
+	seq atomic.Uint64
+}
+
+// NewFileMailer は新しいFileMailerを作成する（ディレクトリが存在しない場合は作成する）
+func NewFileMailer(dir string) (*FileMailer, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("mail directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir}, nil
+}
+
+// Send はメールをファイルに保存する
+func (m *FileMailer) Send(ctx context.Context, msg *Message) error {
+	now := time.Now()
+	name := fmt.Sprintf("%s-%04d.eml", now.UTC().Format("20060102T150405.000000000"), m.seq.Add(1)%10000)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&sb, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&sb, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&sb, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	sb.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	sb.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return os.WriteFile(filepath.Join(m.dir, name), []byte(sb.String()), 0o600)
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// 管理者のロール（上位のロールは下位のロールの権限をすべて持つ）
+const (
+	AdminRoleOwner  = "owner"  // すべての操作（管理者・認証設定の管理を含む）
+	AdminRoleEditor = "editor" // テーブル・エンドポイント・サブフローの作成・更新・削除
+	AdminRoleViewer = "viewer" // 参照のみ
+)
+
+// AdminRoles は管理者のロール（権限の強い順）
+var AdminRoles = []string{AdminRoleOwner, AdminRoleEditor, AdminRoleViewer}
+
+// AdminRoleGrants はロールが持つ権限のロール（自身と下位のロール）を返す
+// 例: editor → [editor viewer]。不明なロールの場合はnil
+func AdminRoleGrants(role string) []string {
+	i := slices.Index(AdminRoles, role)
+	if i < 0 {
+		return nil
+	}
+	return slices.Clone(AdminRoles[i:])
+}
+
+// AdminUser は管理者アカウントを表す
+type AdminUser struct {
+	ID          string     `json:"id"`
+	Email       string     `json:"email"`
+	Role        string     `json:"role"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// AdminUsersResponse は管理者一覧レスポンス
+type AdminUsersResponse struct {
+	Admins []AdminUser `json:"admins"`
+}
+
+// AdminLoginRequest は管理者のログインリクエスト
+type AdminLoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// AdminLoginResponse は管理者のセッショントークンの発行レスポンス
+type AdminLoginResponse struct {
+	AccessToken string     `json:"access_token"`
+	TokenType   string     `json:"token_type"` // Bearer
+	ExpiresIn   int        `json:"expires_in"` // セッションの有効期間（秒）
+	Admin       *AdminUser `json:"admin"`
+}
+
+// CreateAdminUserRequest は管理者作成リクエスト
+type CreateAdminUserRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// UpdateAdminUserRequest は管理者更新リクエスト（省略した項目は変更しない）
+// パスワードを変更した場合は、その管理者のすべてのセッションが失効する
+type UpdateAdminUserRequest struct {
+	Role     string `json:"role"`
+	Password string `json:"password"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// APIKey はサーバー間の呼び出しに使用するAPIキーを表す（キー自体は作成時のみ返す）
+// EndpointsまたはTagsに一致するエンドポイントのみを呼び出せる
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // キーの先頭部分（キーの識別に使用する）
+	Scopes     []string   `json:"scopes"`
+	Endpoints  []string   `json:"endpoints"` // 呼び出せるエンドポイントのID
+	Tags       []string   `json:"tags"`      // 呼び出せるエンドポイントのタグ
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedBy  *string    `json:"created_by"` // 作成した管理者のID
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APIKeysResponse はAPIキー一覧レスポンス
+type APIKeysResponse struct {
+	APIKeys []APIKey `json:"api_keys"`
+}
+
+// CreateAPIKeyRequest はAPIキー作成リクエスト
+// EndpointsとTagsのいずれか（または両方）の指定が必要
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	Endpoints []string   `json:"endpoints"`
+	Tags      []string   `json:"tags"`
+	ExpiresAt *time.Time `json:"expires_at"` // 省略時は無期限
+}
+
+// CreateAPIKeyResponse はAPIキー作成レスポンス（Keyはこのレスポンスでのみ返す）
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
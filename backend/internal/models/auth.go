@@ -4,17 +4,33 @@ import "time"
 
 // AuthSettings は認証設定を表す
 type AuthSettings struct {
-	ID        string                 `json:"id"`
-	Method    string                 `json:"method"`
-	Config    map[string]interface{} `json:"config"`
-	CreatedAt time.Time              `json:"created_at"`
-	UpdatedAt time.Time              `json:"updated_at"`
+	ID        string     `json:"id"`
+	Method    string     `json:"method"`
+	Config    AuthConfig `json:"config"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// AuthConfig はメール＋パスワード認証の設定（パスワードポリシーとメールアドレスの確認）
+type AuthConfig struct {
+	MinPasswordLength  int  `json:"min_password_length"`
+	RequireSpecialChar bool `json:"require_special_char"` // 記号を1文字以上含む
+	RequireNumber      bool `json:"require_number"`       // 数字を1文字以上含む
+	EmailVerification  bool `json:"email_verification"`   // ログインの前にメールアドレスの確認を必須にする
+}
+
+// DefaultAuthConfig は認証設定の既定値（保存された設定で省略された項目にも使用する）
+var DefaultAuthConfig = AuthConfig{
+	MinPasswordLength:  8,
+	RequireSpecialChar: true,
+	RequireNumber:      true,
+	EmailVerification:  true,
 }
 
 // UpdateAuthSettingsRequest は認証設定更新リクエスト
 type UpdateAuthSettingsRequest struct {
-	Method string                 `json:"method" validate:"required,oneof=email oauth"`
-	Config map[string]interface{} `json:"config" validate:"required"`
+	Method string      `json:"method" validate:"required,oneof=email"`
+	Config *AuthConfig `json:"config" validate:"required"`
 }
 
 // AuthField はユーザーフィールドを表す
@@ -0,0 +1,75 @@
+package models
+
+// TestEndpointRequest はエンドポイントのテスト実行（ドライラン）リクエスト
+// Flow と Version を省略した場合は最後に保存されたフロー（下書き）を実行する
+type TestEndpointRequest struct {
+	Flow    *Flow       `json:"flow"`
+	Version *int        `json:"version"`
+	Request TestRequest `json:"request"`
+	Commit  bool        `json:"commit"` // trueの場合のみデータベースへの書き込みを確定する
+}
+
+// TestRequest はテスト実行でフローに渡すリクエスト
+// Path を指定した場合はエンドポイントのパスとマッチさせてパスパラメータを取り出す
+type TestRequest struct {
+	Method  string                 `json:"method"`
+	Path    string                 `json:"path"`
+	Params  map[string]interface{} `json:"params"`
+	Query   map[string]interface{} `json:"query"`
+	Headers map[string]string      `json:"headers"`
+	Cookies map[string]string      `json:"cookies"`
+	Body    interface{}            `json:"body"`
+	Form    map[string]interface{} `json:"form"`
+	// Auth はフローに渡す呼び出し元（エンドポイントの認証設定は検証しない）
+	Auth *Principal `json:"auth"`
+}
+
+// TestEndpointResponse はテスト実行の結果
+type TestEndpointResponse struct {
+	Response   *TestResponse `json:"response"`
+	Error      *TestError    `json:"error"`
+	Trace      []TraceEntry  `json:"trace"`
+	Committed  bool          `json:"committed"`
+	DurationMs float64       `json:"duration_ms"`
+}
+
+// TestResponse はフローが生成したレスポンス
+type TestResponse struct {
+	StatusCode int                 `json:"status_code"`
+	Headers    map[string][]string `json:"headers"`
+	Body       interface{}         `json:"body"`
+	Format     string              `json:"format"` // json以外の場合、bodyは文字列
+}
+
+// TestError はテスト実行中に発生したエラー
+type TestError struct {
+	Code    string      `json:"code"`
+	Class   string      `json:"class"` // エラーの分類（validation, not_found, conflict, timeout, limit, internal）
+	Message string      `json:"message"`
+	NodeID  string      `json:"node_id,omitempty"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// ノードの実行状態
+const (
+	TraceExecuted = "executed"
+	TraceFailed   = "failed"
+	TraceSkipped  = "skipped"
+)
+
+// TraceEntry はノード1つ分の実行トレース
+type TraceEntry struct {
+	NodeID   string                 `json:"node_id"`
+	NodeType string                 `json:"node_type"`
+	Label    string                 `json:"label,omitempty"`
+	Status   string                 `json:"status"`
+	Inputs   map[string]interface{} `json:"inputs,omitempty"`
+	// Config は設定の式（{{ }}）を評価した結果（式を含む場合のみ）
+	Config     map[string]interface{} `json:"config,omitempty"`
+	Outputs    map[string]interface{} `json:"outputs,omitempty"`
+	StartedMs  float64                `json:"started_ms"`
+	DurationMs float64                `json:"duration_ms"`
+	Error      string                 `json:"error,omitempty"`
+	// Iterations はノードの本体（ForEachノードのループ本体）の要素ごとのトレース
+	Iterations [][]TraceEntry `json:"iterations,omitempty"`
+}
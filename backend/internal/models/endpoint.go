@@ -1,35 +1,134 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
 
 // Endpoint はAPIエンドポイントのメタデータを表す
+// Flow・Path・Limits・Auth・RequestSchema は最後に保存されたバージョン（下書き）の値で、
+// Runtime APIはDeployedVersionのフローと設定で実行する
+// Name・Method・Tags はバージョンに含まれず、更新すると即座にRuntime APIに反映される
 type Endpoint struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Method    string    `json:"method"`
-	Path      string    `json:"path"`
-	Flow      Flow      `json:"flow"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              string          `json:"id"`
+	Name            string          `json:"name"`
+	Method          string          `json:"method"`
+	Path            string          `json:"path"`
+	Flow            Flow            `json:"flow"`
+	Limits          EndpointLimits  `json:"limits"`
+	Auth            EndpointAuth    `json:"auth"`
+	Tags            []string        `json:"tags"`
+	RequestSchema   json.RawMessage `json:"request_schema,omitempty"`
+	Version         int             `json:"version"`
+	DeployedVersion *int            `json:"deployed_version"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
 }
 
 // CreateEndpointRequest はエンドポイント作成リクエスト
 type CreateEndpointRequest struct {
-	Name   string `json:"name" validate:"required"`
-	Method string `json:"method" validate:"required,oneof=GET POST PUT DELETE PATCH"`
-	Path   string `json:"path" validate:"required"`
-	Flow   Flow   `json:"flow" validate:"required"`
+	Name   string          `json:"name" validate:"required"`
+	Method string          `json:"method" validate:"required,oneof=GET POST PUT DELETE PATCH"`
+	Path   string          `json:"path" validate:"required"`
+	Flow   Flow            `json:"flow" validate:"required"`
+	Limits *EndpointLimits `json:"limits"`
+	Auth   *EndpointAuth   `json:"auth"` // 省略時はpublic
+	Tags   []string        `json:"tags"`
+	// RequestSchema はリクエストボディのJSON Schema（省略可）
+	RequestSchema json.RawMessage `json:"request_schema"`
 }
 
 // UpdateEndpointRequest はエンドポイント更新リクエスト
 type UpdateEndpointRequest struct {
-	Name   string `json:"name"`
-	Method string `json:"method" validate:"omitempty,oneof=GET POST PUT DELETE PATCH"`
-	Path   string `json:"path"`
-	Flow   Flow   `json:"flow"`
+	Name   string          `json:"name"`
+	Method string          `json:"method" validate:"omitempty,oneof=GET POST PUT DELETE PATCH"`
+	Path   string          `json:"path"`
+	Flow   Flow            `json:"flow"`
+	Limits *EndpointLimits `json:"limits"`
+	Auth   *EndpointAuth   `json:"auth"`
+	Tags   []string        `json:"tags"` // 省略時は変更しない（[]でタグを削除する）
+	// RequestSchema はnullを指定するとスキーマを削除する
+	RequestSchema json.RawMessage `json:"request_schema"`
+}
+
+// EndpointLimits はエンドポイントごとの実行制限
+// 0の項目はサーバー全体の設定値を使用し、全体の設定値を超える値は全体の設定値に切り詰められる
+type EndpointLimits struct {
+	MaxSteps         int `json:"max_steps"`          // ノードの最大実行回数
+	TimeoutMs        int `json:"timeout_ms"`         // フロー全体の実行時間（ミリ秒）
+	MaxRequestBytes  int `json:"max_request_bytes"`  // リクエストボディの最大サイズ
+	MaxResponseBytes int `json:"max_response_bytes"` // レスポンスボディの最大サイズ
+	MaxParallelism   int `json:"max_parallelism"`    // 同時に実行するノードの数
+}
+
+// エンドポイントの認証方式
+const (
+	AuthPublic = "public"  // 認証なし
+	AuthJWT    = "jwt"     // エンドユーザーのアクセストークン（内部IdPのJWT）
+	AuthAPIKey = "api_key" // APIキー
+	AuthAdmin  = "admin"   // 管理者
+)
+
+// ValidAuthModes はエンドポイントで使用できる認証方式
+var ValidAuthModes = map[string]bool{
+	AuthPublic: true,
+	AuthJWT:    true,
+	AuthAPIKey: true,
+	AuthAdmin:  true,
+}
+
+// EndpointAuth はエンドポイントの認証・認可の設定
+// Rolesはいずれか1つを持つ呼び出し元（jwt・admin）、Scopesはすべてを持つ呼び出し元（api_key）のみを許可する
+type EndpointAuth struct {
+	Mode   string   `json:"mode"` // 空の場合はpublic
+	Roles  []string `json:"roles,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// Principal は認証された呼び出し元
+// フローからはリクエストの auth（式では {{ auth.sub }}）として参照できる
+type Principal struct {
+	Type     string   `json:"type"` // jwt, api_key, admin（認証方式）
+	Subject  string   `json:"sub"`  // ユーザーID・APIキーID・管理者ID
+	TenantID string   `json:"tenant_id,omitempty"`
+	Roles    []string `json:"roles"`
+	Scopes   []string `json:"scopes,omitempty"`
+	Email    string   `json:"email,omitempty"`
+}
+
+// MaxTagLength はエンドポイントのタグの最大の長さ
+const MaxTagLength = 50
+
+// ValidateTags はエンドポイント・APIキーのタグを検証し、問題があればメッセージを返す
+func ValidateTags(tags []string) string {
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		switch {
+		case tag == "" || strings.TrimSpace(tag) != tag:
+			return "Tags must be non-empty and must not have leading or trailing spaces"
+		case utf8.RuneCountInString(tag) > MaxTagLength:
+			return fmt.Sprintf("Tags must be at most %d characters", MaxTagLength)
+		case seen[tag]:
+			return fmt.Sprintf("Duplicate tag %q", tag)
+		}
+		seen[tag] = true
+	}
+	return ""
 }
 
 // EndpointsResponse はエンドポイント一覧レスポンス
 type EndpointsResponse struct {
 	Endpoints []Endpoint `json:"endpoints"`
 }
+
+// ValidEndpointMethods はエンドポイントで使用できるHTTPメソッド
+var ValidEndpointMethods = map[string]bool{
+	"GET":    true,
+	"POST":   true,
+	"PUT":    true,
+	"DELETE": true,
+	"PATCH":  true,
+}
@@ -4,17 +4,28 @@ package models
 type Flow struct {
 	Nodes       []Node       `json:"nodes" validate:"required,min=1"`
 	Connections []Connection `json:"connections"`
+	// ErrorResponses はフロー内で処理されなかったエラーの分類ごとのレスポンス（省略した分類は既定のレスポンス）
+	ErrorResponses map[string]ErrorMapping `json:"error_responses,omitempty"`
+}
+
+// ErrorMapping はエラーの分類に対応するHTTPレスポンス（省略した項目は既定値）
+type ErrorMapping struct {
+	StatusCode int    `json:"status_code,omitempty"`
+	Code       string `json:"code,omitempty"`
+	Message    string `json:"message,omitempty"`
 }
 
 // Node はフローのノードを表す
 type Node struct {
 	ID     string                 `json:"id" validate:"required"`
-	Type   string                 `json:"type" validate:"required,oneof=start database filter response process"`
+	Type   string                 `json:"type" validate:"required"`
 	Label  string                 `json:"label" validate:"required"`
 	X      float64                `json:"x"`
 	Y      float64                `json:"y"`
 	Config map[string]interface{} `json:"config"`
 	Pins   []Pin                  `json:"pins" validate:"required,min=1"`
+	// Body はノードが要素ごとに実行するサブグラフ（ForEachノードのみ）
+	Body *Flow `json:"body,omitempty"`
 }
 
 // Pin はノードのピン（入力/出力ポート）を表す
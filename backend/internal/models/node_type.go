@@ -0,0 +1,30 @@
+package models
+
+// NodeTypeInfo はノードタイプの定義を表す
+type NodeTypeInfo struct {
+	Type         string        `json:"type"`
+	Label        string        `json:"label"`
+	Description  string        `json:"description"`
+	ConfigSchema []ConfigField `json:"config_schema"`
+	// Branching がtrueのノードは、条件に一致した出力ピンのみをアクティブにする
+	Branching bool `json:"branching"`
+	// HasBody がtrueのノードは、bodyにサブグラフを持つ
+	HasBody bool `json:"has_body"`
+}
+
+// ConfigField はノード設定項目のスキーマを表す
+type ConfigField struct {
+	Key         string      `json:"key"`
+	Type        string      `json:"type"` // string, number, boolean, array, object, any
+	Required    bool        `json:"required"`
+	Default     interface{} `json:"default,omitempty"`
+	Enum        []string    `json:"enum,omitempty"`
+	Description string      `json:"description,omitempty"`
+	// Raw はスクリプトなど、文字列中の式（{{ }}）を評価しない設定の場合にtrue
+	Raw bool `json:"raw,omitempty"`
+}
+
+// NodeTypesResponse はノードタイプ一覧レスポンス
+type NodeTypesResponse struct {
+	NodeTypes []NodeTypeInfo `json:"node_types"`
+}
@@ -0,0 +1,85 @@
+package models
+
+import "time"
+
+// Subflow は他のフローから呼び出せる名前付きのサブフローを表す
+// 保存のたびに不変のバージョンを作成し、呼び出し側は特定のバージョンを参照する
+// Inputs・Outputs・Flow は最新のバージョンの定義
+type Subflow struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Inputs      []SubflowParam `json:"inputs"`
+	Outputs     []SubflowParam `json:"outputs"`
+	Flow        Flow           `json:"flow"`
+	Version     int            `json:"version"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// SubflowParam はサブフローの入力・出力の宣言
+type SubflowParam struct {
+	Name        string `json:"name"`
+	DataType    string `json:"data_type"` // 省略時はany
+	Required    bool   `json:"required,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// SubflowVersion はサブフローの不変なバージョンを表す
+type SubflowVersion struct {
+	ID        string         `json:"id"`
+	SubflowID string         `json:"subflow_id"`
+	Name      string         `json:"name"`
+	Version   int            `json:"version"`
+	Inputs    []SubflowParam `json:"inputs"`
+	Outputs   []SubflowParam `json:"outputs"`
+	Flow      *Flow          `json:"flow,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// SubflowsResponse はサブフロー一覧レスポンス
+type SubflowsResponse struct {
+	Subflows []Subflow `json:"subflows"`
+}
+
+// SubflowVersionsResponse はサブフローのバージョン一覧レスポンス
+type SubflowVersionsResponse struct {
+	Versions []SubflowVersion `json:"versions"`
+}
+
+// CreateSubflowRequest はサブフロー作成リクエスト
+type CreateSubflowRequest struct {
+	Name        string         `json:"name" validate:"required"`
+	Description string         `json:"description"`
+	Inputs      []SubflowParam `json:"inputs"`
+	Outputs     []SubflowParam `json:"outputs"`
+	Flow        Flow           `json:"flow" validate:"required"`
+}
+
+// UpdateSubflowRequest はサブフロー更新リクエスト
+// Inputs・Outputs・Flow のいずれかを指定した場合は新しいバージョンを作成する（省略した項目は最新のバージョンの値）
+type UpdateSubflowRequest struct {
+	Description *string         `json:"description"`
+	Inputs      *[]SubflowParam `json:"inputs"`
+	Outputs     *[]SubflowParam `json:"outputs"`
+	Flow        *Flow           `json:"flow"`
+}
+
+// SubflowDependent はサブフローを呼び出しているエンドポイント・サブフロー
+type SubflowDependent struct {
+	Kind   string `json:"kind"` // endpoint, subflow
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Method string `json:"method,omitempty"`
+	Path   string `json:"path,omitempty"`
+	// Draft・Deployed はエンドポイントの下書き・デプロイ中のバージョンが呼び出しているかどうか（サブフローの場合は最新のバージョン）
+	Draft    bool `json:"draft"`
+	Deployed bool `json:"deployed"`
+	// Versions は呼び出しているサブフローのバージョン
+	Versions []int `json:"versions"`
+}
+
+// SubflowDependentsResponse はサブフローの依存元一覧レスポンス
+type SubflowDependentsResponse struct {
+	Dependents []SubflowDependent `json:"dependents"`
+}
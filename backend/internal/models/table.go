@@ -11,6 +11,12 @@ type Table struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// ReadOnlyTables は内部IdPがログイン情報（ロール・テナント・認証情報とリフレッシュトークンの外部キー）を保存するユーザーテーブル
+// テーブル管理APIでのカラムの追加・削除と、フローのDatabaseノードからの書き込みを禁止する（参照は可能）
+var ReadOnlyTables = map[string]bool{
+	"users": true,
+}
+
 // CreateTableRequest はテーブル作成リクエスト
 type CreateTableRequest struct {
 	Name    string         `json:"name" validate:"required"`
@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// User はエンドユーザー（内部IdPのユーザー）を表す
+type User struct {
+	ID            string    `json:"id"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	TenantID      string    `json:"tenant_id,omitempty"`
+	Roles         []string  `json:"roles"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// SignupRequest はユーザー登録リクエスト
+type SignupRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// LoginRequest はログインリクエスト
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// RefreshRequest はトークン更新・ログアウトリクエスト
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TokenResponse はアクセストークン・リフレッシュトークンの発行レスポンス
+// ユーザー登録でメールアドレスの確認が必要な場合はトークンを含めず、VerificationRequiredをtrueとする
+type TokenResponse struct {
+	AccessToken          string `json:"access_token,omitempty"`
+	TokenType            string `json:"token_type,omitempty"` // Bearer
+	ExpiresIn            int    `json:"expires_in,omitempty"` // アクセストークンの有効期間（秒）
+	RefreshToken         string `json:"refresh_token,omitempty"`
+	VerificationRequired bool   `json:"verification_required,omitempty"`
+	User                 *User  `json:"user"`
+}
+
+// ChangePasswordRequest はパスワード変更リクエスト
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// VerifyEmailRequest はメールアドレスの確認リクエスト
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// ResendVerificationRequest は確認メールの再送リクエスト
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+// JWKS はJWTの検証に使用する公開鍵の一覧（RFC 7517）
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK はRSA公開鍵（RFC 7517）
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
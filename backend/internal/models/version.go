@@ -0,0 +1,77 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// FlowVersion はエンドポイントのフローの不変なバージョンを表す
+// フローとともにパス・実行制限・認証設定・リクエストスキーマを保存し、デプロイ・ロールバックでまとめて反映する
+type FlowVersion struct {
+	ID            string          `json:"id"`
+	EndpointID    string          `json:"endpoint_id"`
+	Version       int             `json:"version"`
+	Flow          *Flow           `json:"flow,omitempty"`
+	Path          string          `json:"path,omitempty"`
+	Limits        *EndpointLimits `json:"limits,omitempty"`
+	Auth          *EndpointAuth   `json:"auth,omitempty"`
+	RequestSchema json.RawMessage `json:"request_schema,omitempty"`
+	Deployed      bool            `json:"deployed"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// FlowVersionsResponse はバージョン一覧レスポンス
+type FlowVersionsResponse struct {
+	Versions []FlowVersion `json:"versions"`
+}
+
+// Deployment はデプロイ履歴を表す
+type Deployment struct {
+	ID         string    `json:"id"`
+	EndpointID string    `json:"endpoint_id"`
+	Version    int       `json:"version"`
+	Action     string    `json:"action"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DeploymentsResponse はデプロイ履歴一覧レスポンス
+type DeploymentsResponse struct {
+	Deployments []Deployment `json:"deployments"`
+}
+
+// RollbackRequest はロールバックリクエスト
+// Version を省略した場合は直前にデプロイされていたバージョンに戻す
+type RollbackRequest struct {
+	Version *int `json:"version"`
+}
+
+// FlowDiff は2つのバージョン間のフローの差分を表す
+type FlowDiff struct {
+	From        int             `json:"from"`
+	To          int             `json:"to"`
+	Nodes       NodesDiff       `json:"nodes"`
+	Connections ConnectionsDiff `json:"connections"`
+	// Settings は変更されたバージョンの設定（path・limits・auth・request_schema）
+	Settings []string `json:"settings"`
+}
+
+// NodesDiff はノードの差分
+type NodesDiff struct {
+	Added   []Node       `json:"added"`
+	Removed []Node       `json:"removed"`
+	Changed []NodeChange `json:"changed"`
+}
+
+// NodeChange は変更されたノードと変更されたフィールド
+type NodeChange struct {
+	ID     string   `json:"id"`
+	Fields []string `json:"fields"`
+	Before Node     `json:"before"`
+	After  Node     `json:"after"`
+}
+
+// ConnectionsDiff は接続の差分
+type ConnectionsDiff struct {
+	Added   []Connection `json:"added"`
+	Removed []Connection `json:"removed"`
+}
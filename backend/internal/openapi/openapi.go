@@ -0,0 +1,492 @@
+// Package openapi はデプロイされたエンドポイントからRuntime APIのOpenAPIドキュメントを生成する
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/necorox/FlowCore/backend/internal/flow/nodes"
+	"github.com/necorox/FlowCore/backend/internal/models"
+	"github.com/necorox/FlowCore/backend/internal/routing"
+)
+
+// Version は生成するドキュメントのOpenAPIバージョン（JSON Schema 2020-12と互換）
+const Version = "3.1.0"
+
+// Endpoint はドキュメントに含めるエンドポイント
+type Endpoint struct {
+	Name          string
+	Method        string
+	Path          string
+	Flow          *models.Flow
+	Auth          models.EndpointAuth
+	Tags          []string
+	RequestSchema json.RawMessage
+}
+
+// Generate はエンドポイントの一覧からOpenAPIドキュメントを生成する
+// パスパラメータはパステンプレート、その他のパラメータは開始ノードの宣言、
+// リクエストボディはエンドポイントのJSON Schema、securityはエンドポイントの認証設定から生成する
+func Generate(title, version string, endpoints []Endpoint) map[string]interface{} {
+	paths := map[string]interface{}{}
+	schemas := map[string]interface{}{
+		"ErrorResponse": errorResponseSchema,
+	}
+	securitySchemes := map[string]interface{}{}
+
+	for _, ep := range endpoints {
+		pattern, err := routing.ParsePattern(ep.Path)
+		if err != nil {
+			continue
+		}
+
+		path := routing.Prefix + templatePath(pattern)
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[path] = item
+		}
+		item[strings.ToLower(ep.Method)] = operation(ep, pattern, schemas, securitySchemes)
+	}
+
+	components := map[string]interface{}{
+		"schemas": schemas,
+	}
+	if len(securitySchemes) > 0 {
+		components["securitySchemes"] = securitySchemes
+	}
+	return map[string]interface{}{
+		"openapi": Version,
+		"info": map[string]interface{}{
+			"title":   title,
+			"version": version,
+		},
+		"paths":      paths,
+		"components": components,
+	}
+}
+
+// securitySchemeNames は認証方式 → components.securitySchemesの名前
+var securitySchemeNames = map[string]string{
+	models.AuthJWT:    "bearerAuth",
+	models.AuthAPIKey: "apiKeyAuth",
+	models.AuthAdmin:  "adminAuth",
+}
+
+// securitySchemeDefs は認証方式 → セキュリティスキーム
+var securitySchemeDefs = map[string]interface{}{
+	models.AuthJWT: map[string]interface{}{
+		"type":         "http",
+		"scheme":       "bearer",
+		"bearerFormat": "JWT",
+		"description":  "Access token issued by POST /auth/login",
+	},
+	models.AuthAPIKey: map[string]interface{}{
+		"type":        "apiKey",
+		"in":          "header",
+		"name":        "X-API-Key",
+		"description": "API key (also accepted as Authorization: Bearer <key>)",
+	},
+	models.AuthAdmin: map[string]interface{}{
+		"type":        "http",
+		"scheme":      "bearer",
+		"description": "Admin token",
+	},
+}
+
+var errorResponseSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []string{"error"},
+	"properties": map[string]interface{}{
+		"error": map[string]interface{}{
+			"type":     "object",
+			"required": []string{"code", "message"},
+			"properties": map[string]interface{}{
+				"code":    map[string]interface{}{"type": "string"},
+				"message": map[string]interface{}{"type": "string"},
+				"details": map[string]interface{}{},
+			},
+		},
+	},
+}
+
+func operation(ep Endpoint, pattern *routing.Pattern, schemas, securitySchemes map[string]interface{}) map[string]interface{} {
+	id := operationID(ep.Method, pattern)
+	op := map[string]interface{}{
+		"operationId": id,
+		"summary":     ep.Name,
+	}
+	if len(ep.Tags) > 0 {
+		op["tags"] = ep.Tags
+	}
+
+	// 認証が必要なエンドポイントはセキュリティスキームを参照する（OpenAPI 3.1ではロール・スコープを列挙できる）
+	schemeName, secured := securitySchemeNames[ep.Auth.Mode]
+	if secured {
+		securitySchemes[schemeName] = securitySchemeDefs[ep.Auth.Mode]
+		required := append(append([]string{}, ep.Auth.Roles...), ep.Auth.Scopes...)
+		op["security"] = []interface{}{map[string]interface{}{schemeName: required}}
+	}
+
+	params := startParams(ep.Flow)
+	validates := len(ep.RequestSchema) > 0
+
+	// パスパラメータはパステンプレートの型を使用する
+	parameters := []interface{}{}
+	for _, seg := range pattern.Params() {
+		parameters = append(parameters, map[string]interface{}{
+			"name":     seg.Value,
+			"in":       "path",
+			"required": true,
+			"schema":   pathParamSchema(seg.Type),
+		})
+		validates = validates || seg.Type != "string"
+	}
+
+	var bodyParams, formParams []nodes.Param
+	for _, p := range params {
+		validates = validates || p.Required || p.Type != "any"
+
+		in := p.In
+		if in == "" {
+			// 取得元が省略されたパラメータは、ボディを持たないメソッドではクエリとして扱う
+			if ep.Method == http.MethodGet || ep.Method == http.MethodDelete {
+				in = "query"
+			} else {
+				in = "body"
+			}
+		}
+
+		switch in {
+		case "query", "header", "cookie":
+			parameters = append(parameters, map[string]interface{}{
+				"name":     p.Name,
+				"in":       in,
+				"required": p.Required,
+				"schema":   paramSchema(p),
+			})
+		case "body":
+			bodyParams = append(bodyParams, p)
+		case "form", "file":
+			formParams = append(formParams, p)
+		}
+	}
+	if len(parameters) > 0 {
+		op["parameters"] = parameters
+	}
+
+	content := map[string]interface{}{}
+	if len(ep.RequestSchema) > 0 {
+		var raw interface{}
+		if err := json.Unmarshal(ep.RequestSchema, &raw); err == nil {
+			content["application/json"] = map[string]interface{}{
+				"schema": embedSchema(raw, id+".Request", schemas),
+			}
+		}
+	} else if len(bodyParams) > 0 {
+		content["application/json"] = map[string]interface{}{"schema": objectSchema(bodyParams)}
+	}
+	if len(formParams) > 0 {
+		mediaType := "application/x-www-form-urlencoded"
+		for _, p := range formParams {
+			if p.In == "file" {
+				mediaType = "multipart/form-data"
+			}
+		}
+		content[mediaType] = map[string]interface{}{"schema": objectSchema(formParams)}
+	}
+	if len(content) > 0 {
+		op["requestBody"] = map[string]interface{}{
+			"required": len(ep.RequestSchema) > 0,
+			"content":  content,
+		}
+	}
+
+	responses := flowResponses(ep.Flow)
+	if validates {
+		responses["400"] = errorResponse("Invalid request parameters")
+	}
+	if secured {
+		responses["401"] = errorResponse("Authentication required")
+		if len(ep.Auth.Roles) > 0 || len(ep.Auth.Scopes) > 0 {
+			responses["403"] = errorResponse("Insufficient permissions")
+		}
+	}
+	responses["500"] = errorResponse("Flow execution failed")
+	// error_responsesでステータスコードを指定したエラーの分類
+	if ep.Flow != nil {
+		for _, mapping := range ep.Flow.ErrorResponses {
+			code := strconv.Itoa(mapping.StatusCode)
+			if _, exists := responses[code]; mapping.StatusCode == 0 || exists {
+				continue
+			}
+			responses[code] = errorResponse("Flow error")
+		}
+	}
+	op["responses"] = responses
+
+	return op
+}
+
+func errorResponse(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{"$ref": "#/components/schemas/ErrorResponse"},
+			},
+		},
+	}
+}
+
+// templatePath はパステンプレートをOpenAPIのパス形式（/users/{id}）に変換する
+func templatePath(pattern *routing.Pattern) string {
+	if len(pattern.Segments) == 0 {
+		return "/"
+	}
+	var sb strings.Builder
+	for _, seg := range pattern.Segments {
+		sb.WriteString("/")
+		if seg.IsParam() {
+			sb.WriteString("{" + seg.Value + "}")
+		} else {
+			sb.WriteString(seg.Value)
+		}
+	}
+	return sb.String()
+}
+
+// operationID はメソッドとパスからoperationIdを生成する（例: get_users_id_items）
+func operationID(method string, pattern *routing.Pattern) string {
+	parts := []string{strings.ToLower(method)}
+	for _, seg := range pattern.Segments {
+		var sb strings.Builder
+		for _, r := range seg.Value {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+				sb.WriteRune(r)
+			} else {
+				sb.WriteRune('_')
+			}
+		}
+		parts = append(parts, sb.String())
+	}
+	return strings.Join(parts, "_")
+}
+
+func startParams(f *models.Flow) []nodes.Param {
+	if f == nil {
+		return nil
+	}
+	for _, node := range f.Nodes {
+		if node.Type == "start" {
+			params, _ := nodes.ParseParams(node.Config)
+			return params
+		}
+	}
+	return nil
+}
+
+// formatMediaTypes はResponseノードの形式 → メディアタイプ
+var formatMediaTypes = map[string]string{
+	"json": "application/json",
+	"text": "text/plain",
+	"html": "text/html",
+	"csv":  "text/csv",
+}
+
+// flowResponses はResponseノードのステータスコードと形式からレスポンスを生成する
+// ステータスコードが入力ピンから決まる場合はdefaultとする
+func flowResponses(f *models.Flow) map[string]interface{} {
+	mediaTypes := map[string]map[string]bool{}
+	if f != nil {
+		for _, node := range f.Nodes {
+			if node.Type != "response" {
+				continue
+			}
+			code := responseCode(node.Config)
+			if mediaTypes[code] == nil {
+				mediaTypes[code] = map[string]bool{}
+			}
+			format, _ := node.Config["format"].(string)
+			if mediaType, ok := formatMediaTypes[format]; ok {
+				mediaTypes[code][mediaType] = true
+			} else {
+				mediaTypes[code]["application/json"] = true
+			}
+		}
+	}
+	if len(mediaTypes) == 0 {
+		mediaTypes["200"] = map[string]bool{"application/json": true}
+	}
+
+	responses := make(map[string]interface{}, len(mediaTypes))
+	for code, types := range mediaTypes {
+		content := map[string]interface{}{}
+		for mediaType := range types {
+			if mediaType == "application/json" {
+				content[mediaType] = map[string]interface{}{"schema": map[string]interface{}{}}
+			} else {
+				content[mediaType] = map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+			}
+		}
+		responses[code] = map[string]interface{}{"description": "Response", "content": content}
+	}
+	return responses
+}
+
+func responseCode(config map[string]interface{}) string {
+	if pin, _ := config["statusPin"].(string); pin != "" {
+		return "default"
+	}
+	value, ok := config["statusCode"]
+	if !ok {
+		value, ok = config["status"]
+	}
+	if !ok {
+		return "200"
+	}
+	switch v := value.(type) {
+	case float64:
+		return strconv.Itoa(int(v))
+	case string:
+		if _, err := strconv.Atoi(v); err == nil {
+			return v
+		}
+	}
+	return "default"
+}
+
+func pathParamSchema(paramType string) map[string]interface{} {
+	switch paramType {
+	case "int":
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case "uuid":
+		return map[string]interface{}{"type": "string", "format": "uuid"}
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+// paramSchema は開始ノードのパラメータの型をJSON Schemaに変換する
+func paramSchema(p nodes.Param) map[string]interface{} {
+	var schema map[string]interface{}
+	switch p.Type {
+	case "string":
+		schema = map[string]interface{}{"type": "string"}
+	case "int":
+		schema = map[string]interface{}{"type": "integer"}
+	case "number":
+		schema = map[string]interface{}{"type": "number"}
+	case "bool":
+		schema = map[string]interface{}{"type": "boolean"}
+	case "uuid":
+		schema = map[string]interface{}{"type": "string", "format": "uuid"}
+	case "timestamp":
+		schema = map[string]interface{}{"type": "string", "format": "date-time"}
+	case "object":
+		schema = map[string]interface{}{"type": "object"}
+	case "array":
+		if p.In == "file" {
+			schema = map[string]interface{}{"type": "array", "items": fileSchema()}
+		} else {
+			schema = map[string]interface{}{"type": "array"}
+		}
+	case "file":
+		schema = fileSchema()
+	default:
+		schema = map[string]interface{}{}
+	}
+	if p.Default != nil {
+		schema["default"] = p.Default
+	}
+	return schema
+}
+
+func fileSchema() map[string]interface{} {
+	return map[string]interface{}{"type": "string", "contentMediaType": "application/octet-stream"}
+}
+
+func objectSchema(params []nodes.Param) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for _, p := range params {
+		properties[p.Name] = paramSchema(p)
+		if p.Required {
+			required = append(required, p.Name)
+		}
+	}
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// embedSchema はリクエストのJSON Schemaをcomponents.schemasに登録し、参照を返す
+// $defs・definitionsは "<name>.<定義名>" として登録し、ローカル参照をcomponentsへの参照に書き換える
+func embedSchema(raw interface{}, name string, schemas map[string]interface{}) map[string]interface{} {
+	root, ok := raw.(map[string]interface{})
+	if !ok {
+		schemas[name] = raw
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+
+	body := map[string]interface{}{}
+	for key, value := range root {
+		switch key {
+		case "$defs", "definitions":
+			defs, _ := value.(map[string]interface{})
+			for defName, def := range defs {
+				schemas[name+"."+defName] = rewriteRefs(def, name)
+			}
+		case "$schema", "$id":
+		default:
+			body[key] = rewriteRefs(value, name)
+		}
+	}
+	schemas[name] = body
+
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+func rewriteRefs(value interface{}, name string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if ref, ok := item.(string); ok && key == "$ref" {
+				out[key] = rewriteRef(ref, name)
+				continue
+			}
+			out[key] = rewriteRefs(item, name)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = rewriteRefs(item, name)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+func rewriteRef(ref, name string) string {
+	if ref == "#" {
+		return "#/components/schemas/" + name
+	}
+	for _, prefix := range []string{"#/$defs/", "#/definitions/"} {
+		if rest, ok := strings.CutPrefix(ref, prefix); ok {
+			defName, remainder, _ := strings.Cut(rest, "/")
+			defName = strings.ReplaceAll(strings.ReplaceAll(defName, "~1", "/"), "~0", "~")
+			if remainder != "" {
+				remainder = "/" + remainder
+			}
+			return "#/components/schemas/" + name + "." + defName + remainder
+		}
+	}
+	return ref
+}
@@ -0,0 +1,217 @@
+// Package routing はRuntime APIのパステンプレートとルーティングを提供する
+package routing
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Prefix はRuntime APIのパスプレフィックス
+// エンドポイントのパスはプレフィックスを除いた形で保存される
+const Prefix = "/api"
+
+// NormalizePath はパスを保存・照合用の形に正規化する
+// 先頭の /api プレフィックスと末尾のスラッシュを取り除く
+func NormalizePath(path string) string {
+	if path == Prefix || strings.HasPrefix(path, Prefix+"/") {
+		path = path[len(Prefix):]
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	if len(path) > 1 {
+		path = strings.TrimRight(path, "/")
+	}
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// セグメントの種類（値が小さいほど優先度が高い）
+const (
+	segmentStatic = iota
+	segmentTyped
+	segmentParam
+)
+
+// paramTypes はパスパラメータで使用できる型
+var paramTypes = map[string]bool{
+	"string": true,
+	"int":    true,
+	"uuid":   true,
+}
+
+var (
+	paramNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	uuidPattern      = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// Segment はパステンプレートの1セグメント
+type Segment struct {
+	kind  int
+	Value string // 静的セグメントの文字列、またはパラメータ名
+	Type  string // パラメータの型
+}
+
+// IsParam はセグメントがパラメータかどうかを返す
+func (s Segment) IsParam() bool {
+	return s.kind != segmentStatic
+}
+
+// Pattern は /users/{id:int}/items 形式のパステンプレート
+type Pattern struct {
+	Raw      string
+	Segments []Segment
+}
+
+// ParsePattern はパステンプレートを解析する
+// パラメータは {name} または {name:type}（type は string, int, uuid）で記述する
+func ParsePattern(path string) (*Pattern, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("path must start with /")
+	}
+
+	p := &Pattern{Raw: path}
+	names := map[string]bool{}
+	for _, part := range splitPath(path) {
+		if part == "" {
+			return nil, fmt.Errorf("path must not contain empty segments")
+		}
+
+		if !strings.HasPrefix(part, "{") && !strings.HasSuffix(part, "}") {
+			if strings.ContainsAny(part, "{}") {
+				return nil, fmt.Errorf("invalid segment %q", part)
+			}
+			p.Segments = append(p.Segments, Segment{kind: segmentStatic, Value: part})
+			continue
+		}
+
+		if !strings.HasPrefix(part, "{") || !strings.HasSuffix(part, "}") {
+			return nil, fmt.Errorf("invalid segment %q", part)
+		}
+		name, paramType, _ := strings.Cut(part[1:len(part)-1], ":")
+		if paramType == "" {
+			paramType = "string"
+		}
+		if !paramNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid parameter name %q", name)
+		}
+		if !paramTypes[paramType] {
+			return nil, fmt.Errorf("unsupported parameter type %q", paramType)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicate parameter %q", name)
+		}
+		names[name] = true
+
+		kind := segmentTyped
+		if paramType == "string" {
+			kind = segmentParam
+		}
+		p.Segments = append(p.Segments, Segment{kind: kind, Value: name, Type: paramType})
+	}
+
+	return p, nil
+}
+
+// Shape はパラメータ名を除いたテンプレートの形を返す
+// 同じShapeを持つテンプレートは同じリクエストにマッチするため共存できない
+func (p *Pattern) Shape() string {
+	var sb strings.Builder
+	for _, seg := range p.Segments {
+		sb.WriteString("/")
+		if seg.IsParam() {
+			sb.WriteString("{:" + seg.Type + "}")
+		} else {
+			sb.WriteString(seg.Value)
+		}
+	}
+	if sb.Len() == 0 {
+		return "/"
+	}
+	return sb.String()
+}
+
+// Params はテンプレートに含まれるパラメータセグメントを返す
+func (p *Pattern) Params() []Segment {
+	var params []Segment
+	for _, seg := range p.Segments {
+		if seg.IsParam() {
+			params = append(params, seg)
+		}
+	}
+	return params
+}
+
+// Match はパスがテンプレートにマッチするか判定し、型変換済みのパラメータを返す
+func (p *Pattern) Match(path string) (map[string]interface{}, bool) {
+	parts := splitPath(path)
+	if len(parts) != len(p.Segments) {
+		return nil, false
+	}
+
+	params := map[string]interface{}{}
+	for i, seg := range p.Segments {
+		part := parts[i]
+		if !seg.IsParam() {
+			if part != seg.Value {
+				return nil, false
+			}
+			continue
+		}
+
+		value, ok := convertParam(part, seg.Type)
+		if !ok {
+			return nil, false
+		}
+		params[seg.Value] = value
+	}
+	return params, true
+}
+
+// compare はテンプレートの優先順位を比較する
+// 先頭のセグメントから順に 静的 > 型付きパラメータ > 文字列パラメータ の順で優先する
+func compare(a, b *Pattern) int {
+	for i := 0; i < len(a.Segments) && i < len(b.Segments); i++ {
+		if d := a.Segments[i].kind - b.Segments[i].kind; d != 0 {
+			return d
+		}
+	}
+	if d := len(b.Segments) - len(a.Segments); d != 0 {
+		return d
+	}
+	return strings.Compare(a.Raw, b.Raw)
+}
+
+func convertParam(value, paramType string) (interface{}, bool) {
+	if value == "" {
+		return nil, false
+	}
+	switch paramType {
+	case "int":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		return n, true
+	case "uuid":
+		if !uuidPattern.MatchString(value) {
+			return nil, false
+		}
+		return strings.ToLower(value), true
+	default:
+		return value, true
+	}
+}
+
+// splitPath はパスをセグメントに分割する（末尾のスラッシュは無視する）
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
@@ -0,0 +1,55 @@
+package routing
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Route はルーターに登録するルート
+type Route struct {
+	ID      string
+	Method  string
+	Pattern *Pattern
+}
+
+// Router はメソッドとパステンプレートからルートを特定する
+type Router struct {
+	routes map[string][]*Route // メソッド → 優先順位順のルート
+}
+
+// NewRouter はルートからRouterを構築する
+// 同じメソッドで同じ形のテンプレートが複数ある場合は先に渡されたルートを残し、エラーとして報告する
+func NewRouter(routes []Route) (*Router, []error) {
+	r := &Router{routes: map[string][]*Route{}}
+	seen := map[string]string{}
+	var errs []error
+
+	for i := range routes {
+		route := &routes[i]
+		key := route.Method + " " + route.Pattern.Shape()
+		if existing, ok := seen[key]; ok {
+			errs = append(errs, fmt.Errorf("route %s %s (%s) conflicts with %s", route.Method, route.Pattern.Raw, route.ID, existing))
+			continue
+		}
+		seen[key] = route.ID
+		r.routes[route.Method] = append(r.routes[route.Method], route)
+	}
+
+	for _, list := range r.routes {
+		sort.SliceStable(list, func(i, j int) bool {
+			return compare(list[i].Pattern, list[j].Pattern) < 0
+		})
+	}
+
+	return r, errs
+}
+
+// Match はリクエストにマッチするルートとパスパラメータを返す
+func (r *Router) Match(method, path string) (*Route, map[string]interface{}, bool) {
+	for _, route := range r.routes[method] {
+		if params, ok := route.Pattern.Match(path); ok {
+			return route, params, true
+		}
+	}
+	return nil, nil, false
+}
@@ -0,0 +1,176 @@
+package routing
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/items", want: "/items"},
+		{path: "items", want: "/items"},
+		{path: "/items/", want: "/items"},
+		{path: "/api/items", want: "/items"},
+		{path: "/api", want: "/"},
+		{path: "/apis/items", want: "/apis/items"},
+		{path: "/", want: "/"},
+		{path: "", want: "/"},
+	}
+	for _, tt := range tests {
+		if got := NormalizePath(tt.path); got != tt.want {
+			t.Errorf("NormalizePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParsePatternErrors(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr string
+	}{
+		{path: "items", wantErr: "must start with /"},
+		{path: "/items//x", wantErr: "empty segments"},
+		{path: "/items/{id", wantErr: "invalid segment"},
+		{path: "/items/a{id}", wantErr: "invalid segment"},
+		{path: "/items/{1id}", wantErr: "invalid parameter name"},
+		{path: "/items/{id:float}", wantErr: "unsupported parameter type"},
+		{path: "/items/{id}/{id:int}", wantErr: "duplicate parameter"},
+	}
+	for _, tt := range tests {
+		_, err := ParsePattern(tt.path)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ParsePattern(%q) error = %v, want %q", tt.path, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRouterMatch(t *testing.T) {
+	router := mustRouter(t,
+		Route{ID: "list", Method: "GET", Pattern: mustPattern(t, "/users")},
+		Route{ID: "by-name", Method: "GET", Pattern: mustPattern(t, "/users/{name}")},
+		Route{ID: "by-id", Method: "GET", Pattern: mustPattern(t, "/users/{id:int}")},
+		Route{ID: "by-uuid", Method: "GET", Pattern: mustPattern(t, "/users/{uid:uuid}")},
+		Route{ID: "me", Method: "GET", Pattern: mustPattern(t, "/users/me")},
+		Route{ID: "items", Method: "GET", Pattern: mustPattern(t, "/users/{id:int}/items")},
+		Route{ID: "any-items", Method: "GET", Pattern: mustPattern(t, "/{kind}/{id}/items")},
+		Route{ID: "create", Method: "POST", Pattern: mustPattern(t, "/users")},
+	)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantID     string
+		wantParams map[string]interface{}
+	}{
+		{name: "static", method: "GET", path: "/users", wantID: "list", wantParams: map[string]interface{}{}},
+		{name: "static before params", method: "GET", path: "/users/me", wantID: "me", wantParams: map[string]interface{}{}},
+		{name: "int before string", method: "GET", path: "/users/42", wantID: "by-id", wantParams: map[string]interface{}{"id": int64(42)}},
+		{name: "uuid before string", method: "GET", path: "/users/0F8FAD5B-D9CB-469F-A165-70867728950E", wantID: "by-uuid", wantParams: map[string]interface{}{"uid": "0f8fad5b-d9cb-469f-a165-70867728950e"}},
+		{name: "string fallback", method: "GET", path: "/users/alice", wantID: "by-name", wantParams: map[string]interface{}{"name": "alice"}},
+		{name: "earlier static segment wins", method: "GET", path: "/users/7/items", wantID: "items", wantParams: map[string]interface{}{"id": int64(7)}},
+		{name: "typed mismatch falls back", method: "GET", path: "/users/x/items", wantID: "any-items", wantParams: map[string]interface{}{"kind": "users", "id": "x"}},
+		{name: "trailing slash", method: "GET", path: "/users/", wantID: "list", wantParams: map[string]interface{}{}},
+		{name: "method", method: "POST", path: "/users", wantID: "create", wantParams: map[string]interface{}{}},
+		{name: "no route for method", method: "DELETE", path: "/users"},
+		{name: "no route for path", method: "GET", path: "/users/1/items/2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, params, ok := router.Match(tt.method, tt.path)
+			if tt.wantID == "" {
+				if ok {
+					t.Fatalf("Match() = %s, want no match", route.ID)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("Match() no match, want %s", tt.wantID)
+			}
+			if route.ID != tt.wantID {
+				t.Errorf("Match() = %s, want %s", route.ID, tt.wantID)
+			}
+			if !reflect.DeepEqual(params, tt.wantParams) {
+				t.Errorf("Match() params = %#v, want %#v", params, tt.wantParams)
+			}
+		})
+	}
+}
+
+func TestRouterConflicts(t *testing.T) {
+	tests := []struct {
+		name      string
+		routes    []Route
+		conflicts int
+	}{
+		{
+			name: "parameter names differ",
+			routes: []Route{
+				{ID: "a", Method: "GET", Pattern: mustPattern(t, "/users/{id}")},
+				{ID: "b", Method: "GET", Pattern: mustPattern(t, "/users/{user_id}")},
+			},
+			conflicts: 1,
+		},
+		{
+			name: "parameter types differ",
+			routes: []Route{
+				{ID: "a", Method: "GET", Pattern: mustPattern(t, "/users/{id}")},
+				{ID: "b", Method: "GET", Pattern: mustPattern(t, "/users/{id:int}")},
+			},
+		},
+		{
+			name: "methods differ",
+			routes: []Route{
+				{ID: "a", Method: "GET", Pattern: mustPattern(t, "/users/{id}")},
+				{ID: "b", Method: "PUT", Pattern: mustPattern(t, "/users/{id}")},
+			},
+		},
+		{
+			name: "same static path",
+			routes: []Route{
+				{ID: "a", Method: "GET", Pattern: mustPattern(t, "/users")},
+				{ID: "b", Method: "GET", Pattern: mustPattern(t, "/users")},
+				{ID: "c", Method: "GET", Pattern: mustPattern(t, "/users")},
+			},
+			conflicts: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, errs := NewRouter(tt.routes)
+			if len(errs) != tt.conflicts {
+				t.Fatalf("NewRouter() conflicts = %v, want %d", errs, tt.conflicts)
+			}
+			if tt.conflicts == 0 {
+				return
+			}
+			// 競合した場合は先に渡されたルートが残る
+			route, _, ok := router.Match(tt.routes[0].Method, strings.ReplaceAll(tt.routes[0].Pattern.Raw, "{id}", "1"))
+			if !ok || route.ID != tt.routes[0].ID {
+				t.Errorf("Match() = %v, want %s", route, tt.routes[0].ID)
+			}
+		})
+	}
+}
+
+func mustPattern(t *testing.T, path string) *Pattern {
+	t.Helper()
+	p, err := ParsePattern(path)
+	if err != nil {
+		t.Fatalf("ParsePattern(%q) error = %v", path, err)
+	}
+	return p
+}
+
+func mustRouter(t *testing.T, routes ...Route) *Router {
+	t.Helper()
+	router, errs := NewRouter(routes)
+	if len(errs) > 0 {
+		t.Fatalf("NewRouter() conflicts = %v", errs)
+	}
+	return router
+}
@@ -41,11 +41,31 @@ func RespondValidationError(w http.ResponseWriter, details interface{}) {
 	RespondError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request parameters", details)
 }
 
+// RespondUnauthorized は認証エラーを返す
+func RespondUnauthorized(w http.ResponseWriter, message string) {
+	RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", message, nil)
+}
+
+// RespondForbidden は権限エラーを返す
+func RespondForbidden(w http.ResponseWriter, message string) {
+	RespondError(w, http.StatusForbidden, "FORBIDDEN", message, nil)
+}
+
 // RespondNotFound はリソース未検出エラーを返す
 func RespondNotFound(w http.ResponseWriter, message string) {
 	RespondError(w, http.StatusNotFound, "NOT_FOUND", message, nil)
 }
 
+// RespondConflict はリソースの競合エラーを返す
+func RespondConflict(w http.ResponseWriter, message string, details interface{}) {
+	RespondError(w, http.StatusConflict, "CONFLICT", message, details)
+}
+
+// RespondPayloadTooLarge はリクエストボディのサイズ超過エラーを返す
+func RespondPayloadTooLarge(w http.ResponseWriter, message string) {
+	RespondError(w, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", message, nil)
+}
+
 // RespondInternalError はサーバー内部エラーを返す
 func RespondInternalError(w http.ResponseWriter, message string) {
 	RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", message, nil)
@@ -0,0 +1,45 @@
+-- FlowCore Migration: エンドポイントの一意性をメソッドとパスの組み合わせに変更
+-- 同じパスを異なるHTTPメソッドで定義できるようにする（GET /items と POST /items）
+-- 途中で失敗した場合に制約を削除したままにならないよう、1つのトランザクションで実行する
+
+BEGIN;
+
+-- パス単独の一意制約を削除（正規化で /api/items と /items が同じパスになっても違反しないよう、先に削除する）
+ALTER TABLE meta_endpoints DROP CONSTRAINT IF EXISTS meta_endpoints_path_key;
+ALTER TABLE meta_endpoints DROP CONSTRAINT IF EXISTS meta_endpoints_method_path_key;
+
+-- 正規化後にメソッドとパスが重複するエンドポイントがある場合は、手動で解消するよう中断する
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(format('%s %s (%s)', method, normalized_path, paths), '; ' ORDER BY normalized_path, method)
+    INTO duplicates
+    FROM (
+        SELECT method, normalized_path, string_agg(path, ', ' ORDER BY path) AS paths
+        FROM (
+            SELECT method,
+                   path,
+                   CASE WHEN path LIKE '/api/%' THEN substring(path FROM 5) ELSE path END AS normalized_path
+            FROM meta_endpoints
+        ) normalized
+        GROUP BY method, normalized_path
+        HAVING count(*) > 1
+    ) conflicts;
+
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'duplicate endpoints after removing the /api prefix: %', duplicates
+            USING HINT = 'Delete or rename the conflicting endpoints and run this migration again.';
+    END IF;
+END;
+$$;
+
+-- Runtime APIのプレフィックス（/api）を含むパスを正規化
+UPDATE meta_endpoints
+SET path = substring(path FROM 5), updated_at = NOW()
+WHERE path LIKE '/api/%';
+
+-- メソッドとパスの組み合わせで一意にする
+ALTER TABLE meta_endpoints ADD CONSTRAINT meta_endpoints_method_path_key UNIQUE (method, path);
+
+COMMIT;
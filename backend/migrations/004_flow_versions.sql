@@ -0,0 +1,57 @@
+-- FlowCore Migration: フローのバージョン管理とデプロイ
+-- 保存のたびに不変のバージョンを作成し、Runtime APIはデプロイされたバージョンのみを実行する
+
+-- MetaDB: フローバージョン（不変）
+CREATE TABLE IF NOT EXISTS flow_versions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    endpoint_id UUID NOT NULL REFERENCES meta_endpoints(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    flow_definition JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (endpoint_id, version)
+);
+
+-- バージョンの更新を禁止する
+CREATE OR REPLACE FUNCTION prevent_flow_version_update() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'flow_versions are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS flow_versions_immutable ON flow_versions;
+CREATE TRIGGER flow_versions_immutable
+    BEFORE UPDATE ON flow_versions
+    FOR EACH ROW EXECUTE FUNCTION prevent_flow_version_update();
+
+-- デプロイ中のバージョン
+ALTER TABLE meta_endpoints
+    ADD COLUMN IF NOT EXISTS deployed_version_id UUID REFERENCES flow_versions(id);
+
+-- MetaDB: デプロイ履歴
+CREATE TABLE IF NOT EXISTS deployments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    endpoint_id UUID NOT NULL REFERENCES meta_endpoints(id) ON DELETE CASCADE,
+    version_id UUID NOT NULL REFERENCES flow_versions(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL, -- deploy, rollback
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_flow_versions_endpoint_id ON flow_versions(endpoint_id);
+CREATE INDEX IF NOT EXISTS idx_deployments_endpoint_id ON deployments(endpoint_id);
+
+-- 既存のエンドポイントはバージョン1としてデプロイ済みにする
+INSERT INTO flow_versions (endpoint_id, version, flow_definition)
+SELECT e.id, 1, e.flow_definition
+FROM meta_endpoints e
+WHERE NOT EXISTS (SELECT 1 FROM flow_versions v WHERE v.endpoint_id = e.id);
+
+UPDATE meta_endpoints e
+SET deployed_version_id = v.id
+FROM flow_versions v
+WHERE v.endpoint_id = e.id AND v.version = 1 AND e.deployed_version_id IS NULL;
+
+INSERT INTO deployments (endpoint_id, version_id, action)
+SELECT e.id, e.deployed_version_id, 'deploy'
+FROM meta_endpoints e
+WHERE e.deployed_version_id IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM deployments d WHERE d.endpoint_id = e.id);
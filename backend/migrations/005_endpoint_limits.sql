@@ -0,0 +1,5 @@
+-- FlowCore Migration: エンドポイントごとの実行制限
+-- 0または未設定の項目はサーバー全体の設定値を使用する
+
+ALTER TABLE meta_endpoints
+    ADD COLUMN IF NOT EXISTS limits JSONB NOT NULL DEFAULT '{}';
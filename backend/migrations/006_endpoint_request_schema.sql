@@ -0,0 +1,5 @@
+-- FlowCore Migration: リクエストボディのJSON Schema
+-- 設定されている場合、Runtime APIはフローの実行前にリクエストボディを検証する
+
+ALTER TABLE meta_endpoints
+    ADD COLUMN IF NOT EXISTS request_schema JSONB;
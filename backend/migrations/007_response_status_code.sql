@@ -0,0 +1,33 @@
+-- FlowCore Migration: Responseノードのstatus設定をstatusCodeに統一する
+-- 下書きのフローのみ書き換える（不変のflow_versionsの旧形式のstatusは、検証・実行時にstatusCodeの非推奨の別名として受け付ける）
+
+UPDATE meta_endpoints e
+SET flow_definition = jsonb_set(
+        e.flow_definition,
+        '{nodes}',
+        (
+            SELECT jsonb_agg(
+                CASE
+                    WHEN n->>'type' = 'response' AND n->'config' ? 'status' THEN
+                        jsonb_set(
+                            n,
+                            '{config}',
+                            CASE
+                                WHEN n->'config' ? 'statusCode' THEN n->'config' - 'status'
+                                ELSE (n->'config' - 'status') || jsonb_build_object('statusCode', n->'config'->'status')
+                            END
+                        )
+                    ELSE n
+                END
+                ORDER BY ord
+            )
+            FROM jsonb_array_elements(e.flow_definition->'nodes') WITH ORDINALITY AS t(n, ord)
+        )
+    ),
+    updated_at = NOW()
+WHERE jsonb_typeof(e.flow_definition->'nodes') = 'array'
+  AND EXISTS (
+      SELECT 1
+      FROM jsonb_array_elements(e.flow_definition->'nodes') AS n
+      WHERE n->>'type' = 'response' AND n->'config' ? 'status'
+  );
@@ -0,0 +1,40 @@
+-- FlowCore Migration: サブフロー
+-- 他のフローから呼び出せる名前付きのサブフロー。保存のたびに不変のバージョンを作成する
+
+-- MetaDB: サブフロー（最新のバージョンの定義を保持する）
+CREATE TABLE IF NOT EXISTS meta_subflows (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    inputs JSONB NOT NULL DEFAULT '[]',
+    outputs JSONB NOT NULL DEFAULT '[]',
+    flow_definition JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- MetaDB: サブフローのバージョン（不変）
+CREATE TABLE IF NOT EXISTS subflow_versions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subflow_id UUID NOT NULL REFERENCES meta_subflows(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    inputs JSONB NOT NULL DEFAULT '[]',
+    outputs JSONB NOT NULL DEFAULT '[]',
+    flow_definition JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (subflow_id, version)
+);
+
+-- バージョンの更新を禁止する
+CREATE OR REPLACE FUNCTION prevent_subflow_version_update() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'subflow_versions are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS subflow_versions_immutable ON subflow_versions;
+CREATE TRIGGER subflow_versions_immutable
+    BEFORE UPDATE ON subflow_versions
+    FOR EACH ROW EXECUTE FUNCTION prevent_subflow_version_update();
+
+CREATE INDEX IF NOT EXISTS idx_subflow_versions_subflow_id ON subflow_versions(subflow_id);
@@ -0,0 +1,50 @@
+-- FlowCore Migration: 内部IdP（メール＋パスワード）
+-- エンドユーザーの認証情報・リフレッシュトークン・JWTの署名鍵
+
+-- ユーザー（既存のusersテーブルにJWTのクレームに含める項目を追加する）
+-- 追加するカラムはmeta_columnsに登録しないため、フローのDatabaseノードからは参照されない
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS roles JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+-- ユーザーの認証情報（provider: password。provider_subjectは小文字に正規化したメールアドレス）
+CREATE TABLE IF NOT EXISTS user_identities (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    provider_subject TEXT NOT NULL,
+    password_hash TEXT, -- bcrypt
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (provider, provider_subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+-- リフレッシュトークン（トークンはSHA-256のハッシュのみを保存する）
+-- ログインごとにfamily_idを割り当て、ローテーションで発行したトークンは同じfamily_idを引き継ぐ
+-- 使用済み（revoked_at）のトークンが再度使用された場合はfamily全体を失効させる
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    family_id UUID NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    replaced_by UUID REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+
+-- JWT（RS256）の署名鍵
+-- 秘密鍵はMASTER_KEYから導出した鍵でAES-256-GCMにより暗号化して保存する
+CREATE TABLE IF NOT EXISTS auth_signing_keys (
+    kid TEXT PRIMARY KEY,
+    algorithm VARCHAR(20) NOT NULL DEFAULT 'RS256',
+    public_key TEXT NOT NULL,      -- PEM（SubjectPublicKeyInfo）
+    private_key BYTEA NOT NULL,    -- 暗号化したPKCS#8（nonce + ciphertext）
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    retired_at TIMESTAMP           -- 署名に使用しなくなった日時（検証には引き続き使用する）
+);
@@ -0,0 +1,17 @@
+-- FlowCore Migration: メールアドレスの確認
+-- 確認済みの日時と、確認メールで送信する1回限りのトークン
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+
+-- 確認トークン（idはトークンに含まれる乱数。トークンはMASTER_KEYから導出した鍵で署名する）
+-- 使用済み（used_at）・期限切れのトークン、確認時にユーザーのメールアドレスと一致しないトークンは無効
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+    id TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_user_id ON email_verification_tokens(user_id);
@@ -0,0 +1,7 @@
+-- FlowCore Migration: エンドポイントごとの認証・認可
+-- mode: public（認証なし）, jwt（内部IdPのアクセストークン）, api_key, admin
+-- roles（jwt・admin）はいずれか1つ、scopes（api_key）はすべてを持つ呼び出し元のみを許可する
+-- 既存のエンドポイントはpublicとして扱う
+
+ALTER TABLE meta_endpoints
+    ADD COLUMN IF NOT EXISTS auth JSONB NOT NULL DEFAULT '{"mode": "public"}';
@@ -0,0 +1,24 @@
+-- FlowCore Migration: 管理者アカウント（Admin APIの認証・ロールベースの認可）
+-- role: owner（すべての操作）> editor（テーブル・エンドポイント・サブフローの変更）> viewer（参照のみ）
+
+CREATE TABLE IF NOT EXISTS admin_users (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email TEXT NOT NULL UNIQUE, -- 小文字に正規化したメールアドレス
+    password_hash TEXT NOT NULL, -- bcrypt
+    role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    last_login_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 管理者のセッション（トークンはSHA-256のハッシュのみを保存する）
+-- パスワードの変更・ログアウト・管理者の削除で失効する
+CREATE TABLE IF NOT EXISTS admin_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    admin_user_id UUID NOT NULL REFERENCES admin_users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_sessions_admin_user_id ON admin_sessions(admin_user_id);
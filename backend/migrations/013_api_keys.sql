@@ -0,0 +1,22 @@
+-- FlowCore Migration: APIキー（サーバー間の呼び出し）
+-- エンドポイントにタグを追加し、APIキーは対象のエンドポイント（ID）またはタグで呼び出せる範囲を制限する
+
+ALTER TABLE meta_endpoints
+    ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '[]';
+
+-- APIキー（キーはSHA-256のハッシュのみを保存し、作成時に1回のみ返す）
+-- prefixはキーの先頭部分で、一覧・ログでキーを識別するために使用する
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(32) NOT NULL UNIQUE,
+    key_hash TEXT NOT NULL UNIQUE,
+    scopes JSONB NOT NULL DEFAULT '[]', -- エンドポイントの auth.scopes と照合する
+    endpoint_ids JSONB NOT NULL DEFAULT '[]', -- 呼び出せるエンドポイントのID
+    tags JSONB NOT NULL DEFAULT '[]', -- 呼び出せるエンドポイントのタグ（いずれか1つ）
+    expires_at TIMESTAMP, -- NULLの場合は無期限
+    revoked_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_by UUID REFERENCES admin_users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
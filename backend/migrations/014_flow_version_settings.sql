@@ -0,0 +1,23 @@
+-- FlowCore Migration: 実行制限・認証設定・リクエストスキーマをフローのバージョンに含める
+-- Runtime APIはデプロイされたバージョンのフローとこれらの設定で実行し、ロールバックでは設定も以前のバージョンに戻る
+-- メソッド・パス・名前・タグはバージョンに含めず、meta_endpointsの値が即座に反映される
+
+BEGIN;
+
+ALTER TABLE flow_versions
+    ADD COLUMN IF NOT EXISTS limits JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS auth JSONB NOT NULL DEFAULT '{"mode": "public"}',
+    ADD COLUMN IF NOT EXISTS request_schema JSONB;
+
+-- 既存のバージョンには、これまで実行時に使用していたエンドポイントの現在の設定を設定する
+-- （バージョンは不変のため、この移行の間のみ更新を禁止するトリガーを無効にする）
+ALTER TABLE flow_versions DISABLE TRIGGER flow_versions_immutable;
+
+UPDATE flow_versions v
+SET limits = e.limits, auth = e.auth, request_schema = e.request_schema
+FROM meta_endpoints e
+WHERE e.id = v.endpoint_id;
+
+ALTER TABLE flow_versions ENABLE TRIGGER flow_versions_immutable;
+
+COMMIT;
@@ -0,0 +1,22 @@
+-- FlowCore Migration: パスをフローのバージョンに含める
+-- フローはパスパラメータを参照するため、Runtime APIはデプロイされたバージョンのパスでルーティングする
+-- パスを変更しても、新しいバージョンをデプロイするまではデプロイ済みのバージョンが以前のパスで実行される
+
+BEGIN;
+
+ALTER TABLE flow_versions ADD COLUMN IF NOT EXISTS path VARCHAR(500);
+
+-- 既存のバージョンには、これまでルーティングに使用していたエンドポイントの現在のパスを設定する
+-- （バージョンは不変のため、この移行の間のみ更新を禁止するトリガーを無効にする）
+ALTER TABLE flow_versions DISABLE TRIGGER flow_versions_immutable;
+
+UPDATE flow_versions v
+SET path = e.path
+FROM meta_endpoints e
+WHERE e.id = v.endpoint_id;
+
+ALTER TABLE flow_versions ENABLE TRIGGER flow_versions_immutable;
+
+ALTER TABLE flow_versions ALTER COLUMN path SET NOT NULL;
+
+COMMIT;
@@ -29,9 +29,15 @@ export function ApiEditor() {
       clearTimeout(saveTimeoutRef.current)
     }
 
+    const endpointId = selectedEndpoint
     saveTimeoutRef.current = setTimeout(async () => {
-      console.log("Auto-saving flow for endpoint:", selectedEndpoint)
-      await updateEndpoint(selectedEndpoint, { flow: newFlow })
+      console.log("Auto-saving flow for endpoint:", endpointId)
+      const result = await updateEndpoint(endpointId, { flow: newFlow })
+      if (result.success) {
+        // 保存のたびに新しいバージョンが作成されるため、バージョン番号を更新する
+        const { version, deployed_version } = result.data
+        setEndpoints((prev) => prev.map((e) => (e.id === endpointId ? { ...e, version, deployed_version } : e)))
+      }
     }, 1000)
   }
 
@@ -273,6 +279,15 @@ export function ApiEditor() {
                   テスト
                 </Button>
               </div>
+              <p className="mt-2 text-xs text-muted-foreground">
+                {currentEndpoint.deployed_version
+                  ? `下書き v${currentEndpoint.version} / デプロイ中 v${currentEndpoint.deployed_version}`
+                  : `下書き v${currentEndpoint.version} / 未デプロイ`}
+                {currentEndpoint.deployed_version !== null && currentEndpoint.version > currentEndpoint.deployed_version
+                  ? "（デプロイされていない変更があります）"
+                  : ""}
+                。メソッドの変更は即座に公開中のAPIに反映され、フロー・パス・認証・実行制限・リクエストスキーマの変更はデプロイすると反映されます
+              </p>
             </div>
             <div className="flex-1 overflow-hidden">
               <NodeCanvas
@@ -96,6 +96,9 @@ export interface Endpoint {
   method: HttpMethod
   path: string
   flow: Flow
+  // 最後に保存されたバージョンとデプロイ中のバージョン（未デプロイの場合はnull）
+  version: number
+  deployed_version: number | null
   created_at: string
   updated_at: string
 }